@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	stdJSON "encoding/json"
+	"time"
+
+	"github.com/cenkalti/backoff"
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/pkg/errors"
+)
+
+var es *elasticsearch.Client
+
+// elasticSearcher - search over indices of metadata indexer in Elasticsearch mode
+type elasticSearcher struct {
+	client *elasticsearch.Client
+}
+
+// Search -
+func (s elasticSearcher) Search(ctx context.Context, req searchRequest) (SearchResult, error) {
+	data, err := stdJSON.Marshal(req.body())
+	if err != nil {
+		return SearchResult{}, err
+	}
+
+	resp, err := s.client.Search(
+		s.client.Search.WithContext(ctx),
+		s.client.Search.WithIndex(req.index()),
+		s.client.Search.WithBody(bytes.NewReader(data)),
+	)
+	if err != nil {
+		return SearchResult{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.IsError() {
+		return SearchResult{}, errors.New(resp.String())
+	}
+
+	var response searchResponse
+	if err := stdJSON.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return SearchResult{}, err
+	}
+	return newSearchResult(req, response)
+}
+
+func (req *searchRequest) index() string {
+	if req.Type == TypeContract {
+		return IndexContract
+	}
+	return IndexToken
+}
+
+func (req *searchRequest) sort() []map[string]any {
+	return []map[string]any{
+		{req.sortField: req.sortOrder},
+		{"update_id": req.sortOrder},
+	}
+}
+
+func (req *searchRequest) body() map[string]any {
+	filters := make([]map[string]any, 0)
+	if req.Network != "" {
+		filters = append(filters, term("network.keyword", req.Network))
+	}
+	if req.Contract != "" {
+		filters = append(filters, term("contract.keyword", req.Contract))
+	}
+	if req.Creator != "" {
+		filters = append(filters, term("creators", req.Creator))
+	}
+	if req.Tag != "" {
+		filters = append(filters, term("tags", req.Tag))
+	}
+	if req.Mime != "" {
+		filters = append(filters, term("metadata.formats.mimeType", req.Mime))
+	}
+	if req.status > 0 {
+		filters = append(filters, term("status", req.status))
+	}
+
+	boolQuery := map[string]any{
+		"filter": filters,
+	}
+	if req.Query != "" {
+		fields := []string{"name^3", "symbol^3", "metadata.description"}
+		if req.Type == TypeContract {
+			fields = []string{"metadata.name^3", "metadata.description"}
+		}
+		boolQuery["must"] = map[string]any{
+			"multi_match": map[string]any{
+				"query":  req.Query,
+				"fields": fields,
+			},
+		}
+	}
+
+	body := map[string]any{
+		"query": map[string]any{
+			"bool": boolQuery,
+		},
+		"sort":    req.sort(),
+		"size":    req.Limit,
+		"_source": sourceFields,
+	}
+	if req.after != nil {
+		body["search_after"] = req.after
+	}
+	return body
+}
+
+func term(field string, value any) map[string]any {
+	return map[string]any{
+		"term": map[string]any{
+			field: value,
+		},
+	}
+}
+
+func createElastic(path string) (*elasticsearch.Client, error) {
+	retryBackoff := backoff.NewExponentialBackOff()
+	elasticConfig := elasticsearch.Config{
+		Addresses:     []string{path},
+		RetryOnStatus: []int{502, 503, 504, 429},
+		RetryBackoff: func(i int) time.Duration {
+			if i == 1 {
+				retryBackoff.Reset()
+			}
+			return retryBackoff.NextBackOff()
+		},
+		MaxRetries: 5,
+	}
+
+	elastic, err := elasticsearch.NewClient(elasticConfig)
+	if err != nil {
+		return nil, err
+	}
+	response, err := elastic.Ping()
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	return elastic, nil
+}
@@ -2,11 +2,8 @@ package main
 
 import (
 	"os"
-	"time"
 
-	"github.com/cenkalti/backoff"
 	"github.com/dipdup-net/go-lib/config"
-	"github.com/elastic/go-elasticsearch/v8"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 	"github.com/rs/zerolog"
@@ -14,8 +11,6 @@ import (
 	"github.com/spf13/cobra"
 )
 
-var es *elasticsearch.Client
-
 var (
 	rootCmd = &cobra.Command{
 		Use:   "api",
@@ -42,18 +37,6 @@ func main() {
 		return
 	}
 
-	if cfg.Database.Kind != "elastic" {
-		log.Error().Msgf("Invalid database kind: want=elastic got=%s", cfg.Database.Kind)
-		return
-	}
-
-	elastic, err := createElastic(cfg.Database.Path)
-	if err != nil {
-		log.Err(err).Msg("")
-		return
-	}
-	es = elastic
-
 	e := echo.New()
 
 	// Middleware
@@ -63,33 +46,30 @@ func main() {
 	// Routes
 	e.GET("/search", search)
 
-	// Start server
-	e.Logger.Fatal(e.Start(":11111"))
-}
-
-func createElastic(path string) (*elasticsearch.Client, error) {
-	retryBackoff := backoff.NewExponentialBackOff()
-	elasticConfig := elasticsearch.Config{
-		Addresses:     []string{path},
-		RetryOnStatus: []int{502, 503, 504, 429},
-		RetryBackoff: func(i int) time.Duration {
-			if i == 1 {
-				retryBackoff.Reset()
-			}
-			return retryBackoff.NextBackOff()
-		},
-		MaxRetries: 5,
-	}
-
-	elastic, err := elasticsearch.NewClient(elasticConfig)
-	if err != nil {
-		return nil, err
-	}
-	response, err := elastic.Ping()
-	if err != nil {
-		return nil, err
+	switch cfg.Database.Kind {
+	case config.DBKindElasticSearch:
+		elastic, err := createElastic(cfg.Database.Path)
+		if err != nil {
+			log.Err(err).Msg("")
+			return
+		}
+		es = elastic
+		backend = elasticSearcher{client: elastic}
+
+		// completion suggesters are Elasticsearch only
+		e.GET("/suggest", suggest)
+	case config.DBKindPostgres:
+		db, err := createPostgres(cfg.Database)
+		if err != nil {
+			log.Err(err).Msg("")
+			return
+		}
+		backend = postgresSearcher{db: db}
+	default:
+		log.Error().Msgf("Invalid database kind: want=%s or %s got=%s", config.DBKindElasticSearch, config.DBKindPostgres, cfg.Database.Kind)
+		return
 	}
-	defer response.Body.Close()
 
-	return elastic, nil
+	// Start server
+	e.Logger.Fatal(e.Start(":11111"))
 }
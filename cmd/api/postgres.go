@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	stdJSON "encoding/json"
+	"time"
+
+	"github.com/dipdup-net/go-lib/config"
+	"github.com/dipdup-net/go-lib/database"
+
+	"github.com/dipdup-net/metadata/cmd/metadata/models"
+)
+
+// postgresSearcher - full text search over `search_vector` columns of metadata tables in Postgres mode
+type postgresSearcher struct {
+	db *models.Database
+}
+
+func createPostgres(cfg config.Database) (*models.Database, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	db := database.NewPgGo()
+	if err := db.Connect(ctx, cfg); err != nil {
+		return nil, err
+	}
+	database.Wait(ctx, db, 5*time.Second)
+
+	return &models.Database{PgGo: db}, nil
+}
+
+// Search -
+func (s postgresSearcher) Search(ctx context.Context, req searchRequest) (SearchResult, error) {
+	params, err := searchParams(req)
+	if err != nil {
+		return SearchResult{}, err
+	}
+
+	var rows []searchRow
+	switch req.Type {
+	case TypeContract:
+		contracts, err := s.db.SearchContracts(ctx, params)
+		if err != nil {
+			return SearchResult{}, err
+		}
+		for i := range contracts {
+			rows = append(rows, contractRow(contracts[i]))
+		}
+	default:
+		tokens, err := s.db.SearchTokens(ctx, params)
+		if err != nil {
+			return SearchResult{}, err
+		}
+		for i := range tokens {
+			rows = append(rows, tokenRow(tokens[i]))
+		}
+	}
+	return newPostgresResult(req, params, rows)
+}
+
+func searchParams(req searchRequest) (models.SearchParams, error) {
+	params := models.SearchParams{
+		Query:    req.Query,
+		Network:  req.Network,
+		Contract: req.Contract,
+		Status:   req.status,
+		Creator:  req.Creator,
+		Tag:      req.Tag,
+		Mime:     req.Mime,
+		Sort:     req.sortField,
+		Desc:     req.sortOrder == "desc",
+		Limit:    req.Limit,
+	}
+	if req.sortField == sortFields["relevance"] {
+		params.Sort = models.SearchSortRelevance
+	}
+
+	if req.after != nil {
+		var cursor models.SearchCursor
+		value, ok := req.after[0].(stdJSON.RawMessage)
+		if !ok || stdJSON.Unmarshal(value, &cursor.Value) != nil {
+			return params, errInvalidCursor
+		}
+		updateID, ok := req.after[1].(stdJSON.RawMessage)
+		if !ok || stdJSON.Unmarshal(updateID, &cursor.UpdateID) != nil {
+			return params, errInvalidCursor
+		}
+		params.After = &cursor
+	}
+	return params, nil
+}
+
+// searchRow - found row in terms of Elasticsearch hit, so items of both backends are built the same way
+type searchRow struct {
+	hit      searchHit
+	rank     float64
+	updateID int64
+}
+
+func tokenRow(token models.SearchedToken) searchRow {
+	tm := token.TokenMetadata
+	return searchRow{
+		hit: searchHit{
+			Source: searchSource{
+				Network:      tm.Network,
+				Contract:     tm.Contract,
+				TokenID:      tm.TokenID.String(),
+				Link:         tm.Link,
+				Status:       tm.Status,
+				Metadata:     stdJSON.RawMessage(tm.Metadata),
+				CreatedAt:    tm.CreatedAt,
+				UpdatedAt:    tm.UpdatedAt,
+				Name:         tm.Name,
+				Symbol:       tm.Symbol,
+				Decimals:     tm.Decimals,
+				ArtifactURI:  tm.ArtifactURI,
+				DisplayURI:   tm.DisplayURI,
+				ThumbnailURI: tm.ThumbnailURI,
+				Creators:     tm.Creators,
+				Tags:         tm.Tags,
+			},
+		},
+		rank:     token.Rank,
+		updateID: tm.UpdateID,
+	}
+}
+
+func contractRow(contract models.SearchedContract) searchRow {
+	cm := contract.ContractMetadata
+	return searchRow{
+		hit: searchHit{
+			Source: searchSource{
+				Network:   cm.Network,
+				Contract:  cm.Contract,
+				Link:      cm.Link,
+				Status:    cm.Status,
+				Metadata:  stdJSON.RawMessage(cm.Metadata),
+				CreatedAt: cm.CreatedAt,
+				UpdatedAt: cm.UpdatedAt,
+			},
+		},
+		rank:     contract.Rank,
+		updateID: cm.UpdateID,
+	}
+}
+
+// newPostgresResult - cursor of Postgres backend is a pair of sort value and `update_id` of the last row
+func newPostgresResult(req searchRequest, params models.SearchParams, rows []searchRow) (SearchResult, error) {
+	result := SearchResult{
+		Items: make([]SearchItem, 0, len(rows)),
+	}
+	for i := range rows {
+		if req.Query != "" {
+			rank := rows[i].rank
+			rows[i].hit.Score = &rank
+		}
+		result.Items = append(result.Items, newSearchItem(req.Type, rows[i].hit))
+	}
+
+	if len(rows) == req.Limit && len(rows) > 0 {
+		last := rows[len(rows)-1]
+		var value any
+		switch params.Sort {
+		case models.SearchSortCreatedAt:
+			value = last.hit.Source.CreatedAt
+		case models.SearchSortUpdatedAt:
+			value = last.hit.Source.UpdatedAt
+		default:
+			value = last.rank
+		}
+
+		values := make([]stdJSON.RawMessage, 0, 2)
+		for _, v := range []any{value, last.updateID} {
+			data, err := stdJSON.Marshal(v)
+			if err != nil {
+				return result, err
+			}
+			values = append(values, data)
+		}
+		cursor, err := encodeCursor(values)
+		if err != nil {
+			return result, err
+		}
+		result.Cursor = cursor
+	}
+	return result, nil
+}
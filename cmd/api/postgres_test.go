@@ -0,0 +1,106 @@
+package main
+
+import (
+	stdJSON "encoding/json"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dipdup-net/metadata/cmd/metadata/models"
+)
+
+func Test_searchParams(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     searchRequest
+		want    models.SearchParams
+		wantErr bool
+	}{
+		{
+			name: "relevance by default",
+			req:  searchRequest{Query: "hic et nunc", Network: "mainnet", Tag: "art", Status: "applied"},
+			want: models.SearchParams{
+				Query:   "hic et nunc",
+				Network: "mainnet",
+				Tag:     "art",
+				Status:  models.StatusApplied,
+				Sort:    models.SearchSortRelevance,
+				Desc:    true,
+				Limit:   defaultLimit,
+			},
+		}, {
+			name: "cursor",
+			req:  searchRequest{Type: TypeContract, Sort: "updated_at:asc", Limit: 10, Cursor: "WzE2OTAwMDAwMDAsMTJd"},
+			want: models.SearchParams{
+				Sort:  models.SearchSortUpdatedAt,
+				Limit: 10,
+				After: &models.SearchCursor{Value: 1690000000, UpdateID: 12},
+			},
+		}, {
+			name:    "cursor of other backend",
+			req:     searchRequest{Sort: "created_at:desc", Cursor: "WyJhIiwxMl0"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.NoError(t, tt.req.validate())
+
+			params, err := searchParams(tt.req)
+			if tt.wantErr {
+				require.ErrorIs(t, err, errInvalidCursor)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, params)
+		})
+	}
+}
+
+func Test_newPostgresResult(t *testing.T) {
+	req := searchRequest{Query: "heh", Limit: 1}
+	require.NoError(t, req.validate())
+	params, err := searchParams(req)
+	require.NoError(t, err)
+
+	decimals := 6
+	result, err := newPostgresResult(req, params, []searchRow{
+		tokenRow(models.SearchedToken{
+			TokenMetadata: models.TokenMetadata{
+				Network:  "mainnet",
+				Contract: "KT1G",
+				TokenID:  decimal.Zero,
+				Status:   models.StatusApplied,
+				Metadata: models.JSONB(`{"name":"Hedgehoge"}`),
+				Name:     "Hedgehoge",
+				Symbol:   "HEH",
+				Decimals: &decimals,
+				UpdateID: 12,
+			},
+			Rank: 0.0607927,
+		}),
+	})
+	require.NoError(t, err)
+
+	score := 0.0607927
+	assert.Equal(t, []SearchItem{
+		{
+			Type:     TypeToken,
+			Network:  "mainnet",
+			Contract: "KT1G",
+			TokenID:  "0",
+			Name:     "Hedgehoge",
+			Symbol:   "HEH",
+			Decimals: &decimals,
+			Status:   "applied",
+			Metadata: stdJSON.RawMessage(`{"name":"Hedgehoge"}`),
+			Score:    &score,
+		},
+	}, result.Items)
+
+	after, err := decodeCursor(result.Cursor, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []any{stdJSON.RawMessage("0.0607927"), stdJSON.RawMessage("12")}, after)
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"encoding/base64"
+	stdJSON "encoding/json"
+
+	"github.com/pkg/errors"
+
+	"github.com/dipdup-net/metadata/cmd/metadata/models"
+)
+
+// sourceFields - fields of indexed documents which are returned by search. Raw sources, issues and internal fields are skipped.
+var sourceFields = []string{
+	"network", "contract", "token_id", "link", "status", "metadata", "created_at", "updated_at",
+	"name", "symbol", "decimals", "artifact_uri", "display_uri", "thumbnail_uri", "creators", "tags",
+}
+
+type searchResponse struct {
+	Hits struct {
+		Hits []searchHit `json:"hits"`
+	} `json:"hits"`
+}
+
+type searchHit struct {
+	Score  *float64             `json:"_score"`
+	Source searchSource         `json:"_source"`
+	Sort   []stdJSON.RawMessage `json:"sort"`
+}
+
+type searchSource struct {
+	Network      string             `json:"network"`
+	Contract     string             `json:"contract"`
+	TokenID      string             `json:"token_id"`
+	Link         string             `json:"link"`
+	Status       models.Status      `json:"status"`
+	Metadata     stdJSON.RawMessage `json:"metadata"`
+	CreatedAt    int64              `json:"created_at"`
+	UpdatedAt    int64              `json:"updated_at"`
+	Name         string             `json:"name"`
+	Symbol       string             `json:"symbol"`
+	Decimals     *int               `json:"decimals"`
+	ArtifactURI  string             `json:"artifact_uri"`
+	DisplayURI   string             `json:"display_uri"`
+	ThumbnailURI string             `json:"thumbnail_uri"`
+	Creators     []string           `json:"creators"`
+	Tags         []string           `json:"tags"`
+}
+
+// SearchItem - found token or contract metadata
+type SearchItem struct {
+	Type         string             `json:"type"`
+	Network      string             `json:"network"`
+	Contract     string             `json:"contract"`
+	TokenID      string             `json:"token_id,omitempty"`
+	Name         string             `json:"name,omitempty"`
+	Symbol       string             `json:"symbol,omitempty"`
+	Decimals     *int               `json:"decimals,omitempty"`
+	ArtifactURI  string             `json:"artifact_uri,omitempty"`
+	DisplayURI   string             `json:"display_uri,omitempty"`
+	ThumbnailURI string             `json:"thumbnail_uri,omitempty"`
+	Creators     []string           `json:"creators,omitempty"`
+	Tags         []string           `json:"tags,omitempty"`
+	Link         string             `json:"link,omitempty"`
+	Status       string             `json:"status"`
+	Metadata     stdJSON.RawMessage `json:"metadata,omitempty"`
+	CreatedAt    int64              `json:"created_at"`
+	UpdatedAt    int64              `json:"updated_at"`
+	Score        *float64           `json:"score,omitempty"`
+}
+
+// SearchResult - page of found items. `Cursor` is the value of `cursor` parameter for the next page, it's empty on the last page.
+type SearchResult struct {
+	Items  []SearchItem `json:"items"`
+	Cursor string       `json:"cursor,omitempty"`
+}
+
+func newSearchResult(req searchRequest, response searchResponse) (SearchResult, error) {
+	hits := response.Hits.Hits
+	result := SearchResult{
+		Items: make([]SearchItem, 0, len(hits)),
+	}
+	for i := range hits {
+		result.Items = append(result.Items, newSearchItem(req.Type, hits[i]))
+	}
+
+	if len(hits) == req.Limit && len(hits) > 0 {
+		cursor, err := encodeCursor(hits[len(hits)-1].Sort)
+		if err != nil {
+			return result, err
+		}
+		result.Cursor = cursor
+	}
+	return result, nil
+}
+
+func newSearchItem(typ string, hit searchHit) SearchItem {
+	source := hit.Source
+	item := SearchItem{
+		Type:         typ,
+		Network:      source.Network,
+		Contract:     source.Contract,
+		TokenID:      source.TokenID,
+		Name:         source.Name,
+		Symbol:       source.Symbol,
+		Decimals:     source.Decimals,
+		ArtifactURI:  source.ArtifactURI,
+		DisplayURI:   source.DisplayURI,
+		ThumbnailURI: source.ThumbnailURI,
+		Creators:     source.Creators,
+		Tags:         source.Tags,
+		Link:         source.Link,
+		Status:       source.Status.String(),
+		CreatedAt:    source.CreatedAt,
+		UpdatedAt:    source.UpdatedAt,
+		Score:        hit.Score,
+	}
+	if len(source.Metadata) > 0 && string(source.Metadata) != "null" {
+		item.Metadata = source.Metadata
+	}
+
+	// contract metadata isn't normalized, name is taken from TZIP-16 document
+	if typ == TypeContract && item.Name == "" && item.Metadata != nil {
+		var document struct {
+			Name any `json:"name"`
+		}
+		if err := stdJSON.Unmarshal(item.Metadata, &document); err == nil {
+			if name, ok := document.Name.(string); ok {
+				item.Name = name
+			}
+		}
+	}
+	return item
+}
+
+// errInvalidCursor - cursor isn't returned by previous page of the same search
+var errInvalidCursor = errors.New("invalid cursor")
+
+// encodeCursor - cursor is opaque for clients: it's base64 of sort values of the last item which are passed to `search_after`
+func encodeCursor(values []stdJSON.RawMessage) (string, error) {
+	if len(values) == 0 {
+		return "", nil
+	}
+	data, err := stdJSON.Marshal(values)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+func decodeCursor(cursor string, count int) ([]any, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+	var values []stdJSON.RawMessage
+	if err := stdJSON.Unmarshal(data, &values); err != nil || len(values) != count {
+		return nil, errInvalidCursor
+	}
+
+	after := make([]any, len(values))
+	for i := range values {
+		// only scalar sort values are valid
+		switch {
+		case len(values[i]) == 0:
+			return nil, errInvalidCursor
+		case values[i][0] == '{' || values[i][0] == '[':
+			return nil, errInvalidCursor
+		}
+		after[i] = values[i]
+	}
+	return after, nil
+}
@@ -1,13 +1,15 @@
 package main
 
 import (
+	"context"
 	"net/http"
 	"strings"
 
-	"log"
-
 	"github.com/labstack/echo/v4"
 	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+
+	"github.com/dipdup-net/metadata/cmd/metadata/models"
 )
 
 // index names
@@ -16,50 +18,120 @@ const (
 	IndexContract = "contract_metadata"
 )
 
-const maxLimit = 25
+// search types
+const (
+	TypeToken    = "token"
+	TypeContract = "contract"
+)
+
+const (
+	defaultLimit = 25
+	maxLimit     = 100
+
+	minQueryLength = 2
+	maxQueryLength = 256
+)
+
+// luceneReserved - characters of Lucene query syntax: fields, wildcards, regexps, fuzziness, boosts, ranges and phrases. Query is full text, so they are rejected.
+const luceneReserved = `:*?~^\/[]{}<>"`
+
+// sort fields which may be requested, `update_id` is a tiebreaker for cursors because it's unique
+var sortFields = map[string]string{
+	"relevance":  "_score",
+	"created_at": "created_at",
+	"updated_at": "updated_at",
+}
 
 type searchRequest struct {
-	Index  string `query:"i"`
-	Fields string `query:"f"`
-	Offset int    `query:"o"`
-	Limit  int    `query:"l"`
-	Sort   string `query:"s"`
-	Query  string `query:"q"`
+	Query    string `query:"q"`
+	Type     string `query:"type"`
+	Network  string `query:"network"`
+	Contract string `query:"contract"`
+	Creator  string `query:"creator"`
+	Tag      string `query:"tag"`
+	Mime     string `query:"mime"`
+	Status   string `query:"status"`
+	Sort     string `query:"sort"`
+	Limit    int    `query:"limit"`
+	Cursor   string `query:"cursor"`
+
+	status    models.Status
+	sortField string
+	sortOrder string
+	after     []any
 }
 
 func (req *searchRequest) validate() error {
-	if req.Index == "" {
-		req.Index = strings.Join([]string{IndexToken, IndexContract}, ",")
-	} else {
-		for _, index := range strings.Split(req.Index, ",") {
-			if index != IndexToken && index != IndexContract {
-				return errors.Errorf("Invalid index: %s", index)
-			}
+	switch req.Type {
+	case "":
+		req.Type = TypeToken
+	case TypeToken:
+	case TypeContract:
+		if req.Creator != "" || req.Tag != "" || req.Mime != "" {
+			return errors.New("creator, tag and mime filters are supported for tokens only")
 		}
+	default:
+		return errors.Errorf("invalid type: %s. Should be '%s' or '%s'", req.Type, TypeToken, TypeContract)
 	}
 
-	if req.Limit == 0 || req.Limit > maxLimit {
-		req.Limit = maxLimit
+	req.Query = strings.TrimSpace(req.Query)
+	if req.Query != "" {
+		if len(req.Query) < minQueryLength || len(req.Query) > maxQueryLength {
+			return errors.Errorf("invalid query string length: %d. Should be from %d to %d symbols", len(req.Query), minQueryLength, maxQueryLength)
+		}
+		if strings.ContainsAny(req.Query, luceneReserved) || strings.Contains(req.Query, "&&") || strings.Contains(req.Query, "||") {
+			return errors.Errorf("invalid query string: %s. Query syntax is not supported, characters %s are reserved", req.Query, luceneReserved)
+		}
+	}
+
+	if req.Status != "" {
+		status, err := models.ParseStatus(req.Status)
+		if err != nil {
+			return err
+		}
+		req.status = status
 	}
-	if req.Offset < 0 {
-		req.Offset = 0
+
+	if req.Limit <= 0 {
+		req.Limit = defaultLimit
+	} else if req.Limit > maxLimit {
+		req.Limit = maxLimit
 	}
 
 	if req.Sort == "" {
 		req.Sort = "created_at:desc"
-	} else {
-		parts := strings.Split(req.Sort, ":")
-		if len(parts) != 2 || (parts[1] != "asc" && parts[1] != "desc") {
-			return errors.Errorf("Invalid sort: %s. Should be a string of <field>:<direction> pair. direction is 'asc' or 'desc'", req.Sort)
+		if req.Query != "" {
+			req.Sort = "relevance:desc"
 		}
 	}
+	parts := strings.Split(req.Sort, ":")
+	if len(parts) != 2 || (parts[1] != "asc" && parts[1] != "desc") {
+		return errors.Errorf("invalid sort: %s. Should be a string of <field>:<direction> pair. direction is 'asc' or 'desc'", req.Sort)
+	}
+	field, ok := sortFields[parts[0]]
+	if !ok {
+		return errors.Errorf("invalid sort field: %s. Should be 'relevance', 'created_at' or 'updated_at'", parts[0])
+	}
+	req.sortField = field
+	req.sortOrder = parts[1]
 
-	if len(req.Query) < 2 {
-		return errors.Errorf("Invalid query string: %s. Should be at least 2 symbols in length", req.Query)
+	if req.Cursor != "" {
+		after, err := decodeCursor(req.Cursor, len(req.sort()))
+		if err != nil {
+			return err
+		}
+		req.after = after
 	}
 	return nil
 }
 
+// searcher - backend of search API: Elasticsearch or Postgres full text search
+type searcher interface {
+	Search(ctx context.Context, req searchRequest) (SearchResult, error)
+}
+
+var backend searcher
+
 func search(c echo.Context) error {
 	var req searchRequest
 	if err := c.Bind(&req); err != nil {
@@ -67,26 +139,16 @@ func search(c echo.Context) error {
 	}
 
 	if err := req.validate(); err != nil {
-		return err
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
 	}
 
-	log.Println(req)
-
-	resp, err := es.Search(
-		es.Search.WithFrom(req.Offset),
-		es.Search.WithIndex(req.Index),
-		es.Search.WithSort(req.Sort),
-		es.Search.WithSize(req.Limit),
-		es.Search.WithQuery(req.Query),
-	)
+	result, err := backend.Search(c.Request().Context(), req)
 	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	if resp.IsError() {
-		return errors.New(resp.String())
+		if errors.Is(err, errInvalidCursor) {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		log.Err(err).Msg("search")
+		return echo.NewHTTPError(http.StatusInternalServerError, "search failed")
 	}
-
-	return c.Stream(http.StatusOK, "application/json", resp.Body)
+	return c.JSON(http.StatusOK, result)
 }
@@ -0,0 +1,196 @@
+package main
+
+import (
+	stdJSON "encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dipdup-net/metadata/cmd/metadata/models"
+)
+
+func Test_searchRequest_validate(t *testing.T) {
+	tests := []struct {
+		name      string
+		req       searchRequest
+		wantSort  []map[string]any
+		wantLimit int
+		wantErr   bool
+	}{
+		{
+			name:      "defaults without query",
+			req:       searchRequest{},
+			wantSort:  []map[string]any{{"created_at": "desc"}, {"update_id": "desc"}},
+			wantLimit: defaultLimit,
+		}, {
+			name:      "defaults with query",
+			req:       searchRequest{Query: "hedgehoge", Limit: 1000},
+			wantSort:  []map[string]any{{"_score": "desc"}, {"update_id": "desc"}},
+			wantLimit: maxLimit,
+		}, {
+			name:      "explicit sort",
+			req:       searchRequest{Sort: "updated_at:asc", Limit: 10},
+			wantSort:  []map[string]any{{"updated_at": "asc"}, {"update_id": "asc"}},
+			wantLimit: 10,
+		}, {
+			name:    "field query",
+			req:     searchRequest{Query: "metadata.name:test"},
+			wantErr: true,
+		}, {
+			name:    "wildcard",
+			req:     searchRequest{Query: "hedge*"},
+			wantErr: true,
+		}, {
+			name:    "boolean operators",
+			req:     searchRequest{Query: "a && b"},
+			wantErr: true,
+		}, {
+			name:    "short query",
+			req:     searchRequest{Query: "a"},
+			wantErr: true,
+		}, {
+			name:    "unknown sort field",
+			req:     searchRequest{Sort: "metadata.name:asc"},
+			wantErr: true,
+		}, {
+			name:    "invalid sort direction",
+			req:     searchRequest{Sort: "created_at:up"},
+			wantErr: true,
+		}, {
+			name:    "unknown type",
+			req:     searchRequest{Type: "operation"},
+			wantErr: true,
+		}, {
+			name:    "token filter of contracts",
+			req:     searchRequest{Type: TypeContract, Tag: "art"},
+			wantErr: true,
+		}, {
+			name:    "unknown status",
+			req:     searchRequest{Status: "pending"},
+			wantErr: true,
+		}, {
+			name:    "invalid cursor",
+			req:     searchRequest{Cursor: "not a cursor"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.req.validate()
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantSort, tt.req.sort())
+			assert.Equal(t, tt.wantLimit, tt.req.Limit)
+		})
+	}
+}
+
+func Test_searchRequest_body(t *testing.T) {
+	req := searchRequest{
+		Query:   "hedgehoge",
+		Network: "mainnet",
+		Creator: "tz1",
+		Mime:    "image/png",
+		Status:  "applied",
+		Limit:   2,
+	}
+	require.NoError(t, req.validate())
+
+	data, err := stdJSON.Marshal(req.body())
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"query": {"bool": {
+			"filter": [
+				{"term": {"network.keyword": "mainnet"}},
+				{"term": {"creators": "tz1"}},
+				{"term": {"metadata.formats.mimeType": "image/png"}},
+				{"term": {"status": 3}}
+			],
+			"must": {"multi_match": {"query": "hedgehoge", "fields": ["name^3", "symbol^3", "metadata.description"]}}
+		}},
+		"sort": [{"_score": "desc"}, {"update_id": "desc"}],
+		"size": 2,
+		"_source": ["network", "contract", "token_id", "link", "status", "metadata", "created_at", "updated_at", "name", "symbol", "decimals", "artifact_uri", "display_uri", "thumbnail_uri", "creators", "tags"]
+	}`, string(data))
+}
+
+func Test_cursor(t *testing.T) {
+	cursor, err := encodeCursor([]stdJSON.RawMessage{stdJSON.RawMessage(`1.5`), stdJSON.RawMessage(`9007199254740993`)})
+	require.NoError(t, err)
+
+	after, err := decodeCursor(cursor, 2)
+	require.NoError(t, err)
+	data, err := stdJSON.Marshal(after)
+	require.NoError(t, err)
+	assert.Equal(t, `[1.5,9007199254740993]`, string(data), "big integers must be kept as is")
+
+	_, err = decodeCursor(cursor, 3)
+	assert.Error(t, err)
+
+	nested, err := encodeCursor([]stdJSON.RawMessage{stdJSON.RawMessage(`{"script":"x"}`), stdJSON.RawMessage(`1`)})
+	require.NoError(t, err)
+	_, err = decodeCursor(nested, 2)
+	assert.Error(t, err)
+}
+
+func Test_search(t *testing.T) {
+	var received []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/"+IndexContract+"/_search", r.URL.Path)
+		received, _ = io.ReadAll(r.Body)
+
+		w.Header().Set("X-Elastic-Product", "Elasticsearch")
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"took":1,"_shards":{"total":1},"hits":{"total":{"value":2},"hits":[
+			{"_index":"contract_metadata","_id":"mainnet:KT1A","_score":null,"_source":{"network":"mainnet","contract":"KT1A","status":3,"created_at":10,"updated_at":11,"metadata":{"name":"Contract A"}},"sort":[11000,5]},
+			{"_index":"contract_metadata","_id":"mainnet:KT1B","_score":null,"_source":{"network":"mainnet","contract":"KT1B","status":2,"created_at":8,"updated_at":9},"sort":[9000,4]}
+		]}}`))
+	}))
+	defer server.Close()
+
+	client, err := elasticsearch.NewClient(elasticsearch.Config{Addresses: []string{server.URL}})
+	require.NoError(t, err)
+	backend = elasticSearcher{client: client}
+
+	query := url.Values{
+		"type":    {TypeContract},
+		"network": {"mainnet"},
+		"sort":    {"updated_at:desc"},
+		"limit":   {"2"},
+	}
+	e := echo.New()
+	rec := httptest.NewRecorder()
+	c := e.NewContext(httptest.NewRequest(http.MethodGet, "/search?"+query.Encode(), nil), rec)
+	require.NoError(t, search(c))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var result SearchResult
+	require.NoError(t, stdJSON.Unmarshal(rec.Body.Bytes(), &result))
+	require.Len(t, result.Items, 2)
+	assert.Equal(t, "Contract A", result.Items[0].Name)
+	assert.Equal(t, models.StatusApplied.String(), result.Items[0].Status)
+	assert.Equal(t, TypeContract, result.Items[1].Type)
+	assert.Equal(t, "failed", result.Items[1].Status)
+	assert.NotContains(t, rec.Body.String(), "_index", "internals of Elasticsearch must not be exposed")
+
+	after, err := decodeCursor(result.Cursor, 2)
+	require.NoError(t, err)
+	data, err := stdJSON.Marshal(after)
+	require.NoError(t, err)
+	assert.Equal(t, `[9000,4]`, string(data))
+
+	var body map[string]any
+	require.NoError(t, stdJSON.Unmarshal(received, &body))
+	assert.EqualValues(t, 2, body["size"])
+	assert.NotContains(t, body, "search_after")
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"bytes"
+	stdJSON "encoding/json"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	defaultSuggestLimit = 10
+	maxSuggestLimit     = 25
+
+	maxPrefixLength = 64
+)
+
+// suggestSourceFields - fields of suggested documents which are enough to render an item of picker
+var suggestSourceFields = []string{
+	"network", "contract", "token_id", "status", "name", "symbol", "decimals", "thumbnail_uri", "metadata.name",
+}
+
+type suggestRequest struct {
+	Prefix  string `query:"q"`
+	Type    string `query:"type"`
+	Network string `query:"network"`
+	Limit   int    `query:"limit"`
+}
+
+func (req *suggestRequest) validate() error {
+	switch req.Type {
+	case "", TypeToken, TypeContract:
+	default:
+		return errors.Errorf("invalid type: %s. Should be '%s' or '%s'", req.Type, TypeToken, TypeContract)
+	}
+
+	req.Prefix = strings.TrimLeft(req.Prefix, " ")
+	if req.Prefix == "" {
+		return errors.New("empty prefix")
+	}
+	if utf8.RuneCountInString(req.Prefix) > maxPrefixLength {
+		return errors.Errorf("too long prefix. Should be up to %d symbols", maxPrefixLength)
+	}
+
+	if req.Limit <= 0 {
+		req.Limit = defaultSuggestLimit
+	} else if req.Limit > maxSuggestLimit {
+		req.Limit = maxSuggestLimit
+	}
+	return nil
+}
+
+// index - suggestions are searched over both indices if type isn't set
+func (req *suggestRequest) index() string {
+	switch req.Type {
+	case TypeToken:
+		return IndexToken
+	case TypeContract:
+		return IndexContract
+	default:
+		return strings.Join([]string{IndexToken, IndexContract}, ",")
+	}
+}
+
+func (req *suggestRequest) body() map[string]any {
+	completion := map[string]any{
+		"field":           "suggest",
+		"size":            req.Limit,
+		"skip_duplicates": true,
+	}
+	if req.Network != "" {
+		completion["contexts"] = map[string]any{
+			"network": []string{req.Network},
+		}
+	}
+	return map[string]any{
+		"_source": suggestSourceFields,
+		"suggest": map[string]any{
+			"names": map[string]any{
+				"prefix":     req.Prefix,
+				"completion": completion,
+			},
+		},
+	}
+}
+
+type suggestResponse struct {
+	Suggest struct {
+		Names []struct {
+			Options []suggestOption `json:"options"`
+		} `json:"names"`
+	} `json:"suggest"`
+}
+
+type suggestOption struct {
+	Text   string       `json:"text"`
+	Index  string       `json:"_index"`
+	Score  *float64     `json:"_score"`
+	Source searchSource `json:"_source"`
+}
+
+// SuggestItem - token or contract which name or symbol starts with requested prefix. `Text` is the matched input, `Score` is popularity of contract.
+type SuggestItem struct {
+	Type         string   `json:"type"`
+	Network      string   `json:"network"`
+	Contract     string   `json:"contract"`
+	TokenID      string   `json:"token_id,omitempty"`
+	Name         string   `json:"name,omitempty"`
+	Symbol       string   `json:"symbol,omitempty"`
+	Decimals     *int     `json:"decimals,omitempty"`
+	ThumbnailURI string   `json:"thumbnail_uri,omitempty"`
+	Text         string   `json:"text"`
+	Score        *float64 `json:"score,omitempty"`
+}
+
+// SuggestResult -
+type SuggestResult struct {
+	Items []SuggestItem `json:"items"`
+}
+
+func newSuggestResult(response suggestResponse) SuggestResult {
+	result := SuggestResult{
+		Items: make([]SuggestItem, 0),
+	}
+	for _, names := range response.Suggest.Names {
+		for _, option := range names.Options {
+			typ := TypeToken
+			if strings.HasPrefix(option.Index, IndexContract) {
+				typ = TypeContract
+			}
+			item := newSearchItem(typ, searchHit{Source: option.Source})
+			result.Items = append(result.Items, SuggestItem{
+				Type:         item.Type,
+				Network:      item.Network,
+				Contract:     item.Contract,
+				TokenID:      item.TokenID,
+				Name:         item.Name,
+				Symbol:       item.Symbol,
+				Decimals:     item.Decimals,
+				ThumbnailURI: item.ThumbnailURI,
+				Text:         option.Text,
+				Score:        option.Score,
+			})
+		}
+	}
+	return result
+}
+
+func suggest(c echo.Context) error {
+	var req suggestRequest
+	if err := c.Bind(&req); err != nil {
+		return err
+	}
+
+	if err := req.validate(); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	data, err := stdJSON.Marshal(req.body())
+	if err != nil {
+		return err
+	}
+
+	resp, err := es.Search(
+		es.Search.WithContext(c.Request().Context()),
+		es.Search.WithIndex(req.index()),
+		es.Search.WithBody(bytes.NewReader(data)),
+	)
+	if err != nil {
+		log.Err(err).Msg("suggest")
+		return echo.NewHTTPError(http.StatusInternalServerError, "suggest failed")
+	}
+	defer resp.Body.Close()
+
+	if resp.IsError() {
+		log.Error().Str("response", resp.String()).Msg("suggest")
+		return echo.NewHTTPError(http.StatusInternalServerError, "suggest failed")
+	}
+
+	var response suggestResponse
+	if err := stdJSON.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, newSuggestResult(response))
+}
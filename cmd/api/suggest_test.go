@@ -0,0 +1,99 @@
+package main
+
+import (
+	stdJSON "encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_suggestRequest_validate(t *testing.T) {
+	tests := []struct {
+		name      string
+		req       suggestRequest
+		wantIndex string
+		wantLimit int
+		wantErr   bool
+	}{
+		{
+			name:      "both types",
+			req:       suggestRequest{Prefix: "he"},
+			wantIndex: "token_metadata,contract_metadata",
+			wantLimit: defaultSuggestLimit,
+		}, {
+			name:      "tokens",
+			req:       suggestRequest{Prefix: "h", Type: TypeToken, Limit: 100},
+			wantIndex: IndexToken,
+			wantLimit: maxSuggestLimit,
+		}, {
+			name:    "empty prefix",
+			req:     suggestRequest{Prefix: "  "},
+			wantErr: true,
+		}, {
+			name:    "invalid type",
+			req:     suggestRequest{Prefix: "he", Type: "tokens"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.req.validate()
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantIndex, tt.req.index())
+			assert.Equal(t, tt.wantLimit, tt.req.Limit)
+		})
+	}
+}
+
+func Test_suggest(t *testing.T) {
+	var received []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/"+IndexToken+","+IndexContract+"/_search", r.URL.Path)
+		received, _ = io.ReadAll(r.Body)
+
+		w.Header().Set("X-Elastic-Product", "Elasticsearch")
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"took":1,"suggest":{"names":[{"text":"he","offset":0,"length":2,"options":[
+			{"text":"Hic et nunc","_index":"contract_metadata","_id":"mainnet:KT1H","_score":512.0,"_source":{"network":"mainnet","contract":"KT1H","status":3,"metadata":{"name":"Hic et nunc"}},"contexts":{"network":["mainnet"]}},
+			{"text":"HEH","_index":"token_metadata","_id":"mainnet:KT1G:0","_score":1.0,"_source":{"network":"mainnet","contract":"KT1G","token_id":"0","status":3,"name":"Hedgehoge","symbol":"HEH","decimals":6}}
+		]}]}}`))
+	}))
+	defer server.Close()
+
+	client, err := elasticsearch.NewClient(elasticsearch.Config{Addresses: []string{server.URL}})
+	require.NoError(t, err)
+	es = client
+
+	e := echo.New()
+	rec := httptest.NewRecorder()
+	c := e.NewContext(httptest.NewRequest(http.MethodGet, "/suggest?q=he&network=mainnet", nil), rec)
+	require.NoError(t, suggest(c))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	decimals := 6
+	contractScore, tokenScore := 512.0, 1.0
+	var result SuggestResult
+	require.NoError(t, stdJSON.Unmarshal(rec.Body.Bytes(), &result))
+	assert.Equal(t, []SuggestItem{
+		{Type: TypeContract, Network: "mainnet", Contract: "KT1H", Name: "Hic et nunc", Text: "Hic et nunc", Score: &contractScore},
+		{Type: TypeToken, Network: "mainnet", Contract: "KT1G", TokenID: "0", Name: "Hedgehoge", Symbol: "HEH", Decimals: &decimals, Text: "HEH", Score: &tokenScore},
+	}, result.Items)
+
+	assert.JSONEq(t, `{
+		"_source": ["network", "contract", "token_id", "status", "name", "symbol", "decimals", "thumbnail_uri", "metadata.name"],
+		"suggest": {"names": {
+			"prefix": "he",
+			"completion": {"field": "suggest", "size": 10, "skip_duplicates": true, "contexts": {"network": ["mainnet"]}}
+		}}
+	}`, string(received))
+}
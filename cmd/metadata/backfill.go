@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+
+	"github.com/dipdup-net/metadata/cmd/metadata/config"
+	"github.com/dipdup-net/metadata/cmd/metadata/models"
+)
+
+const backfillBatchSize = 1000
+
+func newBackfillCmd(configPath *string) *cobra.Command {
+	return &cobra.Command{
+		Use:   "backfill",
+		Short: "Fill normalized columns of already applied token metadata",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.Load(*configPath)
+			if err != nil {
+				return err
+			}
+
+			if isElastic(cfg.Database) {
+				return errors.Errorf("backfill is not supported by database %s", cfg.Database.Kind)
+			}
+
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			// columns are added by scripts
+			if err := execScripts(ctx, cfg.Database); err != nil {
+				return errors.Wrap(err, "execScripts")
+			}
+
+			db, err := models.NewDatabase(ctx, cfg.Database)
+			if err != nil {
+				return errors.Wrap(err, "models.NewDatabase")
+			}
+			defer db.Close()
+
+			return backfillTokens(ctx, models.NewTokens(db.PgGo))
+		},
+	}
+}
+
+type normalizedTokensRepository interface {
+	GetApplied(ctx context.Context, from uint64, limit int) ([]*models.TokenMetadata, error)
+	SetNormalized(ctx context.Context, metadata []*models.TokenMetadata) error
+}
+
+// backfillTokens - normalizes all applied token metadata by batches sorted by id
+func backfillTokens(ctx context.Context, repo normalizedTokensRepository) error {
+	var (
+		from  uint64
+		count int
+	)
+	for {
+		tokens, err := repo.GetApplied(ctx, from, backfillBatchSize)
+		if err != nil {
+			return errors.Wrap(err, "GetApplied")
+		}
+		if len(tokens) == 0 {
+			break
+		}
+
+		for i := range tokens {
+			tokens[i].Normalize()
+		}
+		if err := repo.SetNormalized(ctx, tokens); err != nil {
+			return errors.Wrap(err, "SetNormalized")
+		}
+
+		from = tokens[len(tokens)-1].ID
+		count += len(tokens)
+		log.Info().Int("count", count).Uint64("last_id", from).Msg("token metadata normalized")
+
+		if len(tokens) < backfillBatchSize {
+			break
+		}
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dipdup-net/metadata/cmd/metadata/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memoryTokens struct {
+	tokens  []*models.TokenMetadata
+	updated map[uint64]models.TokenMetadata
+}
+
+func (m *memoryTokens) GetApplied(ctx context.Context, from uint64, limit int) ([]*models.TokenMetadata, error) {
+	result := make([]*models.TokenMetadata, 0)
+	for i := range m.tokens {
+		if m.tokens[i].ID <= from || m.tokens[i].Status != models.StatusApplied {
+			continue
+		}
+		token := *m.tokens[i]
+		result = append(result, &token)
+		if len(result) == limit {
+			break
+		}
+	}
+	return result, nil
+}
+
+func (m *memoryTokens) SetNormalized(ctx context.Context, metadata []*models.TokenMetadata) error {
+	for i := range metadata {
+		m.updated[metadata[i].ID] = *metadata[i]
+	}
+	return nil
+}
+
+func Test_backfillTokens(t *testing.T) {
+	repo := &memoryTokens{
+		updated: make(map[uint64]models.TokenMetadata),
+	}
+	for id := uint64(1); id <= backfillBatchSize+10; id++ {
+		status := models.StatusApplied
+		if id%100 == 0 {
+			status = models.StatusFailed
+		}
+		repo.tokens = append(repo.tokens, &models.TokenMetadata{
+			ID:       id,
+			Status:   status,
+			Metadata: models.JSONB(`{"name":"token","symbol":"TKN","decimals":"6"}`),
+		})
+	}
+
+	require.NoError(t, backfillTokens(context.Background(), repo))
+	assert.Len(t, repo.updated, backfillBatchSize)
+
+	token, ok := repo.updated[backfillBatchSize+10]
+	require.True(t, ok)
+	assert.Equal(t, "token", token.Name)
+	assert.Equal(t, "TKN", token.Symbol)
+	require.NotNil(t, token.Decimals)
+	assert.Equal(t, 6, *token.Decimals)
+
+	_, ok = repo.updated[100]
+	assert.False(t, ok)
+}
@@ -0,0 +1,151 @@
+package broker
+
+import (
+	"sync"
+
+	"github.com/dipdup-net/metadata/cmd/metadata/models"
+)
+
+// Broker - in-process publisher of metadata changes. It's fed from database by `Feed`. Subscribers which can't keep up are disconnected and should resume from the last received update id.
+type Broker struct {
+	subscriptions map[*Subscription]struct{}
+	bufferSize    int
+	closed        bool
+
+	mx sync.RWMutex
+}
+
+// New -
+func New(opts ...BrokerOption) *Broker {
+	b := &Broker{
+		subscriptions: make(map[*Subscription]struct{}),
+		bufferSize:    1024,
+	}
+
+	for i := range opts {
+		opts[i](b)
+	}
+
+	return b
+}
+
+// Subscribe -
+func (b *Broker) Subscribe(filter Filter) *Subscription {
+	sub := &Subscription{
+		filter: filter,
+		events: make(chan Event, b.bufferSize),
+		broker: b,
+	}
+
+	b.mx.Lock()
+	defer b.mx.Unlock()
+
+	if b.closed {
+		close(sub.events)
+		return sub
+	}
+	b.subscriptions[sub] = struct{}{}
+	return sub
+}
+
+// Publish -
+func (b *Broker) Publish(events ...Event) {
+	if b == nil || len(events) == 0 {
+		return
+	}
+
+	b.mx.Lock()
+	defer b.mx.Unlock()
+
+	for sub := range b.subscriptions {
+		for i := range events {
+			if !sub.filter.Match(events[i]) {
+				continue
+			}
+
+			select {
+			case sub.events <- events[i]:
+			default:
+				b.unsubscribe(sub)
+			}
+
+			if _, ok := b.subscriptions[sub]; !ok {
+				break
+			}
+		}
+	}
+}
+
+// PublishContracts - publishes events about committed contract metadata
+func (b *Broker) PublishContracts(contracts []models.ContractMetadata) {
+	if b == nil {
+		return
+	}
+
+	events := make([]Event, 0, len(contracts))
+	for i := range contracts {
+		// rows skipped by repository don't receive update id
+		if contracts[i].UpdateID == 0 {
+			continue
+		}
+		events = append(events, NewContractEvent(contracts[i]))
+	}
+	b.Publish(events...)
+}
+
+// PublishTokens - publishes events about committed token metadata
+func (b *Broker) PublishTokens(tokens []models.TokenMetadata) {
+	if b == nil {
+		return
+	}
+
+	events := make([]Event, 0, len(tokens))
+	for i := range tokens {
+		// rows skipped by repository don't receive update id
+		if tokens[i].UpdateID == 0 {
+			continue
+		}
+		events = append(events, NewTokenEvent(tokens[i]))
+	}
+	b.Publish(events...)
+}
+
+// Close - closes all subscriptions
+func (b *Broker) Close() error {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+
+	for sub := range b.subscriptions {
+		b.unsubscribe(sub)
+	}
+	b.closed = true
+	return nil
+}
+
+func (b *Broker) unsubscribe(sub *Subscription) {
+	if _, ok := b.subscriptions[sub]; !ok {
+		return
+	}
+	delete(b.subscriptions, sub)
+	close(sub.events)
+}
+
+// Subscription -
+type Subscription struct {
+	filter Filter
+	events chan Event
+	broker *Broker
+}
+
+// Events - channel of events. It's closed when subscription is closed or subscriber is too slow.
+func (sub *Subscription) Events() <-chan Event {
+	return sub.events
+}
+
+// Close -
+func (sub *Subscription) Close() {
+	sub.broker.mx.Lock()
+	defer sub.broker.mx.Unlock()
+
+	sub.broker.unsubscribe(sub)
+}
@@ -0,0 +1,92 @@
+package broker
+
+import (
+	"testing"
+
+	"github.com/dipdup-net/metadata/cmd/metadata/models"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testContract = "KT1G1cCRNBgQ48mVDjopHjEmTN5Sbtar8nn9"
+
+func TestFilter_Match(t *testing.T) {
+	event := Event{Type: TypeToken, Network: "mainnet", Contract: testContract}
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   bool
+	}{
+		{name: "empty filter", filter: Filter{}, want: true},
+		{name: "all fields", filter: Filter{Type: TypeToken, Network: "mainnet", Contract: testContract}, want: true},
+		{name: "other type", filter: Filter{Type: TypeContract}, want: false},
+		{name: "other network", filter: Filter{Network: "ghostnet"}, want: false},
+		{name: "other contract", filter: Filter{Contract: "KT1RJ6PbjHpwc3M5rw5s2Nbmefwbuwbdxton"}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.filter.Match(event))
+		})
+	}
+}
+
+func TestBroker_Publish(t *testing.T) {
+	b := New()
+
+	tokens := b.Subscribe(Filter{Type: TypeToken, Network: "mainnet"})
+	contracts := b.Subscribe(Filter{Type: TypeContract})
+
+	b.PublishTokens([]models.TokenMetadata{
+		{Network: "mainnet", Contract: testContract, TokenID: decimal.NewFromInt(1), Status: models.StatusNew, UpdateID: 1},
+		{Network: "ghostnet", Contract: testContract, TokenID: decimal.NewFromInt(2), Status: models.StatusNew, UpdateID: 2},
+		{Network: "mainnet", Contract: testContract, TokenID: decimal.NewFromInt(3), Status: models.StatusNew},
+	})
+	b.PublishContracts([]models.ContractMetadata{
+		{Network: "ghostnet", Contract: testContract, Status: models.StatusApplied, UpdateID: 7},
+	})
+	require.NoError(t, b.Close())
+
+	tokenID := decimal.NewFromInt(1)
+	assert.Equal(t, []Event{
+		{Type: TypeToken, Network: "mainnet", Contract: testContract, TokenID: &tokenID, Status: "new", UpdateID: 1},
+	}, drain(tokens))
+	assert.Equal(t, []Event{
+		{Type: TypeContract, Network: "ghostnet", Contract: testContract, Status: "applied", UpdateID: 7},
+	}, drain(contracts))
+}
+
+func TestBroker_slowSubscriber(t *testing.T) {
+	b := New(WithBufferSize(2))
+
+	slow := b.Subscribe(Filter{})
+	b.Publish(Event{UpdateID: 1}, Event{UpdateID: 2}, Event{UpdateID: 3})
+
+	assert.Equal(t, []Event{{UpdateID: 1}, {UpdateID: 2}}, drain(slow))
+
+	// closing of dropped subscription is no-op
+	slow.Close()
+
+	fast := b.Subscribe(Filter{})
+	b.Publish(Event{UpdateID: 4})
+	fast.Close()
+	assert.Equal(t, []Event{{UpdateID: 4}}, drain(fast))
+}
+
+func TestBroker_nil(t *testing.T) {
+	var b *Broker
+	assert.NotPanics(t, func() {
+		b.Publish(Event{UpdateID: 1})
+		b.PublishTokens([]models.TokenMetadata{{UpdateID: 1}})
+		b.PublishContracts([]models.ContractMetadata{{UpdateID: 1}})
+	})
+}
+
+func drain(sub *Subscription) []Event {
+	events := make([]Event, 0)
+	for event := range sub.Events() {
+		events = append(events, event)
+	}
+	return events
+}
@@ -0,0 +1,67 @@
+package broker
+
+import (
+	"github.com/dipdup-net/metadata/cmd/metadata/models"
+	"github.com/shopspring/decimal"
+)
+
+// event types
+const (
+	TypeContract = "contract"
+	TypeToken    = "token"
+)
+
+// Event - notification about metadata change
+type Event struct {
+	Type     string           `json:"type"`
+	Network  string           `json:"network"`
+	Contract string           `json:"contract"`
+	TokenID  *decimal.Decimal `json:"token_id,omitempty"`
+	Status   string           `json:"status"`
+	UpdateID int64            `json:"update_id"`
+}
+
+// NewContractEvent -
+func NewContractEvent(cm models.ContractMetadata) Event {
+	return Event{
+		Type:     TypeContract,
+		Network:  cm.Network,
+		Contract: cm.Contract,
+		Status:   cm.Status.String(),
+		UpdateID: cm.UpdateID,
+	}
+}
+
+// NewTokenEvent -
+func NewTokenEvent(tm models.TokenMetadata) Event {
+	tokenID := tm.TokenID
+	return Event{
+		Type:     TypeToken,
+		Network:  tm.Network,
+		Contract: tm.Contract,
+		TokenID:  &tokenID,
+		Status:   tm.Status.String(),
+		UpdateID: tm.UpdateID,
+	}
+}
+
+// Filter - subscription filter. Empty fields match any value.
+type Filter struct {
+	Type     string
+	Network  string
+	Contract string
+}
+
+// Match -
+func (f Filter) Match(event Event) bool {
+	if f.Type != "" && f.Type != event.Type {
+		return false
+	}
+	if f.Network != "" && f.Network != event.Network {
+		return false
+	}
+	if f.Contract != "" && f.Contract != event.Contract {
+		return false
+	}
+	return true
+}
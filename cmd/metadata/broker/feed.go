@@ -0,0 +1,154 @@
+package broker
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/dipdup-net/metadata/cmd/metadata/models"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+// Source - committed metadata of network sorted by update id. Greatest returned update id should be a safe cursor, see `models.Database.ContractsAfter`.
+type Source interface {
+	LastUpdateIDs(ctx context.Context, network string) (contracts int64, tokens int64, err error)
+	ContractsAfter(ctx context.Context, network, contract string, updateID int64, limit int) ([]models.ContractMetadata, error)
+	TokensAfter(ctx context.Context, network, contract string, updateID int64, limit int) ([]models.TokenMetadata, error)
+}
+
+// Feed - publishes metadata committed to database to broker. Database is polled by update id, so subscribers receive changes written by every instance sharing the database.
+type Feed struct {
+	source   Source
+	broker   *Broker
+	networks []string
+
+	interval time.Duration
+	limit    int
+
+	wg *sync.WaitGroup
+}
+
+// FeedOption -
+type FeedOption func(*Feed)
+
+// WithPollInterval - interval of database polling. Default: 1 second.
+func WithPollInterval(interval time.Duration) FeedOption {
+	return func(f *Feed) {
+		if interval > 0 {
+			f.interval = interval
+		}
+	}
+}
+
+// WithPollLimit - count of rows read by one query. Default: 100.
+func WithPollLimit(limit int) FeedOption {
+	return func(f *Feed) {
+		if limit > 0 {
+			f.limit = limit
+		}
+	}
+}
+
+// NewFeed -
+func NewFeed(source Source, broker *Broker, networks []string, opts ...FeedOption) *Feed {
+	f := &Feed{
+		source:   source,
+		broker:   broker,
+		networks: networks,
+		interval: time.Second,
+		limit:    100,
+		wg:       new(sync.WaitGroup),
+	}
+
+	for i := range opts {
+		opts[i](f)
+	}
+
+	return f
+}
+
+// Start - publishes changes committed after start. Earlier changes are read by subscribers from database.
+func (f *Feed) Start(ctx context.Context) {
+	for i := range f.networks {
+		f.wg.Add(1)
+		go f.poll(ctx, f.networks[i])
+	}
+}
+
+// Close - waits till polling is stopped by context
+func (f *Feed) Close() error {
+	f.wg.Wait()
+	return nil
+}
+
+func (f *Feed) poll(ctx context.Context, network string) {
+	defer f.wg.Done()
+
+	ticker := time.NewTicker(f.interval)
+	defer ticker.Stop()
+
+	var (
+		contracts, tokens int64
+		started           bool
+	)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if !started {
+				var err error
+				contracts, tokens, err = f.source.LastUpdateIDs(ctx, network)
+				if err != nil {
+					if !errors.Is(err, context.Canceled) {
+						log.Err(err).Str("network", network).Msg("feed: last update ids")
+					}
+					continue
+				}
+				started = true
+				continue
+			}
+
+			var err error
+			if contracts, err = f.publishContracts(ctx, network, contracts); err != nil && !errors.Is(err, context.Canceled) {
+				log.Err(err).Str("network", network).Msg("feed: contracts")
+			}
+			if tokens, err = f.publishTokens(ctx, network, tokens); err != nil && !errors.Is(err, context.Canceled) {
+				log.Err(err).Str("network", network).Msg("feed: tokens")
+			}
+		}
+	}
+}
+
+func (f *Feed) publishContracts(ctx context.Context, network string, after int64) (int64, error) {
+	for {
+		contracts, err := f.source.ContractsAfter(ctx, network, "", after, f.limit)
+		if err != nil {
+			return after, err
+		}
+		if len(contracts) > 0 {
+			after = contracts[len(contracts)-1].UpdateID
+			f.broker.PublishContracts(contracts)
+		}
+		if len(contracts) < f.limit {
+			return after, nil
+		}
+	}
+}
+
+func (f *Feed) publishTokens(ctx context.Context, network string, after int64) (int64, error) {
+	for {
+		tokens, err := f.source.TokensAfter(ctx, network, "", after, f.limit)
+		if err != nil {
+			return after, err
+		}
+		if len(tokens) > 0 {
+			after = tokens[len(tokens)-1].UpdateID
+			f.broker.PublishTokens(tokens)
+		}
+		if len(tokens) < f.limit {
+			return after, nil
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package broker
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dipdup-net/metadata/cmd/metadata/models"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stubSource struct {
+	mx        sync.Mutex
+	contracts []models.ContractMetadata
+	tokens    []models.TokenMetadata
+}
+
+func (s *stubSource) LastUpdateIDs(ctx context.Context, network string) (int64, int64, error) {
+	return 1, 1, nil
+}
+
+func (s *stubSource) ContractsAfter(ctx context.Context, network, contract string, updateID int64, limit int) ([]models.ContractMetadata, error) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	result := make([]models.ContractMetadata, 0)
+	for i := range s.contracts {
+		if s.contracts[i].Network == network && s.contracts[i].UpdateID > updateID && len(result) < limit {
+			result = append(result, s.contracts[i])
+		}
+	}
+	return result, nil
+}
+
+func (s *stubSource) TokensAfter(ctx context.Context, network, contract string, updateID int64, limit int) ([]models.TokenMetadata, error) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	result := make([]models.TokenMetadata, 0)
+	for i := range s.tokens {
+		if s.tokens[i].Network == network && s.tokens[i].UpdateID > updateID && len(result) < limit {
+			result = append(result, s.tokens[i])
+		}
+	}
+	return result, nil
+}
+
+func (s *stubSource) add(tokens ...models.TokenMetadata) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	s.tokens = append(s.tokens, tokens...)
+}
+
+func TestFeed(t *testing.T) {
+	source := &stubSource{
+		contracts: []models.ContractMetadata{
+			{Network: "mainnet", Contract: testContract, Status: models.StatusApplied, UpdateID: 1},
+		},
+		tokens: []models.TokenMetadata{
+			{Network: "mainnet", Contract: testContract, TokenID: decimal.NewFromInt(1), Status: models.StatusNew, UpdateID: 1},
+		},
+	}
+
+	b := New()
+	sub := b.Subscribe(Filter{Network: "mainnet"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	feed := NewFeed(source, b, []string{"mainnet"}, WithPollInterval(10*time.Millisecond), WithPollLimit(1))
+	feed.Start(ctx)
+
+	// rows committed before start are read by subscribers from database
+	time.Sleep(50 * time.Millisecond)
+	source.add(
+		models.TokenMetadata{Network: "mainnet", Contract: testContract, TokenID: decimal.NewFromInt(2), Status: models.StatusNew, UpdateID: 2},
+		models.TokenMetadata{Network: "ghostnet", Contract: testContract, TokenID: decimal.NewFromInt(3), Status: models.StatusNew, UpdateID: 3},
+		models.TokenMetadata{Network: "mainnet", Contract: testContract, TokenID: decimal.NewFromInt(4), Status: models.StatusRemoved, UpdateID: 4},
+	)
+
+	received := make([]Event, 0)
+	require.Eventually(t, func() bool {
+		for {
+			select {
+			case event := <-sub.Events():
+				received = append(received, event)
+			default:
+				return len(received) >= 2
+			}
+		}
+	}, 5*time.Second, 10*time.Millisecond)
+
+	cancel()
+	require.NoError(t, feed.Close())
+	require.NoError(t, b.Close())
+
+	require.Len(t, received, 2)
+	assert.EqualValues(t, 2, received[0].UpdateID)
+	assert.EqualValues(t, 4, received[1].UpdateID)
+	assert.Equal(t, "removed", received[1].Status)
+	assert.Empty(t, drain(sub))
+}
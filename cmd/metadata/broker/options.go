@@ -0,0 +1,13 @@
+package broker
+
+// BrokerOption -
+type BrokerOption func(*Broker)
+
+// WithBufferSize - size of subscription buffer
+func WithBufferSize(size int) BrokerOption {
+	return func(b *Broker) {
+		if size > 0 {
+			b.bufferSize = size
+		}
+	}
+}
@@ -47,6 +47,16 @@ func substituteDataSources(c *Config, dataSource *MetadataDataSource) error {
 		}
 		dataSource.Tzkt.SetStruct(source)
 	}
+	if name := dataSource.Node.Name(); name != "" {
+		source, ok := c.DataSources[name]
+		if !ok {
+			return errors.Errorf("unknown node data source: %s", name)
+		}
+		if source.Kind != "tezos-node" {
+			return errors.Errorf("Invalid node data source kind. Expected `tezos-node`, got `%s`", source.Kind)
+		}
+		dataSource.Node.SetStruct(source)
+	}
 	return nil
 }
 
@@ -87,6 +97,7 @@ func (f Filters) Addresses() []string {
 // MetadataDataSource -
 type MetadataDataSource struct {
 	Tzkt config.Alias[config.DataSource] `yaml:"tzkt" validate:"url"`
+	Node config.Alias[config.DataSource] `yaml:"node"`
 }
 
 // Settings -
@@ -99,6 +110,56 @@ type Settings struct {
 	Thumbnail              Thumbnail `yaml:"thumbnail"`
 	AWS                    AWS       `yaml:"aws"`
 	MaxCPU                 int       `yaml:"max_cpu,omitempty" validate:"omitempty,min=1"`
+	API                    API       `yaml:"api"`
+	Webhooks               Webhooks  `yaml:"webhooks"`
+	Retry                  Retry     `yaml:"retry"`
+	HTTP                   HTTP      `yaml:"http"`
+}
+
+// API -
+type API struct {
+	Bind string `yaml:"bind" validate:"omitempty,hostname_port"`
+	// AdminToken - bearer token of `/admin` endpoints. They're disabled if it's empty.
+	AdminToken string `yaml:"admin_token"`
+}
+
+// Webhooks -
+type Webhooks struct {
+	Endpoints   map[string]*Webhook `yaml:"endpoints" validate:"omitempty,dive"`
+	MaxAttempts int                 `yaml:"max_attempts" validate:"omitempty,min=1"`
+	Timeout     int                 `yaml:"timeout" validate:"omitempty,min=1"`
+}
+
+// Webhook - endpoint which receives notifications about applied and failed metadata. Empty filters match any value.
+type Webhook struct {
+	URL       string   `yaml:"url" validate:"required,url"`
+	Secret    string   `yaml:"secret" validate:"required"`
+	Networks  []string `yaml:"networks" validate:"omitempty"`
+	Contracts []string `yaml:"contracts" validate:"omitempty,dive,len=36"`
+}
+
+// Retry - schedule of resolving attempts by type of error, e.g. `timeout`, `rate_limited`, `not_found` or `http_request`. Unset fields are taken from built-in policy of the type, types without policy use `default` one.
+type Retry struct {
+	Policies map[string]RetryPolicy `yaml:"policies" validate:"omitempty,dive"`
+}
+
+// RetryPolicy - exponential backoff: attempt N is delayed by `delay * factor^(N-1)` seconds up to `max_delay`, the delay is randomized by `jitter` share
+type RetryPolicy struct {
+	Delay       int     `yaml:"delay" validate:"omitempty,min=1"`
+	MaxDelay    int     `yaml:"max_delay" validate:"omitempty,min=1"`
+	Factor      float64 `yaml:"factor" validate:"omitempty,min=1"`
+	Jitter      float64 `yaml:"jitter" validate:"omitempty,min=0,max=1"`
+	MaxAttempts int     `yaml:"max_attempts" validate:"omitempty,min=1,max=127"`
+	// Revive - failed metadata is retried again by periodic retry of recent metadata
+	Revive *bool `yaml:"revive"`
+}
+
+// HTTP - limits of requests to every host of HTTP metadata links. Host is parked for `open_timeout` seconds after `failure_threshold` consecutive failures.
+type HTTP struct {
+	RateLimit        float64 `yaml:"rate_limit" validate:"omitempty,gt=0"`
+	Burst            int     `yaml:"burst" validate:"omitempty,min=1"`
+	FailureThreshold int     `yaml:"failure_threshold" validate:"omitempty,min=1"`
+	OpenTimeout      int     `yaml:"open_timeout" validate:"omitempty,min=1"`
 }
 
 // AWS -
@@ -128,4 +189,9 @@ type IPFS struct {
 	Fallback  string          `yaml:"fallback" validate:"url"`
 	Delay     int             `yaml:"delay" validate:"min=1"`
 	Providers []ipfs.Provider `yaml:"providers" validate:"omitempty"`
+
+	// QuarantineFailures - consecutive failures of gateway after which it isn't used till health probe succeeds
+	QuarantineFailures int `yaml:"quarantine_failures" validate:"omitempty,min=1"`
+	// ProbeInterval - seconds between health probes of quarantined gateways
+	ProbeInterval int `yaml:"probe_interval" validate:"omitempty,min=1"`
 }
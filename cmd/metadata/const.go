@@ -2,4 +2,6 @@ package main
 
 const (
 	emptyHash = "expru5X1yxJG6ezR2uHMotwMLNmSzQyh5t1vUnhjx4cS6Pv9qE1Sdo"
+
+	actionRemoveKey = "remove_key"
 )
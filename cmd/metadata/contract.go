@@ -3,12 +3,15 @@ package main
 import (
 	"context"
 	"fmt"
+	"time"
 	"unicode/utf8"
 
 	api "github.com/dipdup-net/go-lib/tzkt/data"
 	"github.com/dipdup-net/metadata/cmd/metadata/helpers"
 	"github.com/dipdup-net/metadata/cmd/metadata/models"
+	"github.com/dipdup-net/metadata/cmd/metadata/prometheus"
 	"github.com/dipdup-net/metadata/cmd/metadata/resolver"
+	"github.com/dipdup-net/metadata/cmd/metadata/validation"
 	"github.com/pkg/errors"
 )
 
@@ -20,6 +23,15 @@ func (indexer *Indexer) processContractMetadata(update api.BigMapUpdate) (*model
 		return nil, indexer.tezosKeys.Add(update, indexer.network)
 	}
 
+	if update.Action == actionRemoveKey {
+		indexer.prom.IncrementMetadataCounter(indexer.network, prometheus.MetadataTypeContract, models.StatusRemoved.String())
+		return &models.ContractMetadata{
+			Network:  indexer.network,
+			Contract: update.Contract.Address,
+			Status:   models.StatusRemoved,
+		}, nil
+	}
+
 	link, err := helpers.Decode(update.Content.Value)
 	if err != nil {
 		return nil, err
@@ -47,16 +59,15 @@ func (indexer *Indexer) resolveContractMetadata(ctx context.Context, cm *models.
 			return err
 		}
 		cm.Error = err.Error()
+		cm.ErrorType = string(resolver.Classify(err))
+		next, retry := indexer.retrier.Next(err, int(cm.RetryCount), time.Now())
 		if e, ok := err.(resolver.ResolvingError); ok {
 			indexer.prom.IncrementErrorCounter(indexer.network, e)
 			err = e.Err
-
-			if e.IsFatal() {
-				cm.RetryCount = int8(indexer.settings.MaxRetryCountOnError)
-			}
 		}
 
-		if cm.RetryCount < int8(indexer.settings.MaxRetryCountOnError) {
+		if retry {
+			cm.NextAttemptAt = next.Unix()
 			indexer.logContractMetadata(*cm, fmt.Sprintf("retry: %s", err.Error()))
 		} else {
 			cm.Status = models.StatusFailed
@@ -67,9 +78,13 @@ func (indexer *Indexer) resolveContractMetadata(ctx context.Context, cm *models.
 		if utf8.Valid(resolved.Data) {
 			cm.Status = models.StatusApplied
 			cm.Error = ""
+			cm.ErrorType = ""
+			cm.Sha256 = resolved.Sha256
+			cm.Issues = indexer.validate(prometheus.MetadataTypeContract, resolved.Data, validation.Contract, nil)
 			indexer.log().Int64("response_time", resolved.ResponseTime).Str("contract", cm.Contract).Msg("resolved contract metadata")
 		} else {
 			cm.Error = "invalid json"
+			cm.ErrorType = string(resolver.ErrorTypeInvalidJSON)
 			cm.Status = models.StatusFailed
 		}
 	}
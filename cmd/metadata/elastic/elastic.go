@@ -0,0 +1,385 @@
+package elastic
+
+import (
+	"bytes"
+	"context"
+	stdJSON "encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/cenkalti/backoff"
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+	"github.com/pkg/errors"
+)
+
+// index names
+const (
+	IndexTokens    = "token_metadata"
+	IndexContracts = "contract_metadata"
+	IndexState     = "dipdup_state"
+	IndexTezosKeys = "dipdup_metadata_context"
+)
+
+// refresh - writes are visible for search after request is returned, because queues of metadata are built by search
+const refresh = "wait_for"
+
+// Elastic -
+type Elastic struct {
+	client   *elasticsearch.Client
+	mappings string
+}
+
+// New - creates Elasticsearch client. `addresses` is comma-separated list of nodes.
+func New(addresses string, opts ...ElasticOption) (*Elastic, error) {
+	es := &Elastic{
+		mappings: "mappings",
+	}
+	for i := range opts {
+		opts[i](es)
+	}
+
+	retryBackoff := backoff.NewExponentialBackOff()
+	client, err := elasticsearch.NewClient(elasticsearch.Config{
+		Addresses:     strings.Split(addresses, ","),
+		RetryOnStatus: []int{502, 503, 504, 429},
+		RetryBackoff: func(i int) time.Duration {
+			if i == 1 {
+				retryBackoff.Reset()
+			}
+			return retryBackoff.NextBackOff()
+		},
+		MaxRetries: 5,
+	})
+	if err != nil {
+		return nil, err
+	}
+	es.client = client
+	return es, nil
+}
+
+// Ping -
+func (es *Elastic) Ping(ctx context.Context) error {
+	resp, err := es.client.Ping(es.client.Ping.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.IsError() {
+		return newError(resp)
+	}
+	return nil
+}
+
+// CreateIndices - creates absent indices with mappings from `{mappings}/{index}.json` files
+func (es *Elastic) CreateIndices() error {
+	for _, index := range []string{IndexTokens, IndexContracts, IndexState, IndexTezosKeys} {
+		if err := es.createIndex(context.Background(), index); err != nil {
+			return errors.Wrap(err, index)
+		}
+	}
+	return nil
+}
+
+func (es *Elastic) createIndex(ctx context.Context, index string) error {
+	exists, err := es.client.Indices.Exists([]string{index}, es.client.Indices.Exists.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	exists.Body.Close()
+
+	switch exists.StatusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusNotFound:
+	default:
+		return errors.Errorf("unexpected status code: %d", exists.StatusCode)
+	}
+
+	return es.create(ctx, index, index)
+}
+
+// create - creates index `name` with mapping of `index`
+func (es *Elastic) create(ctx context.Context, name, index string) error {
+	mapping, err := os.ReadFile(filepath.Join(es.mappings, index+".json"))
+	if err != nil {
+		return err
+	}
+
+	resp, err := es.client.Indices.Create(name,
+		es.client.Indices.Create.WithContext(ctx),
+		es.client.Indices.Create.WithBody(bytes.NewReader(mapping)),
+	)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.IsError() {
+		return newError(resp)
+	}
+	return nil
+}
+
+// Close -
+func (es *Elastic) Close() error {
+	return nil
+}
+
+// Error - error response of Elasticsearch
+type Error struct {
+	Status int
+	Type   string
+	Reason string
+}
+
+// Error -
+func (e Error) Error() string {
+	return fmt.Sprintf("elasticsearch: %d %s: %s", e.Status, e.Type, e.Reason)
+}
+
+func newError(resp *esapi.Response) error {
+	e := Error{Status: resp.StatusCode}
+
+	var body struct {
+		Error stdJSON.RawMessage `json:"error"`
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if err := stdJSON.Unmarshal(data, &body); err != nil || len(body.Error) == 0 {
+		e.Reason = string(data)
+		return e
+	}
+
+	var cause struct {
+		Type   string `json:"type"`
+		Reason string `json:"reason"`
+	}
+	if err := stdJSON.Unmarshal(body.Error, &cause); err != nil {
+		// error may be a plain string
+		e.Reason = string(body.Error)
+		return e
+	}
+	e.Type = cause.Type
+	e.Reason = cause.Reason
+	return e
+}
+
+func decode(resp *esapi.Response, output any) error {
+	defer resp.Body.Close()
+
+	if resp.IsError() {
+		return newError(resp)
+	}
+	if output == nil {
+		_, err := io.Copy(io.Discard, resp.Body)
+		return err
+	}
+	return stdJSON.NewDecoder(resp.Body).Decode(output)
+}
+
+func body(value any) (io.Reader, error) {
+	data, err := stdJSON.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(data), nil
+}
+
+type hit struct {
+	ID     string             `json:"_id"`
+	Source stdJSON.RawMessage `json:"_source"`
+}
+
+type searchResponse struct {
+	Hits struct {
+		Hits []hit `json:"hits"`
+	} `json:"hits"`
+	Aggregations map[string]struct {
+		Value *float64 `json:"value"`
+	} `json:"aggregations"`
+}
+
+func (es *Elastic) search(ctx context.Context, index string, query map[string]any) (searchResponse, error) {
+	var response searchResponse
+
+	reader, err := body(query)
+	if err != nil {
+		return response, err
+	}
+	resp, err := es.client.Search(
+		es.client.Search.WithContext(ctx),
+		es.client.Search.WithIndex(index),
+		es.client.Search.WithBody(reader),
+	)
+	if err != nil {
+		return response, err
+	}
+	err = decode(resp, &response)
+	return response, err
+}
+
+func (es *Elastic) count(ctx context.Context, index string, query map[string]any) (int, error) {
+	reader, err := body(query)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := es.client.Count(
+		es.client.Count.WithContext(ctx),
+		es.client.Count.WithIndex(index),
+		es.client.Count.WithBody(reader),
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	var response struct {
+		Count int `json:"count"`
+	}
+	err = decode(resp, &response)
+	return response.Count, err
+}
+
+func (es *Elastic) updateByQuery(ctx context.Context, index string, query map[string]any) error {
+	reader, err := body(query)
+	if err != nil {
+		return err
+	}
+	resp, err := es.client.UpdateByQuery([]string{index},
+		es.client.UpdateByQuery.WithContext(ctx),
+		es.client.UpdateByQuery.WithBody(reader),
+		es.client.UpdateByQuery.WithConflicts("proceed"),
+		es.client.UpdateByQuery.WithRefresh(true),
+	)
+	if err != nil {
+		return err
+	}
+	return decode(resp, nil)
+}
+
+func (es *Elastic) deleteByQuery(ctx context.Context, index string, query map[string]any) error {
+	reader, err := body(query)
+	if err != nil {
+		return err
+	}
+	resp, err := es.client.DeleteByQuery([]string{index}, reader,
+		es.client.DeleteByQuery.WithContext(ctx),
+		es.client.DeleteByQuery.WithConflicts("proceed"),
+		es.client.DeleteByQuery.WithRefresh(true),
+	)
+	if err != nil {
+		return err
+	}
+	return decode(resp, nil)
+}
+
+// bulk - executes bulk request. Actions are pairs of action and document lines. Returns error of the first failed item except errors of `ignore` types.
+func (es *Elastic) bulk(ctx context.Context, index string, lines []any, ignore ...string) error {
+	return es.bulkRefresh(ctx, index, refresh, lines, ignore...)
+}
+
+func (es *Elastic) bulkRefresh(ctx context.Context, index, policy string, lines []any, ignore ...string) error {
+	if len(lines) == 0 {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	encoder := stdJSON.NewEncoder(&buf)
+	for i := range lines {
+		if err := encoder.Encode(lines[i]); err != nil {
+			return err
+		}
+	}
+
+	resp, err := es.client.Bulk(&buf,
+		es.client.Bulk.WithContext(ctx),
+		es.client.Bulk.WithIndex(index),
+		es.client.Bulk.WithRefresh(policy),
+	)
+	if err != nil {
+		return err
+	}
+
+	var response struct {
+		Errors bool `json:"errors"`
+		Items  []map[string]struct {
+			ID     string `json:"_id"`
+			Status int    `json:"status"`
+			Error  *struct {
+				Type   string `json:"type"`
+				Reason string `json:"reason"`
+			} `json:"error"`
+		} `json:"items"`
+	}
+	if err := decode(resp, &response); err != nil {
+		return err
+	}
+	if !response.Errors {
+		return nil
+	}
+
+	for i := range response.Items {
+		for _, item := range response.Items[i] {
+			if item.Error != nil && !contains(ignore, item.Error.Type) {
+				return errors.Wrap(Error{
+					Status: item.Status,
+					Type:   item.Error.Type,
+					Reason: item.Error.Reason,
+				}, item.ID)
+			}
+		}
+	}
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for i := range values {
+		if values[i] == value {
+			return true
+		}
+	}
+	return false
+}
+
+type action struct {
+	Index  *actionTarget `json:"index,omitempty"`
+	Update *actionTarget `json:"update,omitempty"`
+}
+
+type actionTarget struct {
+	ID string `json:"_id"`
+}
+
+func term(field string, value any) map[string]any {
+	return map[string]any{
+		"term": map[string]any{
+			field: value,
+		},
+	}
+}
+
+func rangeQuery(field, op string, value any) map[string]any {
+	return map[string]any{
+		"range": map[string]any{
+			field: map[string]any{
+				op: value,
+			},
+		},
+	}
+}
+
+func filter(filters ...map[string]any) map[string]any {
+	return map[string]any{
+		"bool": map[string]any{
+			"filter": filters,
+		},
+	}
+}
@@ -0,0 +1,378 @@
+package elastic
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	stdJSON "encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/dipdup-net/go-lib/config"
+	"github.com/dipdup-net/go-lib/database"
+	"github.com/dipdup-net/metadata/cmd/metadata/models"
+	"github.com/go-pg/pg/v10"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testContract = "KT1G1cCRNBgQ48mVDjopHjEmTN5Sbtar8nn9"
+
+type request struct {
+	Method string
+	Path   string
+	Body   string
+}
+
+type response struct {
+	Status int
+	Body   string
+}
+
+// fakeElastic - Elasticsearch stand-in which responds by `method path` routes and records requests
+type fakeElastic struct {
+	url      string
+	routes   map[string]response
+	requests []request
+	mx       sync.Mutex
+}
+
+func newFakeElastic(t *testing.T, routes map[string]response) (*fakeElastic, *Elastic) {
+	fake := &fakeElastic{routes: routes}
+	server := httptest.NewServer(http.HandlerFunc(fake.handle))
+	t.Cleanup(server.Close)
+	fake.url = server.URL
+
+	es, err := New(server.URL, WithMappings("../mappings"))
+	require.NoError(t, err)
+	return fake, es
+}
+
+func (f *fakeElastic) handle(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+
+	f.mx.Lock()
+	f.requests = append(f.requests, request{Method: r.Method, Path: r.URL.Path, Body: string(body)})
+	resp, ok := f.routes[r.Method+" "+r.URL.Path]
+	f.mx.Unlock()
+
+	// product check of v8 client
+	w.Header().Set("X-Elastic-Product", "Elasticsearch")
+	w.Header().Set("Content-Type", "application/json")
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"error":{"type":"not_found","reason":"no route"},"status":404}`))
+		return
+	}
+	if resp.Status == 0 {
+		resp.Status = http.StatusOK
+	}
+	w.WriteHeader(resp.Status)
+	_, _ = w.Write([]byte(resp.Body))
+}
+
+func (f *fakeElastic) find(method, path string) (request, bool) {
+	f.mx.Lock()
+	defer f.mx.Unlock()
+	for i := range f.requests {
+		if f.requests[i].Method == method && f.requests[i].Path == path {
+			return f.requests[i], true
+		}
+	}
+	return request{}, false
+}
+
+func ndjson(t *testing.T, body string) []map[string]any {
+	lines := make([]map[string]any, 0)
+	scanner := bufio.NewScanner(bytes.NewBufferString(body))
+	for scanner.Scan() {
+		var line map[string]any
+		require.NoError(t, stdJSON.Unmarshal(scanner.Bytes(), &line))
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func TestElastic_CreateIndices(t *testing.T) {
+	fake, es := newFakeElastic(t, map[string]response{
+		"HEAD /" + IndexTokens:    {Status: http.StatusNotFound},
+		"PUT /" + IndexTokens:     {Body: `{"acknowledged":true}`},
+		"HEAD /" + IndexContracts: {},
+		"HEAD /" + IndexState:     {},
+		"HEAD /" + IndexTezosKeys: {},
+	})
+
+	require.NoError(t, es.CreateIndices())
+
+	created, ok := fake.find(http.MethodPut, "/"+IndexTokens)
+	require.True(t, ok)
+	mapping, err := os.ReadFile(filepath.Join("../mappings", IndexTokens+".json"))
+	require.NoError(t, err)
+	assert.JSONEq(t, string(mapping), created.Body)
+
+	_, ok = fake.find(http.MethodPut, "/"+IndexContracts)
+	assert.False(t, ok, "existing index must not be created")
+}
+
+func TestMetadata_Save(t *testing.T) {
+	fake, es := newFakeElastic(t, map[string]response{
+		"POST /" + IndexTokens + "/_bulk":    {Body: `{"errors":false,"items":[{"update":{"_id":"mainnet:` + testContract + `:1","status":201}}]}`},
+		"POST /" + IndexTokens + "/_search":  {Body: `{"hits":{"hits":[]},"aggregations":{"contracts":{"buckets":[{"key":"` + testContract + `","doc_count":12}]}}}`},
+		"POST /" + IndexContracts + "/_bulk": {Body: `{"errors":true,"items":[{"update":{"_id":"mainnet:` + testContract + `","status":404,"error":{"type":"document_missing_exception","reason":"document missing"}}}]}`},
+	})
+
+	name := "second"
+	err := NewTokens(es).Save(context.Background(), []*models.TokenMetadata{
+		{Network: "mainnet", Contract: testContract, TokenID: decimal.NewFromInt(1), Status: models.StatusNew, Link: "ipfs://first"},
+		{Network: "mainnet", Contract: testContract, TokenID: decimal.NewFromInt(1), Status: models.StatusApplied, Link: "ipfs://second", Name: name, Metadata: models.JSONB(`{"name":"second"}`)},
+	})
+	require.NoError(t, err)
+
+	bulk, ok := fake.find(http.MethodPost, "/"+IndexTokens+"/_bulk")
+	require.True(t, ok)
+	lines := ndjson(t, bulk.Body)
+	require.Len(t, lines, 2, "duplicates must be skipped")
+
+	assert.Equal(t, map[string]any{"update": map[string]any{"_id": "mainnet:" + testContract + ":1"}}, lines[0])
+
+	doc := lines[1]["doc"].(map[string]any)
+	assert.NotContains(t, doc, "created_at")
+	assert.Equal(t, "ipfs://second", doc["link"])
+	assert.Equal(t, map[string]any{"name": "second"}, doc["metadata"])
+	assert.Equal(t, name, doc["name"])
+	assert.Contains(t, doc, "symbol")
+	assert.Nil(t, doc["symbol"], "omitted fields must be cleared")
+
+	upsert := lines[1]["upsert"].(map[string]any)
+	assert.Contains(t, upsert, "created_at")
+	assert.Contains(t, upsert, "update_id")
+	assert.Equal(t, "1", upsert["token_id"])
+	assert.EqualValues(t, models.StatusApplied, upsert["status"])
+	assert.Equal(t, map[string]any{
+		"input":    []any{"second"},
+		"weight":   float64(12),
+		"contexts": map[string]any{"network": []any{"mainnet"}},
+	}, doc["suggest"])
+
+	popularity, ok := fake.find(http.MethodPost, "/"+IndexContracts+"/_bulk")
+	require.True(t, ok, "popularity of contracts must be updated")
+	lines = ndjson(t, popularity.Body)
+	require.Len(t, lines, 2)
+	assert.Equal(t, map[string]any{"update": map[string]any{"_id": "mainnet:" + testContract}}, lines[0])
+	assert.Equal(t, map[string]any{"count": float64(12), "weight": float64(12)}, lines[1]["script"].(map[string]any)["params"])
+}
+
+func Test_contractSuggestion(t *testing.T) {
+	tests := []struct {
+		name string
+		cm   models.ContractMetadata
+		want *suggestion
+	}{
+		{
+			name: "applied",
+			cm:   models.ContractMetadata{Network: "mainnet", Status: models.StatusApplied, Metadata: models.JSONB(`{"name":"Hic et nunc"}`)},
+			want: &suggestion{Input: []string{"Hic et nunc"}, Weight: 3, Contexts: map[string][]string{"network": {"mainnet"}}},
+		}, {
+			name: "not applied",
+			cm:   models.ContractMetadata{Network: "mainnet", Status: models.StatusFailed, Metadata: models.JSONB(`{"name":"Hic et nunc"}`)},
+		}, {
+			name: "name is not a string",
+			cm:   models.ContractMetadata{Network: "mainnet", Status: models.StatusApplied, Metadata: models.JSONB(`{"name":1}`)},
+		}, {
+			name: "without metadata",
+			cm:   models.ContractMetadata{Network: "mainnet", Status: models.StatusApplied},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, contractSuggestion(&tt.cm, 3))
+		})
+	}
+}
+
+func TestMetadata_Update(t *testing.T) {
+	fake, es := newFakeElastic(t, map[string]response{
+		"POST /" + IndexContracts + "/_bulk": {Body: `{"errors":false,"items":[]}`},
+	})
+
+	cm := &models.ContractMetadata{Network: "mainnet", Contract: testContract, Status: models.StatusFailed, RetryCount: 2, Error: "timeout", ErrorType: "timeout"}
+	require.NoError(t, NewContracts(es).Update(context.Background(), []*models.ContractMetadata{cm}))
+	assert.NotZero(t, cm.UpdateID)
+
+	bulk, ok := fake.find(http.MethodPost, "/"+IndexContracts+"/_bulk")
+	require.True(t, ok)
+	lines := ndjson(t, bulk.Body)
+	require.Len(t, lines, 2)
+	assert.Equal(t, map[string]any{"update": map[string]any{"_id": "mainnet:" + testContract}}, lines[0])
+
+	doc := lines[1]["doc"].(map[string]any)
+	assert.Len(t, doc, 11)
+	assert.Contains(t, doc, "suggest")
+	assert.Equal(t, "timeout", doc["error"])
+	assert.Equal(t, "timeout", doc["error_type"])
+	assert.EqualValues(t, 2, doc["retry_count"])
+	assert.EqualValues(t, cm.UpdateID, doc["update_id"])
+	assert.NotContains(t, doc, "link")
+}
+
+func TestMetadata_Get(t *testing.T) {
+	id := "mainnet:" + testContract + ":7"
+	fake, es := newFakeElastic(t, map[string]response{
+		"POST /" + IndexTokens + "/_search": {Body: `{"hits":{"hits":[{"_id":"` + id + `","_source":{"network":"mainnet","contract":"` + testContract + `","token_id":"7","status":1,"retry_count":1,"created_at":100,"update_id":42,"metadata":{"name":"Hedgehoge"},"creators":["tz1"]}}]}}`},
+	})
+
+	tokens, err := NewTokens(es).Get("mainnet", models.StatusNew, 10, 0)
+	require.NoError(t, err)
+	require.Len(t, tokens, 1)
+
+	assert.Equal(t, documentID(id), tokens[0].ID)
+	assert.EqualValues(t, 42, tokens[0].UpdateID)
+	assert.True(t, decimal.NewFromInt(7).Equal(tokens[0].TokenID))
+	assert.Equal(t, models.StatusNew, tokens[0].Status)
+	assert.Equal(t, []string{"tz1"}, tokens[0].Creators)
+	assert.JSONEq(t, `{"name":"Hedgehoge"}`, string(tokens[0].Metadata))
+
+	search, ok := fake.find(http.MethodPost, "/"+IndexTokens+"/_search")
+	require.True(t, ok)
+	var query struct {
+		Size  int `json:"size"`
+		Query struct {
+			Bool struct {
+				Filter []map[string]any `json:"filter"`
+			} `json:"bool"`
+		} `json:"query"`
+	}
+	require.NoError(t, stdJSON.Unmarshal([]byte(search.Body), &query))
+	assert.Equal(t, 10, query.Size)
+	assert.Len(t, query.Query.Bool.Filter, 3)
+	assert.Equal(t, map[string]any{"term": map[string]any{"network.keyword": "mainnet"}}, query.Query.Bool.Filter[0])
+	assert.Contains(t, query.Query.Bool.Filter[2], "bool", "due documents or documents without schedule")
+}
+
+func TestMetadata_LastUpdateID(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want int64
+	}{
+		{
+			name: "filled index",
+			body: `{"hits":{"hits":[]},"aggregations":{"last_update_id":{"value":15.0}}}`,
+			want: 15,
+		}, {
+			name: "empty index",
+			body: `{"hits":{"hits":[]},"aggregations":{"last_update_id":{"value":null}}}`,
+			want: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, es := newFakeElastic(t, map[string]response{
+				"POST /" + IndexContracts + "/_search": {Body: tt.body},
+			})
+			got, err := NewContracts(es).LastUpdateID()
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestMetadata_bulkError(t *testing.T) {
+	_, es := newFakeElastic(t, map[string]response{
+		"POST /" + IndexTokens + "/_bulk": {Body: `{"errors":true,"items":[{"update":{"_id":"mainnet:KT1:0","status":400,"error":{"type":"mapper_parsing_exception","reason":"failed to parse field [metadata.name]"}}}]}`},
+	})
+
+	err := NewTokens(es).Save(context.Background(), []*models.TokenMetadata{
+		{Network: "mainnet", Contract: "KT1", TokenID: decimal.Zero},
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "mapper_parsing_exception")
+	assert.Contains(t, err.Error(), "mainnet:KT1:0")
+}
+
+func TestElastic_State(t *testing.T) {
+	fake, es := newFakeElastic(t, map[string]response{
+		"GET /" + IndexState + "/_doc/metadata_mainnet": {Body: `{"_id":"metadata_mainnet","found":true,"_source":{"index_name":"metadata_mainnet","index_type":"metadata","level":100}}`},
+		"PUT /" + IndexState + "/_doc/metadata_mainnet": {Status: http.StatusCreated, Body: `{"result":"created"}`},
+	})
+	ctx := context.Background()
+
+	state, err := es.State(ctx, "metadata_mainnet")
+	require.NoError(t, err)
+	assert.EqualValues(t, 100, state.Level)
+	assert.Equal(t, "metadata", state.IndexType)
+
+	_, err = es.State(ctx, "metadata_ghostnet")
+	assert.ErrorIs(t, err, pg.ErrNoRows)
+
+	require.NoError(t, es.UpdateState(ctx, &database.State{IndexName: "metadata_mainnet", Level: 101}))
+	put, ok := fake.find(http.MethodPut, "/"+IndexState+"/_doc/metadata_mainnet")
+	require.True(t, ok)
+	var doc database.State
+	require.NoError(t, stdJSON.Unmarshal([]byte(put.Body), &doc))
+	assert.EqualValues(t, 101, doc.Level)
+	assert.NotZero(t, doc.UpdatedAt)
+}
+
+func TestTezosKeys(t *testing.T) {
+	id := tezosKeyID("mainnet", testContract, "metadata")
+	fake, es := newFakeElastic(t, map[string]response{
+		"GET /" + IndexTezosKeys + "/_doc/" + id: {Body: `{"_id":"` + id + `","found":true,"_source":{"network":"mainnet","address":"` + testContract + `","key":"metadata","value":"dGVzdA=="}}`},
+		"POST /" + IndexTezosKeys + "/_search":   {Body: `{"hits":{"hits":[]}}`},
+		"POST /" + IndexTezosKeys + "/_bulk":     {Body: `{"errors":false,"items":[]}`},
+	})
+	keys := NewTezosKeys(es)
+
+	key, err := keys.Get("mainnet", testContract, "metadata")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("test"), key.Value)
+
+	_, err = keys.Get("mainnet", testContract, "")
+	assert.ErrorIs(t, err, pg.ErrNoRows)
+
+	_, err = keys.Get("mainnet", testContract, "absent")
+	assert.ErrorIs(t, err, pg.ErrNoRows)
+
+	require.NoError(t, keys.Save(models.TezosKey{Network: "mainnet", Address: testContract, Key: "metadata", Value: []byte("new")}))
+	bulk, ok := fake.find(http.MethodPost, "/"+IndexTezosKeys+"/_bulk")
+	require.True(t, ok)
+	lines := ndjson(t, bulk.Body)
+	require.Len(t, lines, 2)
+	assert.Equal(t, map[string]any{"index": map[string]any{"_id": id}}, lines[0])
+	assert.Equal(t, "bmV3", lines[1]["value"])
+}
+
+func TestNewStorage(t *testing.T) {
+	fake, _ := newFakeElastic(t, map[string]response{
+		"HEAD /":                  {},
+		"HEAD /" + IndexTokens:    {},
+		"HEAD /" + IndexContracts: {},
+		"HEAD /" + IndexState:     {},
+		"HEAD /" + IndexTezosKeys: {},
+	})
+
+	_, err := NewStorage(context.Background(), config.Database{Kind: config.DBKindPostgres})
+	require.Error(t, err)
+
+	storage, err := NewStorage(context.Background(), config.Database{
+		Kind: config.DBKindElasticSearch,
+		Path: fake.url,
+	}, WithMappings("../mappings"))
+	require.NoError(t, err)
+
+	assert.NotNil(t, storage.Tokens)
+	assert.NotNil(t, storage.Contracts)
+	assert.NotNil(t, storage.TezosKeys)
+	assert.Nil(t, storage.Changes)
+	assert.Nil(t, storage.History)
+	assert.Nil(t, storage.Webhooks)
+
+	_, ok := fake.find(http.MethodHead, "/"+IndexState)
+	assert.True(t, ok)
+}
@@ -0,0 +1,349 @@
+package elastic
+
+import (
+	"context"
+	stdJSON "encoding/json"
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"github.com/dipdup-net/metadata/cmd/metadata/models"
+)
+
+// maxSize - maximum size of search result window in Elasticsearch
+const maxSize = 10000
+
+type document interface {
+	models.Model
+
+	BeforeInsert(ctx context.Context) (context.Context, error)
+	BeforeUpdate(ctx context.Context) (context.Context, error)
+}
+
+// Metadata - implementation of `models.ModelRepository` for Elasticsearch. Document ids are built from unique fields of metadata like in Postgres unique constraints.
+type Metadata[T document] struct {
+	es    *Elastic
+	index string
+
+	// fields overwritten by `Update` and by `Save` of existing document, same as columns in Postgres
+	updateFields []string
+	saveFields   []string
+
+	create     func() T
+	docID      func(T) string
+	updateID   func(T) int64
+	setIDs     func(T, uint64, int64)
+	contract   func(T) contractKey
+	suggestion func(T, int) *suggestion
+
+	// tokens change popularity of contracts, see `updateContractsPopularity`
+	updatesPopularity bool
+
+	mx sync.Mutex
+}
+
+// NewTokens -
+func NewTokens(es *Elastic) *Metadata[*models.TokenMetadata] {
+	return &Metadata[*models.TokenMetadata]{
+		es:           es,
+		index:        IndexTokens,
+		updateFields: append([]string{"metadata", "update_id", "updated_at", "status", "retry_count", "error", "sha256", "issues", "on_chain_metadata", "off_chain_metadata", "next_attempt_at", "error_type", suggestField}, models.NormalizedTokenColumns...),
+		saveFields:   append([]string{"metadata", "link", "updated_at", "update_id", "status", "retry_count", "sha256", "source", "issues", "on_chain_metadata", "off_chain_metadata", "next_attempt_at", "error_type", suggestField}, models.NormalizedTokenColumns...),
+		create: func() *models.TokenMetadata {
+			return new(models.TokenMetadata)
+		},
+		docID: func(tm *models.TokenMetadata) string {
+			return fmt.Sprintf("%s:%s:%s", tm.Network, tm.Contract, tm.TokenID.String())
+		},
+		updateID: func(tm *models.TokenMetadata) int64 {
+			return tm.UpdateID
+		},
+		setIDs: func(tm *models.TokenMetadata, id uint64, updateID int64) {
+			tm.ID = id
+			tm.UpdateID = updateID
+		},
+		contract: func(tm *models.TokenMetadata) contractKey {
+			return contractKey{tm.Network, tm.Contract}
+		},
+		suggestion:        tokenSuggestion,
+		updatesPopularity: true,
+	}
+}
+
+// NewContracts -
+func NewContracts(es *Elastic) *Metadata[*models.ContractMetadata] {
+	return &Metadata[*models.ContractMetadata]{
+		es:           es,
+		index:        IndexContracts,
+		updateFields: []string{"metadata", "update_id", "updated_at", "status", "retry_count", "error", "sha256", "issues", "next_attempt_at", "error_type", suggestField},
+		saveFields:   []string{"metadata", "link", "updated_at", "update_id", "status", "retry_count", "sha256", "issues", "next_attempt_at", "error_type", suggestField},
+		create: func() *models.ContractMetadata {
+			return new(models.ContractMetadata)
+		},
+		docID: func(cm *models.ContractMetadata) string {
+			return fmt.Sprintf("%s:%s", cm.Network, cm.Contract)
+		},
+		updateID: func(cm *models.ContractMetadata) int64 {
+			return cm.UpdateID
+		},
+		setIDs: func(cm *models.ContractMetadata, id uint64, updateID int64) {
+			cm.ID = id
+			cm.UpdateID = updateID
+		},
+		contract: func(cm *models.ContractMetadata) contractKey {
+			return contractKey{cm.Network, cm.Contract}
+		},
+		suggestion: contractSuggestion,
+	}
+}
+
+// Get - returns metadata with `status` which next attempt is due. Documents indexed before retry schedule was introduced don't have it.
+func (m *Metadata[T]) Get(network string, status models.Status, limit, offset int) ([]T, error) {
+	filters := []map[string]any{
+		term("network.keyword", network),
+		term("status", status),
+		{
+			"bool": map[string]any{
+				"should": []map[string]any{
+					rangeQuery("next_attempt_at", "lte", time.Now().Unix()),
+					{
+						"bool": map[string]any{
+							"must_not": map[string]any{
+								"exists": map[string]any{
+									"field": "next_attempt_at",
+								},
+							},
+						},
+					},
+				},
+				"minimum_should_match": 1,
+			},
+		},
+	}
+
+	if limit <= 0 || limit > maxSize {
+		limit = maxSize
+	}
+	response, err := m.es.search(context.Background(), m.index, map[string]any{
+		"query": filter(filters...),
+		"sort": []map[string]any{
+			{"retry_count": "desc"},
+			{"updated_at": "desc"},
+		},
+		"from": offset,
+		"size": limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]T, 0, len(response.Hits.Hits))
+	for i := range response.Hits.Hits {
+		item, err := m.parse(response.Hits.Hits[i])
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, item)
+	}
+	return result, nil
+}
+
+// Update - overwrites changeable fields of existing documents
+func (m *Metadata[T]) Update(ctx context.Context, metadata []T) error {
+	if len(metadata) == 0 {
+		return nil
+	}
+
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	popularity, err := m.popularity(ctx, metadata)
+	if err != nil {
+		return err
+	}
+
+	lines := make([]any, 0, len(metadata)*2)
+	for i := range metadata {
+		if _, err := metadata[i].BeforeUpdate(ctx); err != nil {
+			return err
+		}
+		doc, err := m.document(metadata[i], popularity)
+		if err != nil {
+			return err
+		}
+		lines = append(lines,
+			action{Update: &actionTarget{ID: m.docID(metadata[i])}},
+			map[string]any{
+				"doc": fields(doc, m.updateFields),
+			},
+		)
+	}
+	return m.es.bulk(ctx, m.index, lines)
+}
+
+// Save - creates documents or overwrites fields of existing ones. Last of duplicated items wins.
+func (m *Metadata[T]) Save(ctx context.Context, metadata []T) error {
+	if len(metadata) == 0 {
+		return nil
+	}
+
+	savings := make([]T, 0)
+	has := make(map[string]struct{})
+	for i := len(metadata) - 1; i >= 0; i-- {
+		id := m.docID(metadata[i])
+		if _, ok := has[id]; !ok {
+			has[id] = struct{}{}
+			savings = append(savings, metadata[i])
+		}
+	}
+
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	popularity, err := m.popularity(ctx, savings)
+	if err != nil {
+		return err
+	}
+
+	lines := make([]any, 0, len(savings)*2)
+	for i := range savings {
+		if _, err := savings[i].BeforeInsert(ctx); err != nil {
+			return err
+		}
+		doc, err := m.document(savings[i], popularity)
+		if err != nil {
+			return err
+		}
+		lines = append(lines,
+			action{Update: &actionTarget{ID: m.docID(savings[i])}},
+			map[string]any{
+				"doc":    fields(doc, m.saveFields),
+				"upsert": doc,
+			},
+		)
+	}
+	if err := m.es.bulk(ctx, m.index, lines); err != nil {
+		return err
+	}
+
+	if m.updatesPopularity {
+		return m.es.updateContractsPopularity(ctx, uniqueContracts(savings, m.contract))
+	}
+	return nil
+}
+
+// LastUpdateID -
+func (m *Metadata[T]) LastUpdateID() (int64, error) {
+	return m.es.maxUpdateID(context.Background(), m.index)
+}
+
+// CountByStatus -
+func (m *Metadata[T]) CountByStatus(network string, status models.Status) (int, error) {
+	return m.es.count(context.Background(), m.index, map[string]any{
+		"query": filter(
+			term("network.keyword", network),
+			term("status", status),
+		),
+	})
+}
+
+// Retry - returns failed metadata which was created within `window` seconds and failed with one of `errorTypes` to new status
+func (m *Metadata[T]) Retry(network string, errorTypes []string, window time.Duration) error {
+	if len(errorTypes) == 0 {
+		return nil
+	}
+	return m.es.updateByQuery(context.Background(), m.index, map[string]any{
+		"query": filter(
+			term("network.keyword", network),
+			term("status", models.StatusFailed),
+			rangeQuery("created_at", "gt", time.Now().Unix()-int64(window)),
+			map[string]any{
+				"terms": map[string]any{
+					"error_type.keyword": errorTypes,
+				},
+			},
+		),
+		"script": map[string]any{
+			"source": "ctx._source.retry_count = 0; ctx._source.next_attempt_at = 0; ctx._source.status = params.status",
+			"params": map[string]any{
+				"status": models.StatusNew,
+			},
+		},
+	})
+}
+
+// popularity - popularity of contracts of applied metadata which get suggestions
+func (m *Metadata[T]) popularity(ctx context.Context, items []T) (map[contractKey]int, error) {
+	applied := make([]T, 0, len(items))
+	for i := range items {
+		if items[i].GetStatus() == models.StatusApplied {
+			applied = append(applied, items[i])
+		}
+	}
+	if len(applied) == 0 {
+		return nil, nil
+	}
+	return m.es.popularity(ctx, uniqueContracts(applied, m.contract))
+}
+
+// document - JSON fields of metadata with `update_id` which is hidden from API responses and completion `suggest` field
+func (m *Metadata[T]) document(item T, popularity map[contractKey]int) (map[string]stdJSON.RawMessage, error) {
+	data, err := stdJSON.Marshal(item)
+	if err != nil {
+		return nil, err
+	}
+	var doc map[string]stdJSON.RawMessage
+	if err := stdJSON.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	updateID, err := stdJSON.Marshal(m.updateID(item))
+	if err != nil {
+		return nil, err
+	}
+	doc["update_id"] = updateID
+
+	if s := m.suggestion(item, weight(popularity, m.contract(item))); s != nil {
+		suggest, err := stdJSON.Marshal(s)
+		if err != nil {
+			return nil, err
+		}
+		doc[suggestField] = suggest
+	}
+	return doc, nil
+}
+
+func (m *Metadata[T]) parse(h hit) (T, error) {
+	item := m.create()
+	if err := stdJSON.Unmarshal(h.Source, item); err != nil {
+		return item, err
+	}
+	var ids struct {
+		UpdateID int64 `json:"update_id"`
+	}
+	if err := stdJSON.Unmarshal(h.Source, &ids); err != nil {
+		return item, err
+	}
+	m.setIDs(item, documentID(h.ID), ids.UpdateID)
+	return item, nil
+}
+
+// fields - picks `names` from document. Absent fields are nulls, so values omitted by JSON are cleared.
+func fields(doc map[string]stdJSON.RawMessage, names []string) map[string]stdJSON.RawMessage {
+	result := make(map[string]stdJSON.RawMessage, len(names))
+	for _, name := range names {
+		if value, ok := doc[name]; ok {
+			result[name] = value
+		} else {
+			result[name] = stdJSON.RawMessage("null")
+		}
+	}
+	return result
+}
+
+// documentID - numeric id of document which is used by services to deduplicate queues
+func documentID(id string) uint64 {
+	hash := fnv.New64a()
+	_, _ = hash.Write([]byte(id))
+	return hash.Sum64()
+}
@@ -0,0 +1,13 @@
+package elastic
+
+// ElasticOption -
+type ElasticOption func(*Elastic)
+
+// WithMappings - directory with index mapping files. Default: `mappings`.
+func WithMappings(dir string) ElasticOption {
+	return func(es *Elastic) {
+		if dir != "" {
+			es.mappings = dir
+		}
+	}
+}
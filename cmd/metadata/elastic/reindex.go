@@ -0,0 +1,323 @@
+package elastic
+
+import (
+	"context"
+	stdJSON "encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/dipdup-net/metadata/cmd/metadata/models"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+const defaultReindexBatchSize = 1000
+
+// ReindexSource - rows of Postgres table which are copied to a new version of index
+type ReindexSource[T any] interface {
+	Count(ctx context.Context) (int, error)
+	// Scan - returns rows of all networks sorted by `update_id` and `id` which are greater than the pair
+	Scan(ctx context.Context, updateID int64, id uint64, limit int) ([]T, error)
+	// Popularity - returns count of tokens of `contracts`, it's a weight of suggestions
+	Popularity(ctx context.Context, network string, contracts []string) (map[string]int, error)
+}
+
+// ReindexOption -
+type ReindexOption func(*reindex)
+
+type reindex struct {
+	batchSize    int
+	fresh        bool
+	replaceIndex bool
+}
+
+// WithBatchSize - count of rows in bulk request. Default: 1000.
+func WithBatchSize(size int) ReindexOption {
+	return func(r *reindex) {
+		if size > 0 {
+			r.batchSize = size
+		}
+	}
+}
+
+// WithFresh - creates a new version of index even if the previous reindexing was interrupted
+func WithFresh() ReindexOption {
+	return func(r *reindex) {
+		r.fresh = true
+	}
+}
+
+// WithReplaceIndex - allows to drop index which was created before aliases were introduced. Its name is taken by alias.
+func WithReplaceIndex() ReindexOption {
+	return func(r *reindex) {
+		r.replaceIndex = true
+	}
+}
+
+// reindexCursor - sort values of the last copied row
+type reindexCursor struct {
+	updateID int64
+	id       uint64
+}
+
+// Reindex - copies all rows of `source` to a new version of index `{index}_v{N}` created with the current mapping and atomically points alias with name of index to it.
+// Interrupted reindexing is resumed from the last copied `update_id` of the latest version which isn't aliased yet. Previous versions are kept.
+func (m *Metadata[T]) Reindex(ctx context.Context, source ReindexSource[T], opts ...ReindexOption) error {
+	r := reindex{
+		batchSize: defaultReindexBatchSize,
+	}
+	for i := range opts {
+		opts[i](&r)
+	}
+
+	v, err := m.es.versions(ctx, m.index)
+	if err != nil {
+		return errors.Wrap(err, "versions")
+	}
+	if v.concrete && !r.replaceIndex {
+		return errors.Errorf("%s is an index, not an alias. It will be dropped on alias switch, so replacing should be allowed explicitly", m.index)
+	}
+
+	var (
+		cursor reindexCursor
+		copied int
+	)
+	target := v.latest
+	if target != "" && !contains(v.current, target) && !r.fresh {
+		updateID, err := m.es.maxUpdateID(ctx, target)
+		if err != nil {
+			return errors.Wrap(err, "max update id")
+		}
+		// rows with the last copied update id may be not copied yet, documents are rewritten by id
+		cursor.updateID = updateID
+
+		copied, err = m.es.count(ctx, target, map[string]any{})
+		if err != nil {
+			return errors.Wrap(err, "count")
+		}
+		log.Info().Str("index", target).Int64("update_id", updateID).Msg("resuming reindexing")
+	} else {
+		target = fmt.Sprintf("%s_v%d", m.index, v.version+1)
+		if err := m.es.create(ctx, target, m.index); err != nil {
+			return errors.Wrap(err, target)
+		}
+		log.Info().Str("index", target).Msg("index is created")
+	}
+
+	total, err := source.Count(ctx)
+	if err != nil {
+		return errors.Wrap(err, "count")
+	}
+
+	copied, err = m.copy(ctx, source, target, &cursor, r.batchSize, copied, total)
+	if err != nil {
+		return err
+	}
+
+	if err := m.es.refresh(ctx, target); err != nil {
+		return errors.Wrap(err, "refresh")
+	}
+	if err := m.es.switchAlias(ctx, m.index, target, v); err != nil {
+		return errors.Wrap(err, "switch alias")
+	}
+	log.Info().Str("alias", m.index).Str("index", target).Strs("previous", v.current).Msg("alias is switched")
+
+	// rows which were changed while alias was switched
+	if _, err := m.copy(ctx, source, target, &cursor, r.batchSize, copied, total); err != nil {
+		return errors.Wrap(err, "catch up")
+	}
+	return m.es.refresh(ctx, target)
+}
+
+// copy - copies rows after `cursor` by batches and moves it. Returns count of copied rows including already copied ones.
+func (m *Metadata[T]) copy(ctx context.Context, source ReindexSource[T], target string, cursor *reindexCursor, batchSize, copied, total int) (int, error) {
+	for {
+		items, err := source.Scan(ctx, cursor.updateID, cursor.id, batchSize)
+		if err != nil {
+			return copied, errors.Wrap(err, "scan")
+		}
+		if len(items) == 0 {
+			return copied, nil
+		}
+
+		popularity, err := m.sourcePopularity(ctx, source, items)
+		if err != nil {
+			return copied, errors.Wrap(err, "popularity")
+		}
+
+		lines := make([]any, 0, len(items)*2)
+		for i := range items {
+			doc, err := m.document(items[i], popularity)
+			if err != nil {
+				return copied, err
+			}
+			lines = append(lines,
+				action{Index: &actionTarget{ID: m.docID(items[i])}},
+				doc,
+			)
+		}
+		// new index isn't searched until alias is switched, so refresh is skipped
+		if err := m.es.bulkRefresh(ctx, target, "false", lines); err != nil {
+			return copied, err
+		}
+
+		last := items[len(items)-1]
+		cursor.updateID = m.updateID(last)
+		cursor.id = last.GetID()
+		copied += len(items)
+		log.Info().Str("index", target).Int("copied", copied).Int("total", total).Int64("update_id", cursor.updateID).Msg("reindexing")
+
+		if len(items) < batchSize {
+			return copied, nil
+		}
+	}
+}
+
+// sourcePopularity - popularity of contracts of applied items by source. Index can't be used because it's incomplete while reindexing.
+func (m *Metadata[T]) sourcePopularity(ctx context.Context, source ReindexSource[T], items []T) (map[contractKey]int, error) {
+	applied := make([]T, 0, len(items))
+	for i := range items {
+		if items[i].GetStatus() == models.StatusApplied {
+			applied = append(applied, items[i])
+		}
+	}
+
+	byNetwork := make(map[string][]string)
+	for _, key := range uniqueContracts(applied, m.contract) {
+		byNetwork[key.Network] = append(byNetwork[key.Network], key.Contract)
+	}
+
+	result := make(map[contractKey]int)
+	for network, contracts := range byNetwork {
+		counts, err := source.Popularity(ctx, network, contracts)
+		if err != nil {
+			return nil, err
+		}
+		for contract, count := range counts {
+			result[contractKey{network, contract}] = count
+		}
+	}
+	return result, nil
+}
+
+// indexVersions - versions of index and indices which alias with name of index points to
+type indexVersions struct {
+	// concrete - index was created without versions and alias
+	concrete bool
+	current  []string
+	latest   string
+	version  int
+}
+
+func (es *Elastic) versions(ctx context.Context, index string) (indexVersions, error) {
+	var v indexVersions
+
+	resp, err := es.client.Indices.GetAlias(
+		es.client.Indices.GetAlias.WithContext(ctx),
+		es.client.Indices.GetAlias.WithIndex(index+"*"),
+	)
+	if err != nil {
+		return v, err
+	}
+
+	var response map[string]struct {
+		Aliases map[string]stdJSON.RawMessage `json:"aliases"`
+	}
+	if err := decode(resp, &response); err != nil {
+		return v, err
+	}
+
+	for name, aliases := range response {
+		if name == index {
+			v.concrete = true
+			continue
+		}
+		if !strings.HasPrefix(name, index+"_v") {
+			continue
+		}
+		version, err := strconv.Atoi(strings.TrimPrefix(name, index+"_v"))
+		if err != nil {
+			continue
+		}
+		if _, ok := aliases.Aliases[index]; ok {
+			v.current = append(v.current, name)
+		}
+		if version > v.version {
+			v.version = version
+			v.latest = name
+		}
+	}
+	return v, nil
+}
+
+func (es *Elastic) maxUpdateID(ctx context.Context, index string) (int64, error) {
+	response, err := es.search(ctx, index, map[string]any{
+		"size": 0,
+		"aggs": map[string]any{
+			"last_update_id": map[string]any{
+				"max": map[string]any{
+					"field": "update_id",
+				},
+			},
+		},
+	})
+	if err != nil {
+		return 0, err
+	}
+	if agg, ok := response.Aggregations["last_update_id"]; ok && agg.Value != nil {
+		return int64(*agg.Value), nil
+	}
+	return 0, nil
+}
+
+func (es *Elastic) refresh(ctx context.Context, index string) error {
+	resp, err := es.client.Indices.Refresh(
+		es.client.Indices.Refresh.WithContext(ctx),
+		es.client.Indices.Refresh.WithIndex(index),
+	)
+	if err != nil {
+		return err
+	}
+	return decode(resp, nil)
+}
+
+// switchAlias - points `alias` to `target` in one request, so searches never see missing or partial index
+func (es *Elastic) switchAlias(ctx context.Context, alias, target string, v indexVersions) error {
+	actions := make([]map[string]any, 0)
+	if v.concrete {
+		actions = append(actions, map[string]any{
+			"remove_index": map[string]any{
+				"index": alias,
+			},
+		})
+	}
+	for _, index := range v.current {
+		actions = append(actions, map[string]any{
+			"remove": map[string]any{
+				"index": index,
+				"alias": alias,
+			},
+		})
+	}
+	actions = append(actions, map[string]any{
+		"add": map[string]any{
+			"index": target,
+			"alias": alias,
+		},
+	})
+
+	reader, err := body(map[string]any{
+		"actions": actions,
+	})
+	if err != nil {
+		return err
+	}
+	resp, err := es.client.Indices.UpdateAliases(reader,
+		es.client.Indices.UpdateAliases.WithContext(ctx),
+	)
+	if err != nil {
+		return err
+	}
+	return decode(resp, nil)
+}
@@ -0,0 +1,164 @@
+package elastic
+
+import (
+	"context"
+	stdJSON "encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/dipdup-net/metadata/cmd/metadata/models"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memorySource - rows of table sorted by update id and id
+type memorySource struct {
+	tokens []*models.TokenMetadata
+	scans  [][2]int64
+}
+
+func (s *memorySource) Count(ctx context.Context) (int, error) {
+	return len(s.tokens), nil
+}
+
+func (s *memorySource) Scan(ctx context.Context, updateID int64, id uint64, limit int) ([]*models.TokenMetadata, error) {
+	s.scans = append(s.scans, [2]int64{updateID, int64(id)})
+
+	result := make([]*models.TokenMetadata, 0)
+	for _, token := range s.tokens {
+		if token.UpdateID < updateID || (token.UpdateID == updateID && token.ID <= id) {
+			continue
+		}
+		result = append(result, token)
+		if len(result) == limit {
+			break
+		}
+	}
+	return result, nil
+}
+
+func (s *memorySource) Popularity(ctx context.Context, network string, contracts []string) (map[string]int, error) {
+	result := make(map[string]int)
+	for _, token := range s.tokens {
+		if token.Network == network {
+			result[token.Contract]++
+		}
+	}
+	return result, nil
+}
+
+func newMemorySource(count int) *memorySource {
+	source := new(memorySource)
+	for i := 1; i <= count; i++ {
+		source.tokens = append(source.tokens, &models.TokenMetadata{
+			ID:       uint64(i),
+			UpdateID: int64(i),
+			Network:  "mainnet",
+			Contract: testContract,
+			TokenID:  decimal.NewFromInt(int64(i)),
+			Status:   models.StatusApplied,
+			Name:     "token",
+		})
+	}
+	return source
+}
+
+func bulkResponse(count int) string {
+	items := make([]map[string]any, count)
+	for i := range items {
+		items[i] = map[string]any{"index": map[string]any{"status": 201}}
+	}
+	data, _ := stdJSON.Marshal(map[string]any{"errors": false, "items": items})
+	return string(data)
+}
+
+func aliasActions(t *testing.T, fake *fakeElastic) []map[string]map[string]any {
+	update, ok := fake.find(http.MethodPost, "/_aliases")
+	require.True(t, ok)
+	var body struct {
+		Actions []map[string]map[string]any `json:"actions"`
+	}
+	require.NoError(t, stdJSON.Unmarshal([]byte(update.Body), &body))
+	return body.Actions
+}
+
+func TestMetadata_Reindex(t *testing.T) {
+	t.Run("replace index without alias", func(t *testing.T) {
+		fake, es := newFakeElastic(t, map[string]response{
+			"GET /" + IndexTokens + "*/_alias":      {Body: `{"token_metadata":{"aliases":{}}}`},
+			"PUT /" + IndexTokens + "_v1":           {Body: `{"acknowledged":true}`},
+			"POST /" + IndexTokens + "_v1/_bulk":    {Body: bulkResponse(2)},
+			"POST /" + IndexTokens + "_v1/_refresh": {Body: `{}`},
+			"POST /_aliases":                        {Body: `{"acknowledged":true}`},
+		})
+
+		source := newMemorySource(3)
+		require.NoError(t, NewTokens(es).Reindex(context.Background(), source, WithBatchSize(2), WithReplaceIndex()))
+
+		assert.Equal(t, [][2]int64{{0, 0}, {2, 2}, {3, 3}}, source.scans)
+
+		bulk, ok := fake.find(http.MethodPost, "/"+IndexTokens+"_v1/_bulk")
+		require.True(t, ok)
+		lines := ndjson(t, bulk.Body)
+		require.Len(t, lines, 4)
+		assert.Equal(t, map[string]any{"index": map[string]any{"_id": "mainnet:" + testContract + ":1"}}, lines[0])
+		assert.EqualValues(t, 1, lines[1]["update_id"])
+		assert.EqualValues(t, 3, lines[1]["suggest"].(map[string]any)["weight"])
+
+		assert.Equal(t, []map[string]map[string]any{
+			{"remove_index": {"index": IndexTokens}},
+			{"add": {"index": IndexTokens + "_v1", "alias": IndexTokens}},
+		}, aliasActions(t, fake))
+	})
+
+	t.Run("index without alias isn't replaced by default", func(t *testing.T) {
+		fake, es := newFakeElastic(t, map[string]response{
+			"GET /" + IndexTokens + "*/_alias": {Body: `{"token_metadata":{"aliases":{}}}`},
+		})
+
+		require.Error(t, NewTokens(es).Reindex(context.Background(), newMemorySource(1)))
+		_, ok := fake.find(http.MethodPut, "/"+IndexTokens+"_v1")
+		assert.False(t, ok)
+	})
+
+	t.Run("resume", func(t *testing.T) {
+		fake, es := newFakeElastic(t, map[string]response{
+			"GET /" + IndexTokens + "*/_alias":      {Body: `{"token_metadata_v1":{"aliases":{"token_metadata":{}}},"token_metadata_v2":{"aliases":{}}}`},
+			"POST /" + IndexTokens + "_v2/_search":  {Body: `{"hits":{"hits":[]},"aggregations":{"last_update_id":{"value":2.0}}}`},
+			"POST /" + IndexTokens + "_v2/_count":   {Body: `{"count":2}`},
+			"POST /" + IndexTokens + "_v2/_bulk":    {Body: bulkResponse(2)},
+			"POST /" + IndexTokens + "_v2/_refresh": {Body: `{}`},
+			"POST /_aliases":                        {Body: `{"acknowledged":true}`},
+		})
+
+		source := newMemorySource(3)
+		require.NoError(t, NewTokens(es).Reindex(context.Background(), source))
+
+		require.NotEmpty(t, source.scans)
+		assert.Equal(t, [2]int64{2, 0}, source.scans[0], "row with the last update id is copied again")
+
+		_, ok := fake.find(http.MethodPut, "/"+IndexTokens+"_v3")
+		assert.False(t, ok)
+
+		assert.Equal(t, []map[string]map[string]any{
+			{"remove": {"index": IndexTokens + "_v1", "alias": IndexTokens}},
+			{"add": {"index": IndexTokens + "_v2", "alias": IndexTokens}},
+		}, aliasActions(t, fake))
+	})
+
+	t.Run("fresh", func(t *testing.T) {
+		fake, es := newFakeElastic(t, map[string]response{
+			"GET /" + IndexTokens + "*/_alias":      {Body: `{"token_metadata_v1":{"aliases":{"token_metadata":{}}},"token_metadata_v2":{"aliases":{}}}`},
+			"PUT /" + IndexTokens + "_v3":           {Body: `{"acknowledged":true}`},
+			"POST /" + IndexTokens + "_v3/_bulk":    {Body: bulkResponse(1)},
+			"POST /" + IndexTokens + "_v3/_refresh": {Body: `{}`},
+			"POST /_aliases":                        {Body: `{"acknowledged":true}`},
+		})
+
+		require.NoError(t, NewTokens(es).Reindex(context.Background(), newMemorySource(1), WithFresh()))
+
+		_, ok := fake.find(http.MethodPut, "/"+IndexTokens+"_v3")
+		assert.True(t, ok)
+	})
+}
@@ -0,0 +1,77 @@
+package elastic
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/dipdup-net/go-lib/database"
+	"github.com/go-pg/pg/v10"
+)
+
+// State - returns indexer state by index name. Returns `pg.ErrNoRows` if it's not found to be compatible with Postgres backend.
+func (es *Elastic) State(ctx context.Context, name string) (*database.State, error) {
+	resp, err := es.client.Get(IndexState, name, es.client.Get.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil, pg.ErrNoRows
+	}
+
+	var response struct {
+		Source database.State `json:"_source"`
+	}
+	if err := decode(resp, &response); err != nil {
+		return nil, err
+	}
+	return &response.Source, nil
+}
+
+// UpdateState -
+func (es *Elastic) UpdateState(ctx context.Context, state *database.State) error {
+	if _, err := state.BeforeUpdate(ctx); err != nil {
+		return err
+	}
+	return es.indexState(ctx, state)
+}
+
+// CreateState -
+func (es *Elastic) CreateState(ctx context.Context, state *database.State) error {
+	if _, err := state.BeforeInsert(ctx); err != nil {
+		return err
+	}
+	return es.indexState(ctx, state)
+}
+
+// DeleteState -
+func (es *Elastic) DeleteState(ctx context.Context, state *database.State) error {
+	resp, err := es.client.Delete(IndexState, state.IndexName,
+		es.client.Delete.WithContext(ctx),
+		es.client.Delete.WithRefresh(refresh),
+	)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil
+	}
+	return decode(resp, nil)
+}
+
+func (es *Elastic) indexState(ctx context.Context, state *database.State) error {
+	reader, err := body(state)
+	if err != nil {
+		return err
+	}
+	resp, err := es.client.Index(IndexState, reader,
+		es.client.Index.WithContext(ctx),
+		es.client.Index.WithDocumentID(state.IndexName),
+		es.client.Index.WithRefresh(refresh),
+	)
+	if err != nil {
+		return err
+	}
+	return decode(resp, nil)
+}
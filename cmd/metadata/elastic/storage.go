@@ -0,0 +1,34 @@
+package elastic
+
+import (
+	"context"
+
+	"github.com/dipdup-net/go-lib/config"
+	"github.com/dipdup-net/metadata/cmd/metadata/models"
+	"github.com/pkg/errors"
+)
+
+// NewStorage - creates storage of indexer backed by Elasticsearch. `cfg.Path` is comma-separated list of nodes. Changes tracking, versions history and webhooks aren't supported, so their repositories are nil.
+func NewStorage(ctx context.Context, cfg config.Database, opts ...ElasticOption) (*models.Storage, error) {
+	if cfg.Kind != config.DBKindElasticSearch {
+		return nil, errors.Errorf("invalid database kind: want=%s got=%s", config.DBKindElasticSearch, cfg.Kind)
+	}
+
+	es, err := New(cfg.Path, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if err := es.Ping(ctx); err != nil {
+		return nil, errors.Wrap(err, "ping")
+	}
+	if err := es.CreateIndices(); err != nil {
+		return nil, errors.Wrap(err, "create indices")
+	}
+
+	return &models.Storage{
+		Backend:   es,
+		Tokens:    NewTokens(es),
+		Contracts: NewContracts(es),
+		TezosKeys: NewTezosKeys(es),
+	}, nil
+}
@@ -0,0 +1,179 @@
+package elastic
+
+import (
+	"context"
+	stdJSON "encoding/json"
+	"math"
+
+	"github.com/dipdup-net/metadata/cmd/metadata/models"
+)
+
+// suggestField - completion field of token and contract indices. Inputs are names (and symbols of tokens), weight is popularity of contract.
+const suggestField = "suggest"
+
+type suggestion struct {
+	Input    []string            `json:"input"`
+	Weight   int                 `json:"weight"`
+	Contexts map[string][]string `json:"contexts"`
+}
+
+type contractKey struct {
+	Network  string
+	Contract string
+}
+
+func (key contractKey) id() string {
+	return key.Network + ":" + key.Contract
+}
+
+// newSuggestion - suggestions are built for applied metadata with names only
+func newSuggestion(network string, status models.Status, weight int, inputs ...string) *suggestion {
+	if status != models.StatusApplied {
+		return nil
+	}
+
+	s := &suggestion{
+		Weight: weight,
+		Contexts: map[string][]string{
+			"network": {network},
+		},
+	}
+	for i := range inputs {
+		if inputs[i] != "" {
+			s.Input = append(s.Input, inputs[i])
+		}
+	}
+	if len(s.Input) == 0 {
+		return nil
+	}
+	return s
+}
+
+func tokenSuggestion(tm *models.TokenMetadata, weight int) *suggestion {
+	return newSuggestion(tm.Network, tm.Status, weight, tm.Name, tm.Symbol)
+}
+
+func contractSuggestion(cm *models.ContractMetadata, weight int) *suggestion {
+	if cm.Metadata.IsNull() {
+		return nil
+	}
+	var document struct {
+		Name any `json:"name"`
+	}
+	if err := stdJSON.Unmarshal(cm.Metadata, &document); err != nil {
+		return nil
+	}
+	name, _ := document.Name.(string)
+	return newSuggestion(cm.Network, cm.Status, weight, name)
+}
+
+// popularity - returns count of indexed tokens of contracts. It's used as weight of suggestions: names of big collections are suggested first.
+func (es *Elastic) popularity(ctx context.Context, keys []contractKey) (map[contractKey]int, error) {
+	byNetwork := make(map[string][]string)
+	for i := range keys {
+		byNetwork[keys[i].Network] = append(byNetwork[keys[i].Network], keys[i].Contract)
+	}
+
+	result := make(map[contractKey]int, len(keys))
+	for network, contracts := range byNetwork {
+		reader, err := body(map[string]any{
+			"size": 0,
+			"query": filter(
+				term("network.keyword", network),
+				map[string]any{
+					"terms": map[string]any{
+						"contract.keyword": contracts,
+					},
+				},
+			),
+			"aggs": map[string]any{
+				"contracts": map[string]any{
+					"terms": map[string]any{
+						"field": "contract.keyword",
+						"size":  len(contracts),
+					},
+				},
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+		resp, err := es.client.Search(
+			es.client.Search.WithContext(ctx),
+			es.client.Search.WithIndex(IndexTokens),
+			es.client.Search.WithBody(reader),
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		var response struct {
+			Aggregations struct {
+				Contracts struct {
+					Buckets []struct {
+						Key      string `json:"key"`
+						DocCount int    `json:"doc_count"`
+					} `json:"buckets"`
+				} `json:"contracts"`
+			} `json:"aggregations"`
+		}
+		if err := decode(resp, &response); err != nil {
+			return nil, err
+		}
+		for _, bucket := range response.Aggregations.Contracts.Buckets {
+			result[contractKey{network, bucket.Key}] = bucket.DocCount
+		}
+	}
+	return result, nil
+}
+
+// weight - weight of completion suggestion is positive int32
+func weight(popularity map[contractKey]int, key contractKey) int {
+	count := popularity[key]
+	switch {
+	case count < 1:
+		return 1
+	case count > math.MaxInt32:
+		return math.MaxInt32
+	default:
+		return count
+	}
+}
+
+// updateContractsPopularity - sets count of tokens to existing contract documents after tokens are saved. Contracts without metadata aren't indexed, so missing documents are skipped.
+func (es *Elastic) updateContractsPopularity(ctx context.Context, keys []contractKey) error {
+	popularity, err := es.popularity(ctx, keys)
+	if err != nil {
+		return err
+	}
+
+	lines := make([]any, 0, len(keys)*2)
+	for _, key := range keys {
+		lines = append(lines,
+			action{Update: &actionTarget{ID: key.id()}},
+			map[string]any{
+				"script": map[string]any{
+					"source": "ctx._source.token_count = params.count; if (ctx._source." + suggestField + " != null) { ctx._source." + suggestField + ".weight = params.weight }",
+					"params": map[string]any{
+						"count":  popularity[key],
+						"weight": weight(popularity, key),
+					},
+				},
+			},
+		)
+	}
+	return es.bulk(ctx, IndexContracts, lines, "document_missing_exception")
+}
+
+func uniqueContracts[T any](items []T, key func(T) contractKey) []contractKey {
+	keys := make([]contractKey, 0)
+	has := make(map[contractKey]struct{})
+	for i := range items {
+		k := key(items[i])
+		if _, ok := has[k]; !ok {
+			has[k] = struct{}{}
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
@@ -0,0 +1,136 @@
+package elastic
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	stdJSON "encoding/json"
+	"net/http"
+
+	"github.com/dipdup-net/metadata/cmd/metadata/models"
+	"github.com/go-pg/pg/v10"
+)
+
+type tezosKey struct {
+	Network string `json:"network"`
+	Address string `json:"address"`
+	Key     string `json:"key"`
+	Value   []byte `json:"value"`
+}
+
+func tezosKeyID(network, address, key string) string {
+	hash := sha256.Sum256([]byte(network + "/" + address + "/" + key))
+	return hex.EncodeToString(hash[:])
+}
+
+// TezosKeys - storage of `tezos-storage:` values
+type TezosKeys struct {
+	es *Elastic
+}
+
+// NewTezosKeys -
+func NewTezosKeys(es *Elastic) *TezosKeys {
+	return &TezosKeys{es}
+}
+
+// Get - returns first key matching non-empty arguments. Returns `pg.ErrNoRows` if it's not found.
+func (keys *TezosKeys) Get(network, address, key string) (models.TezosKey, error) {
+	ctx := context.Background()
+
+	if network != "" && address != "" && key != "" {
+		return keys.getByID(ctx, tezosKeyID(network, address, key))
+	}
+
+	response, err := keys.es.search(ctx, IndexTezosKeys, map[string]any{
+		"size":  1,
+		"query": tezosKeyQuery(network, address, key),
+	})
+	if err != nil {
+		return models.TezosKey{}, err
+	}
+	if len(response.Hits.Hits) == 0 {
+		return models.TezosKey{}, pg.ErrNoRows
+	}
+	return parseTezosKey(response.Hits.Hits[0].Source)
+}
+
+func (keys *TezosKeys) getByID(ctx context.Context, id string) (models.TezosKey, error) {
+	resp, err := keys.es.client.Get(IndexTezosKeys, id, keys.es.client.Get.WithContext(ctx))
+	if err != nil {
+		return models.TezosKey{}, err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return models.TezosKey{}, pg.ErrNoRows
+	}
+
+	var response hit
+	if err := decode(resp, &response); err != nil {
+		return models.TezosKey{}, err
+	}
+	return parseTezosKey(response.Source)
+}
+
+// Save - creates or replaces key value
+func (keys *TezosKeys) Save(tk models.TezosKey) error {
+	return keys.es.bulk(context.Background(), IndexTezosKeys, []any{
+		action{Index: &actionTarget{ID: tezosKeyID(tk.Network, tk.Address, tk.Key)}},
+		tezosKey{
+			Network: tk.Network,
+			Address: tk.Address,
+			Key:     tk.Key,
+			Value:   tk.Value,
+		},
+	})
+}
+
+// Delete - removes keys matching non-empty fields of `tk`
+func (keys *TezosKeys) Delete(tk models.TezosKey) error {
+	ctx := context.Background()
+
+	if tk.Network == "" || tk.Address == "" || tk.Key == "" {
+		return keys.es.deleteByQuery(ctx, IndexTezosKeys, map[string]any{
+			"query": tezosKeyQuery(tk.Network, tk.Address, tk.Key),
+		})
+	}
+
+	resp, err := keys.es.client.Delete(IndexTezosKeys, tezosKeyID(tk.Network, tk.Address, tk.Key),
+		keys.es.client.Delete.WithContext(ctx),
+		keys.es.client.Delete.WithRefresh(refresh),
+	)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil
+	}
+	return decode(resp, nil)
+}
+
+func tezosKeyQuery(network, address, key string) map[string]any {
+	filters := make([]map[string]any, 0, 3)
+	if network != "" {
+		filters = append(filters, term("network.keyword", network))
+	}
+	if address != "" {
+		filters = append(filters, term("address.keyword", address))
+	}
+	if key != "" {
+		filters = append(filters, term("key.keyword", key))
+	}
+	return filter(filters...)
+}
+
+func parseTezosKey(data stdJSON.RawMessage) (models.TezosKey, error) {
+	var doc tezosKey
+	if err := stdJSON.Unmarshal(data, &doc); err != nil {
+		return models.TezosKey{}, err
+	}
+	return models.TezosKey{
+		Network: doc.Network,
+		Address: doc.Address,
+		Key:     doc.Key,
+		Value:   doc.Value,
+	}, nil
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+
+	api "github.com/dipdup-net/go-lib/tzkt/data"
+	"github.com/dipdup-net/metadata/cmd/metadata/models"
+	"github.com/pkg/errors"
+)
+
+func newContractHistory(update api.BigMapUpdate, cm *models.ContractMetadata) *models.ContractMetadataHistory {
+	return &models.ContractMetadataHistory{
+		Network:        cm.Network,
+		Contract:       cm.Contract,
+		Level:          update.Level,
+		Timestamp:      update.Timestamp.UTC(),
+		BigMapUpdateID: update.ID,
+		Action:         update.Action,
+		Link:           cm.Link,
+		Status:         cm.Status,
+		Metadata:       cm.Metadata,
+		ContentHash:    models.ContentHash(cm.Metadata),
+	}
+}
+
+func newTokenHistory(update api.BigMapUpdate, tm *models.TokenMetadata) *models.TokenMetadataHistory {
+	return &models.TokenMetadataHistory{
+		Network:        tm.Network,
+		Contract:       tm.Contract,
+		TokenID:        tm.TokenID,
+		Level:          update.Level,
+		Timestamp:      update.Timestamp.UTC(),
+		BigMapUpdateID: update.ID,
+		Action:         update.Action,
+		Link:           tm.Link,
+		Status:         tm.Status,
+		Metadata:       tm.Metadata,
+		ContentHash:    models.ContentHash(tm.Metadata),
+		Source:         tm.Source,
+	}
+}
+
+func (indexer *Indexer) onContractsResolved(ctx context.Context, contracts []*models.ContractMetadata) error {
+	if err := indexer.db.History.ResolveContracts(ctx, contracts); err != nil {
+		return errors.Wrap(err, "resolve contract metadata history")
+	}
+	return indexer.notifyContracts(ctx, contracts)
+}
+
+func (indexer *Indexer) onTokensResolved(ctx context.Context, tokens []*models.TokenMetadata) error {
+	if err := indexer.db.History.ResolveTokens(ctx, tokens); err != nil {
+		return errors.Wrap(err, "resolve token metadata history")
+	}
+	return indexer.notifyTokens(ctx, tokens)
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	api "github.com/dipdup-net/go-lib/tzkt/data"
+	"github.com/dipdup-net/metadata/cmd/metadata/models"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_newTokenHistory(t *testing.T) {
+	timestamp := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		update api.BigMapUpdate
+		token  *models.TokenMetadata
+		want   *models.TokenMetadataHistory
+	}{
+		{
+			name: "link",
+			update: api.BigMapUpdate{
+				ID:        4163559,
+				Level:     1477522,
+				Timestamp: timestamp.In(time.FixedZone("UTC+3", 3*60*60)),
+				Action:    "add_key",
+			},
+			token: &models.TokenMetadata{
+				Network:  "mainnet",
+				Contract: "KT1G1cCRNBgQ48mVDjopHjEmTN5Sbtar8nn9",
+				TokenID:  decimal.NewFromInt(1),
+				Link:     "ipfs://QmXL3FZ5kcwXC8mdwkS1iCHS2qVoyg69ugBhU2ap8z1zcs",
+				Status:   models.StatusNew,
+				Source:   models.TokenSourceBigMap,
+			},
+			want: &models.TokenMetadataHistory{
+				Network:        "mainnet",
+				Contract:       "KT1G1cCRNBgQ48mVDjopHjEmTN5Sbtar8nn9",
+				TokenID:        decimal.NewFromInt(1),
+				Level:          1477522,
+				Timestamp:      timestamp,
+				BigMapUpdateID: 4163559,
+				Action:         "add_key",
+				Link:           "ipfs://QmXL3FZ5kcwXC8mdwkS1iCHS2qVoyg69ugBhU2ap8z1zcs",
+				Status:         models.StatusNew,
+				Source:         models.TokenSourceBigMap,
+			},
+		}, {
+			name: "on-chain metadata",
+			update: api.BigMapUpdate{
+				ID:        4163560,
+				Level:     1477523,
+				Timestamp: timestamp,
+				Action:    "update_key",
+			},
+			token: &models.TokenMetadata{
+				Network:  "mainnet",
+				Contract: "KT1G1cCRNBgQ48mVDjopHjEmTN5Sbtar8nn9",
+				TokenID:  decimal.NewFromInt(0),
+				Metadata: models.JSONB(`{"name":"Hedgehoge"}`),
+				Status:   models.StatusApplied,
+				Source:   models.TokenSourceBigMap,
+			},
+			want: &models.TokenMetadataHistory{
+				Network:        "mainnet",
+				Contract:       "KT1G1cCRNBgQ48mVDjopHjEmTN5Sbtar8nn9",
+				TokenID:        decimal.NewFromInt(0),
+				Level:          1477523,
+				Timestamp:      timestamp,
+				BigMapUpdateID: 4163560,
+				Action:         "update_key",
+				Metadata:       models.JSONB(`{"name":"Hedgehoge"}`),
+				ContentHash:    "60805056fbe4ab41b3e95c105ea509fea9deefc21c85498088b91bfc70bf705e",
+				Status:         models.StatusApplied,
+				Source:         models.TokenSourceBigMap,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, newTokenHistory(tt.update, tt.token))
+		})
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/go-pg/pg/v10"
 	"github.com/pkg/errors"
@@ -13,8 +15,11 @@ import (
 
 	generalConfig "github.com/dipdup-net/go-lib/config"
 	"github.com/dipdup-net/go-lib/database"
+	tzktAPI "github.com/dipdup-net/go-lib/tzkt/api"
+	"github.com/dipdup-net/go-lib/tzkt/events"
 	"github.com/dipdup-net/metadata/cmd/metadata/config"
 	"github.com/dipdup-net/metadata/cmd/metadata/models"
+	"github.com/dipdup-net/metadata/cmd/metadata/offchainviews"
 	"github.com/dipdup-net/metadata/cmd/metadata/prometheus"
 	"github.com/dipdup-net/metadata/cmd/metadata/resolver"
 	"github.com/dipdup-net/metadata/cmd/metadata/service"
@@ -22,9 +27,16 @@ import (
 	"github.com/dipdup-net/metadata/cmd/metadata/tezoskeys"
 	"github.com/dipdup-net/metadata/cmd/metadata/thumbnail"
 	"github.com/dipdup-net/metadata/cmd/metadata/tzkt"
+	"github.com/dipdup-net/metadata/cmd/metadata/webhooks"
 	"github.com/dipdup-net/metadata/internal/ipfs"
 )
 
+// rollbackDepth - count of levels for which changes are kept to revert them on reorg
+const rollbackDepth = 100
+
+// leaderCheckInterval - how often follower tries to become leader and leader checks its lock
+const leaderCheckInterval = 5 * time.Second
+
 var createIndex sync.Once
 
 // Indexer -
@@ -32,14 +44,18 @@ type Indexer struct {
 	network   string
 	indexName string
 	state     *database.State
+	head      atomic.Pointer[database.State]
 	resolver  resolver.Receiver
-	db        *models.Database
-	scanner   *tzkt.Scanner
+	retrier   *resolver.Retrier
+	db        *models.Storage
+	tzkt      generalConfig.DataSource
 	prom      *prometheus.Prometheus
 	tezosKeys *tezoskeys.TezosKeys
 	contracts *service.Service[*models.ContractMetadata]
 	tokens    *service.Service[*models.TokenMetadata]
 	thumbnail *thumbnail.Service
+	views     *offchainviews.Service
+	webhooks  *webhooks.Service
 	settings  config.Settings
 	filters   config.Filters
 
@@ -47,24 +63,29 @@ type Indexer struct {
 }
 
 // NewIndexer -
-func NewIndexer(ctx context.Context, network string, indexerConfig *config.Indexer, database generalConfig.Database, filters config.Filters, settings config.Settings, prom *prometheus.Prometheus, node *ipfs.Node) (*Indexer, error) {
-	db, err := models.NewDatabase(ctx, database)
+func NewIndexer(ctx context.Context, network string, indexerConfig *config.Indexer, database generalConfig.Database, filters config.Filters, settings config.Settings, prom *prometheus.Prometheus, node *ipfs.Node, gateways *ipfs.Scores, networks *tezoskeys.Networks) (*Indexer, error) {
+	db, err := newStorage(ctx, database)
 	if err != nil {
 		return nil, err
 	}
-	keys := tezoskeys.NewTezosKeys(db.TezosKeys)
+	keys := tezoskeys.NewTezosKeys(db.TezosKeys, tezoskeys.WithNetworks(networks), tezoskeys.WithChanges(db.Changes))
 
-	metadataResolver, err := resolver.New(ctx, settings, keys, node)
-	if err != nil {
-		return nil, err
-	}
-	scanner, err := tzkt.New(indexerConfig.DataSource.Tzkt.Struct(), filters.Addresses()...)
+	metadataResolver, err := resolver.New(ctx, settings, keys, node, resolver.WithHostObserver(func(host string, open bool) {
+		if open {
+			log.Warn().Str("network", network).Str("host", host).Msg("host is parked after failures")
+		} else {
+			log.Info().Str("network", network).Str("host", host).Msg("host is available again")
+		}
+		prom.SetHTTPCircuit(network, host, open)
+	}))
 	if err != nil {
 		return nil, err
 	}
+	retrier := resolver.NewRetrier(settings.Retry, settings.IPFS.Delay, settings.MaxRetryCountOnError)
 
 	indexer := &Indexer{
-		scanner:   scanner,
+		tzkt:      indexerConfig.DataSource.Tzkt.Struct(),
+		retrier:   retrier,
 		network:   network,
 		indexName: models.IndexName(network),
 		resolver:  metadataResolver,
@@ -77,29 +98,63 @@ func NewIndexer(ctx context.Context, network string, indexerConfig *config.Index
 	}
 
 	if aws := storage.NewAWS(settings.AWS); aws != nil {
+		tokens, ok := db.Tokens.(*models.Tokens)
+		if !ok {
+			return nil, errors.Errorf("thumbnails are not supported by database %s", database.Kind)
+		}
 		indexer.thumbnail = thumbnail.New(
-			aws, db.Tokens.(*models.Tokens), network, settings.IPFS.Gateways,
+			aws, tokens, network, settings.IPFS.Gateways,
 			thumbnail.WithPrometheus(prom),
+			thumbnail.WithGatewayScores(gateways),
 			thumbnail.WithWorkers(settings.Thumbnail.Workers),
 			thumbnail.WithFileSizeLimit(settings.Thumbnail.MaxFileSize),
 			thumbnail.WithSize(settings.Thumbnail.Size),
 			thumbnail.WithTimeout(settings.Thumbnail.Timeout),
 		)
 	}
-	indexer.contracts = service.NewService(
-		db.Contracts, indexer.resolveContractMetadata, network,
-		service.WithMaxRetryCount[*models.ContractMetadata](settings.MaxRetryCountOnError),
+	if node := indexerConfig.DataSource.Node.Struct(); node.URL != "" && db.Updates != nil {
+		indexer.views = offchainviews.New(
+			offchainviews.NewRPC(node.URL, time.Duration(settings.HTTPTimeout)*time.Second),
+			offchainviews.NewTzKT(tzktAPI.New(indexerConfig.DataSource.Tzkt.Struct().URL)),
+			indexer.handleViewTokens,
+		)
+	}
+
+	if endpoints := settings.Webhooks.Endpoints; len(endpoints) > 0 {
+		if db.Webhooks == nil {
+			return nil, errors.Errorf("webhooks are not supported by database %s", database.Kind)
+		}
+		indexer.webhooks = webhooks.New(
+			db.Webhooks, endpoints, network,
+			webhooks.WithMaxAttempts(settings.Webhooks.MaxAttempts),
+			webhooks.WithTimeout(settings.Webhooks.Timeout),
+			webhooks.WithPrometheus(prom),
+		)
+	}
+
+	contractOpts := []service.ServiceOption[*models.ContractMetadata]{
+		service.WithRevivedErrors[*models.ContractMetadata](retrier.Revived()),
 		service.WithWorkersCount[*models.ContractMetadata](settings.ContractServiceWorkers),
 		service.WithPrometheus[*models.ContractMetadata](prom, prometheus.MetadataTypeContract),
-		service.WithDelay[*models.ContractMetadata](settings.IPFS.Delay),
-	)
-	indexer.tokens = service.NewService(
-		db.Tokens, indexer.resolveTokenMetadata, network,
-		service.WithMaxRetryCount[*models.TokenMetadata](settings.MaxRetryCountOnError),
+		service.WithPublisher(indexer.onContractsResolved),
+		service.WithTransactions[*models.ContractMetadata](db.Transactions),
+	}
+	if db.ContractJobs != nil {
+		contractOpts = append(contractOpts, service.WithJobQueue[*models.ContractMetadata](db.ContractJobs))
+	}
+	indexer.contracts = service.NewService(db.Contracts, indexer.resolveContractMetadata, network, contractOpts...)
+
+	tokenOpts := []service.ServiceOption[*models.TokenMetadata]{
+		service.WithRevivedErrors[*models.TokenMetadata](retrier.Revived()),
 		service.WithWorkersCount[*models.TokenMetadata](settings.TokenServiceWorkers),
 		service.WithPrometheus[*models.TokenMetadata](prom, prometheus.MetadataTypeToken),
-		service.WithDelay[*models.TokenMetadata](settings.IPFS.Delay),
-	)
+		service.WithPublisher(indexer.onTokensResolved),
+		service.WithTransactions[*models.TokenMetadata](db.Transactions),
+	}
+	if db.TokenJobs != nil {
+		tokenOpts = append(tokenOpts, service.WithJobQueue[*models.TokenMetadata](db.TokenJobs))
+	}
+	indexer.tokens = service.NewService(db.Tokens, indexer.resolveTokenMetadata, network, tokenOpts...)
 
 	return indexer, nil
 }
@@ -120,10 +175,6 @@ func (indexer *Indexer) Start(ctx context.Context) error {
 		return nil
 	}
 
-	if indexer.thumbnail != nil {
-		indexer.thumbnail.Start(ctx)
-	}
-
 	if indexer.prom != nil {
 		newContractCount, err := indexer.db.Contracts.CountByStatus(indexer.network, models.StatusNew)
 		if err != nil {
@@ -142,15 +193,138 @@ func (indexer *Indexer) Start(ctx context.Context) error {
 	indexer.tokens.Start(ctx)
 
 	indexer.wg.Add(1)
-	go indexer.listen(ctx)
+	go indexer.lead(ctx)
+
+	return nil
+}
+
+// lead - runs scanner, webhooks delivery, thumbnails and off-chain views while instance is leader of network, so they aren't duplicated by instances sharing database. Metadata is resolved by all instances.
+func (indexer *Indexer) lead(ctx context.Context) {
+	defer indexer.wg.Done()
+
+	if indexer.db.Leaders == nil {
+		indexer.term(ctx)
+		return
+	}
+
+	ticker := time.NewTicker(leaderCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		leadership, err := indexer.db.Leaders.TryAcquire(ctx, indexer.indexName)
+		switch {
+		case err != nil:
+			if !errors.Is(err, context.Canceled) {
+				log.Err(err).Str("name", indexer.indexName).Msg("acquire leadership")
+			}
+		case leadership != nil:
+			log.Info().Str("name", indexer.indexName).Msg("instance is leader")
+			indexer.hold(ctx, leadership, ticker)
+
+			// context is canceled already
+			if err := leadership.Release(context.Background()); err != nil {
+				log.Err(err).Str("name", indexer.indexName).Msg("release leadership")
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// hold - runs term of leader till context is canceled or lock is lost
+func (indexer *Indexer) hold(ctx context.Context, leadership *models.Leadership, ticker *time.Ticker) {
+	termCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		indexer.term(termCtx)
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			<-done
+			return
+		case <-done:
+			// term failed to start, leadership is released to retry
+			return
+		case <-ticker.C:
+			held, err := leadership.Held(ctx)
+			if err == nil && held {
+				continue
+			}
+			if errors.Is(err, context.Canceled) {
+				continue
+			}
+			log.Warn().Err(err).Str("name", indexer.indexName).Msg("leadership is lost")
+			cancel()
+			<-done
+			return
+		}
+	}
+}
+
+// term - indexes chain from the saved state till context is canceled
+func (indexer *Indexer) term(ctx context.Context) {
+	// state may be moved by previous leader
+	state, err := indexer.db.State(ctx, indexer.indexName)
+	if err != nil {
+		if !errors.Is(err, context.Canceled) {
+			log.Err(err).Str("name", indexer.indexName).Msg("state")
+		}
+		return
+	}
+	indexer.state = state
+	indexer.publishHead()
+
+	scanner, err := tzkt.New(indexer.tzkt, indexer.filters.Addresses()...)
+	if err != nil {
+		log.Err(err).Str("name", indexer.indexName).Msg("tzkt.New")
+		return
+	}
+
+	if indexer.thumbnail != nil {
+		indexer.thumbnail.Start(ctx)
+	}
+
+	if indexer.webhooks != nil {
+		indexer.webhooks.Start(ctx)
+	}
+
+	// views are bound to head which is moved by leader only
+	if indexer.views != nil {
+		indexer.views.Start(ctx)
+
+		indexer.wg.Add(1)
+		go indexer.watchViews(ctx)
+	}
 
 	startLevel := indexer.state.Level
 	if indexer.filters.FirstLevel > 0 && startLevel < indexer.filters.FirstLevel {
 		startLevel = indexer.filters.FirstLevel
 	}
-	indexer.scanner.Start(ctx, startLevel, indexer.filters.LastLevel)
+	scanner.Start(ctx, startLevel, indexer.filters.LastLevel)
 
-	return nil
+	indexer.listen(ctx, scanner)
+	// scanner is stopped by context only
+	<-ctx.Done()
+
+	if err := scanner.Close(); err != nil {
+		log.Err(err).Str("name", indexer.indexName).Msg("scanner.Close")
+	}
+
+	// next term doesn't start before views of this one are stopped
+	if indexer.views != nil {
+		if err := indexer.views.Close(); err != nil {
+			log.Err(err).Str("name", indexer.indexName).Msg("views.Close")
+		}
+	}
 }
 
 // Close -
@@ -158,10 +332,6 @@ func (indexer *Indexer) Close() error {
 	indexer.log().Msg("closing indexer...")
 	indexer.wg.Wait()
 
-	if err := indexer.scanner.Close(); err != nil {
-		return err
-	}
-
 	if err := indexer.tokens.Close(); err != nil {
 		return err
 	}
@@ -170,6 +340,12 @@ func (indexer *Indexer) Close() error {
 		return err
 	}
 
+	if indexer.webhooks != nil {
+		if err := indexer.webhooks.Close(); err != nil {
+			return err
+		}
+	}
+
 	if indexer.thumbnail != nil {
 		if err := indexer.thumbnail.Close(); err != nil {
 			return err
@@ -205,9 +381,16 @@ func (indexer *Indexer) initState(ctx context.Context) error {
 			return err
 		}
 	}
+	indexer.publishHead()
 	return indexer.initialTokenMetadata(ctx)
 }
 
+// publishHead - state is changed by indexing loop only, workers of services read its copy
+func (indexer *Indexer) publishHead() {
+	head := *indexer.state
+	indexer.head.Store(&head)
+}
+
 func (indexer *Indexer) initCounters() error {
 	contractActionsCounter, err := indexer.db.Contracts.LastUpdateID()
 	if err != nil {
@@ -224,26 +407,33 @@ func (indexer *Indexer) initCounters() error {
 	return nil
 }
 
+// log - it's called by workers of services too, so level is taken from published head
 func (indexer *Indexer) log() *zerolog.Event {
-	return log.Info().Uint64("state", indexer.state.Level).Str("name", indexer.indexName)
+	var level uint64
+	if head := indexer.head.Load(); head != nil {
+		level = head.Level
+	}
+	return log.Info().Uint64("state", level).Str("name", indexer.indexName)
 }
 
-func (indexer *Indexer) listen(ctx context.Context) {
-	defer indexer.wg.Done()
-
+func (indexer *Indexer) listen(ctx context.Context, scanner *tzkt.Scanner) {
 	for {
 		select {
 		case <-ctx.Done():
 			return
-		case msg := <-indexer.scanner.BigMaps():
+		case msg := <-scanner.BigMaps():
 			if err := indexer.handlerUpdate(ctx, msg); err != nil {
 				log.Err(err).Msg("handlerUpdate")
 			}
-		case block := <-indexer.scanner.Blocks():
+		case block := <-scanner.Blocks():
 			if block.Level > indexer.state.Level+1 {
 				indexer.state.Level = block.Level
 				indexer.state.Hash = block.Hash
 				indexer.state.Timestamp = block.Timestamp.UTC()
+				indexer.publishHead()
+				if indexer.views != nil {
+					indexer.views.Refresh(indexer.state.Level, indexer.state.Timestamp)
+				}
 				if err := indexer.db.UpdateState(ctx, indexer.state); err != nil {
 					log.Err(err).Msg("UpdateState")
 				} else {
@@ -260,8 +450,14 @@ func (indexer *Indexer) listen(ctx context.Context) {
 }
 
 func (indexer *Indexer) handlerUpdate(ctx context.Context, msg tzkt.Message) error {
+	if msg.Type == events.MessageTypeReorg {
+		return indexer.rollback(ctx, msg.Level)
+	}
+
 	tokens := make([]*models.TokenMetadata, 0)
 	contracts := make([]*models.ContractMetadata, 0)
+	tokensHistory := make([]*models.TokenMetadataHistory, 0)
+	contractsHistory := make([]*models.ContractMetadataHistory, 0)
 	for i := range msg.Body {
 		path := strings.Split(msg.Body[i].Path, ".")
 
@@ -273,6 +469,7 @@ func (indexer *Indexer) handlerUpdate(ctx context.Context, msg tzkt.Message) err
 			}
 			if token != nil {
 				tokens = append(tokens, token)
+				tokensHistory = append(tokensHistory, newTokenHistory(msg.Body[i], token))
 			}
 		case "metadata":
 			contract, err := indexer.processContractMetadata(msg.Body[i])
@@ -280,18 +477,89 @@ func (indexer *Indexer) handlerUpdate(ctx context.Context, msg tzkt.Message) err
 				return errors.Wrap(err, "contract_metadata")
 			}
 			if contract != nil {
-				indexer.prom.IncrementMetadataNew(indexer.network, prometheus.MetadataTypeContract)
+				if contract.Status == models.StatusNew {
+					indexer.prom.IncrementMetadataNew(indexer.network, prometheus.MetadataTypeContract)
+				}
 				contracts = append(contracts, contract)
+				contractsHistory = append(contractsHistory, newContractHistory(msg.Body[i], contract))
 			}
 		}
 	}
 
-	if err := indexer.db.Contracts.Save(contracts); err != nil {
+	// versions, changes, metadata and webhooks outbox of the message are written at once, so rollback always finds changes of saved rows
+	if err := indexer.db.Transactions.Run(ctx, func(ctx context.Context) error {
+		if err := indexer.db.History.AddContracts(ctx, contractsHistory); err != nil {
+			return errors.Wrap(err, "contract metadata history")
+		}
+		if err := indexer.db.History.AddTokens(ctx, tokensHistory); err != nil {
+			return errors.Wrap(err, "token metadata history")
+		}
+
+		if err := indexer.db.Changes.TrackContracts(ctx, indexer.network, msg.Level, contracts); err != nil {
+			return errors.Wrap(err, "track contract changes")
+		}
+		if err := indexer.db.Contracts.Save(ctx, contracts); err != nil {
+			return err
+		}
+
+		if err := indexer.db.Changes.TrackTokens(ctx, indexer.network, msg.Level, tokens); err != nil {
+			return errors.Wrap(err, "track token changes")
+		}
+		if err := indexer.db.Tokens.Save(ctx, tokens); err != nil {
+			return err
+		}
+
+		if err := indexer.notifyContracts(ctx, contracts); err != nil {
+			return err
+		}
+		return indexer.notifyTokens(ctx, tokens)
+	}); err != nil {
 		return err
 	}
 
-	if err := indexer.db.Tokens.Save(tokens); err != nil {
+	if msg.Level > rollbackDepth {
+		if err := indexer.db.Changes.Prune(indexer.network, msg.Level-rollbackDepth); err != nil {
+			return errors.Wrap(err, "prune changes")
+		}
+	}
+	return nil
+}
+
+func (indexer *Indexer) rollback(ctx context.Context, level uint64) error {
+	if indexer.db.Changes == nil {
+		log.Warn().Str("name", indexer.indexName).Uint64("level", level).Msg("changes are not tracked by database, metadata is kept as is on rollback")
+	}
+
+	state := *indexer.state
+	state.Level = level
+	state.Hash = ""
+
+	// metadata, versions and state are reverted at once, so crash doesn't leave state pointing past reverted metadata
+	var reverted models.Reverted
+	if err := indexer.db.Transactions.Run(ctx, func(ctx context.Context) error {
+		var err error
+		reverted, err = indexer.db.Changes.Rollback(ctx, indexer.network, level)
+		if err != nil {
+			return errors.Wrap(err, "rollback")
+		}
+		if err := indexer.db.History.Rollback(ctx, indexer.network, level); err != nil {
+			return errors.Wrap(err, "rollback history")
+		}
+		if err := indexer.db.UpdateState(ctx, &state); err != nil {
+			return err
+		}
+
+		if err := indexer.notifyContracts(ctx, reverted.Contracts); err != nil {
+			return err
+		}
+		return indexer.notifyTokens(ctx, reverted.Tokens)
+	}); err != nil {
 		return err
 	}
+
+	*indexer.state = state
+	indexer.publishHead()
+
+	log.Warn().Str("name", indexer.indexName).Uint64("level", level).Int("reverted", reverted.Changes).Msg("rolled back")
 	return nil
 }
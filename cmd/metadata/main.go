@@ -17,9 +17,14 @@ import (
 
 	golibConfig "github.com/dipdup-net/go-lib/config"
 	"github.com/dipdup-net/go-lib/hasura"
+	tzktAPI "github.com/dipdup-net/go-lib/tzkt/api"
+	"github.com/dipdup-net/metadata/cmd/metadata/broker"
 	"github.com/dipdup-net/metadata/cmd/metadata/config"
+	"github.com/dipdup-net/metadata/cmd/metadata/elastic"
 	"github.com/dipdup-net/metadata/cmd/metadata/models"
 	"github.com/dipdup-net/metadata/cmd/metadata/prometheus"
+	"github.com/dipdup-net/metadata/cmd/metadata/rest"
+	"github.com/dipdup-net/metadata/cmd/metadata/tezoskeys"
 	"github.com/dipdup-net/metadata/internal/ipfs"
 )
 
@@ -42,16 +47,20 @@ func main() {
 	}).Level(zerolog.InfoLevel)
 
 	configPath := rootCmd.PersistentFlags().StringP("config", "c", "dipdup.yml", "path to YAML config file")
+	rootCmd.Run = func(cmd *cobra.Command, args []string) {
+		run(*configPath)
+	}
+	rootCmd.AddCommand(newBackfillCmd(configPath))
+	rootCmd.AddCommand(newReindexCmd(configPath))
+
 	if err := rootCmd.Execute(); err != nil {
 		log.Panic().Err(err).Msg("command line execute")
 		return
 	}
-	if err := rootCmd.MarkFlagRequired("config"); err != nil {
-		log.Panic().Err(err).Msg("config command line arg is required")
-		return
-	}
+}
 
-	cfg, err := config.Load(*configPath)
+func run(configPath string) {
+	cfg, err := config.Load(configPath)
 	if err != nil {
 		log.Err(err).Msg("")
 		return
@@ -59,6 +68,7 @@ func main() {
 	runtime.GOMAXPROCS(cfg.Metadata.Settings.MaxCPU)
 
 	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 
 	signals := make(chan os.Signal, 1)
 	signal.Notify(signals, os.Interrupt, syscall.SIGTERM, syscall.SIGINT)
@@ -68,21 +78,28 @@ func main() {
 		prometheusService.Start()
 	}
 
-	if err := execScripts(ctx, cfg.Database); err != nil {
-		log.Err(err).Msg("execScripts")
-		return
-	}
+	var (
+		views          []string
+		custom_configs []hasura.Request
+	)
+	// Elasticsearch indices are created by indexers, SQL scripts, views and Hasura are Postgres only
+	if !isElastic(cfg.Database) {
+		if err := execScripts(ctx, cfg.Database); err != nil {
+			log.Err(err).Msg("execScripts")
+			return
+		}
 
-	views, err := createViews(ctx, cfg.Database)
-	if err != nil {
-		log.Err(err).Msg("createViews")
-		return
-	}
+		views, err = createViews(ctx, cfg.Database)
+		if err != nil {
+			log.Err(err).Msg("createViews")
+			return
+		}
 
-	custom_configs, err := hasura.ReadCustomConfigs(ctx, cfg.Database, "custom_hasura_config")
-	if err != nil {
-		log.Err(err).Msg("readCustomHasuraConfigs")
-		return
+		custom_configs, err = hasura.ReadCustomConfigs(ctx, cfg.Database, "custom_hasura_config")
+		if err != nil {
+			log.Err(err).Msg("readCustomHasuraConfigs")
+			return
+		}
 	}
 
 	ipfsNode, err := ipfs.NewNode(ctx, cfg.Metadata.Settings.IPFS.Dir, 1024*1024, cfg.Metadata.Settings.IPFS.Blacklist, cfg.Metadata.Settings.IPFS.Providers)
@@ -96,13 +113,52 @@ func main() {
 		return
 	}
 
+	gateways := ipfs.NewScores(cfg.Metadata.Settings.IPFS.Gateways,
+		ipfs.WithQuarantineFailures(cfg.Metadata.Settings.IPFS.QuarantineFailures),
+		ipfs.WithProbeInterval(time.Duration(cfg.Metadata.Settings.IPFS.ProbeInterval)*time.Second),
+		ipfs.WithScoreObserver(prometheusService.SetGatewayScore),
+	)
+	gateways.Start(ctx)
+
 	var indexers sync.Map
 	var indexerCancels sync.Map
 
+	var (
+		restServer *rest.Server
+		apiDB      *models.Database
+		events     *broker.Broker
+		feed       *broker.Feed
+	)
+	if bind := cfg.Metadata.Settings.API.Bind; bind != "" && isElastic(cfg.Database) {
+		log.Warn().Str("bind", bind).Msg("REST API is not supported by Elasticsearch database, use `api` service to search metadata")
+	} else if bind != "" {
+		apiDB, err = models.NewDatabase(ctx, cfg.Database)
+		if err != nil {
+			log.Err(err).Msg("models.NewDatabase")
+			return
+		}
+
+		networks := make([]string, 0, len(cfg.Metadata.Indexers))
+		for network := range cfg.Metadata.Indexers {
+			networks = append(networks, network)
+		}
+
+		events = broker.New()
+		feed = broker.NewFeed(apiDB, events, networks)
+		feed.Start(ctx)
+		restServer = rest.New(apiDB, bind, rest.WithBroker(events), rest.WithGatewayScores(gateways), rest.WithAdminToken(cfg.Metadata.Settings.API.AdminToken))
+		restServer.Start()
+	}
+
+	networks := tezoskeys.NewNetworks()
+	for network, indexer := range cfg.Metadata.Indexers {
+		networks.Add(network, tzktAPI.New(indexer.DataSource.Tzkt.Struct().URL))
+	}
+
 	var hasuraInit sync.Once
 	for network, indexer := range cfg.Metadata.Indexers {
 		go func(network string, ind *config.Indexer) {
-			result, err := startIndexer(ctx, cfg, *ind, network, prometheusService, ipfsNode, views, custom_configs, &hasuraInit)
+			result, err := startIndexer(ctx, cfg, *ind, network, prometheusService, ipfsNode, gateways, networks, views, custom_configs, &hasuraInit)
 			if err != nil {
 				log.Err(err).Str("network", network).Msg("startIndexer")
 			} else {
@@ -119,7 +175,7 @@ func main() {
 				case <-ctx.Done():
 					return
 				case <-ticker.C:
-					result, err := startIndexer(ctx, cfg, *ind, network, prometheusService, ipfsNode, views, custom_configs, &hasuraInit)
+					result, err := startIndexer(ctx, cfg, *ind, network, prometheusService, ipfsNode, gateways, networks, views, custom_configs, &hasuraInit)
 					if err != nil {
 						log.Err(err).Str("network", network).Msg("startIndexer")
 					} else {
@@ -141,6 +197,18 @@ func main() {
 		return true
 	})
 
+	if restServer != nil {
+		if err := events.Close(); err != nil {
+			log.Err(err).Msg("events.Close()")
+		}
+		if err := restServer.Close(); err != nil {
+			log.Err(err).Msg("restServer.Close()")
+		}
+		if err := apiDB.Close(); err != nil {
+			log.Err(err).Msg("apiDB.Close()")
+		}
+	}
+
 	log.Warn().Msgf("Trying carefully stopping....")
 	indexers.Range(func(key, value interface{}) bool {
 		if err := value.(*Indexer).Close(); err != nil {
@@ -151,6 +219,16 @@ func main() {
 
 	cancel()
 
+	if feed != nil {
+		if err := feed.Close(); err != nil {
+			log.Err(err).Msg("feed.Close()")
+		}
+	}
+
+	if err := gateways.Close(); err != nil {
+		log.Err(err).Msg("gateways.Close()")
+	}
+
 	if err := ipfsNode.Close(); err != nil {
 		log.Err(err).Msgf("ipfsNode.Close()")
 	}
@@ -164,11 +242,11 @@ func main() {
 	close(signals)
 }
 
-func startIndexer(ctx context.Context, cfg config.Config, indexerConfig config.Indexer, network string, prom *prometheus.Prometheus, ipfsNode *ipfs.Node, views []string, customConfigs []hasura.Request, hasuraInit *sync.Once) (startResult, error) {
+func startIndexer(ctx context.Context, cfg config.Config, indexerConfig config.Indexer, network string, prom *prometheus.Prometheus, ipfsNode *ipfs.Node, gateways *ipfs.Scores, networks *tezoskeys.Networks, views []string, customConfigs []hasura.Request, hasuraInit *sync.Once) (startResult, error) {
 	var result startResult
 	indexerCtx, cancel := context.WithCancel(ctx)
 
-	indexer, err := NewIndexer(indexerCtx, network, &indexerConfig, cfg.Database, indexerConfig.Filters, cfg.Metadata.Settings, prom, ipfsNode)
+	indexer, err := NewIndexer(indexerCtx, network, &indexerConfig, cfg.Database, indexerConfig.Filters, cfg.Metadata.Settings, prom, ipfsNode, gateways, networks)
 	if err != nil {
 		cancel()
 		return result, err
@@ -181,12 +259,18 @@ func startIndexer(ctx context.Context, cfg config.Config, indexerConfig config.I
 	}
 
 	hasuraInit.Do(func() {
+		if isElastic(cfg.Database) {
+			return
+		}
 		if err := hasura.Create(ctx, hasura.GenerateArgs{
 			Config:               cfg.Hasura,
 			DatabaseConfig:       cfg.Database,
 			Views:                views,
 			CustomConfigurations: customConfigs,
-			Models:               []any{new(models.TokenMetadata), new(models.ContractMetadata)},
+			Models: []any{
+				new(models.TokenMetadata), new(models.ContractMetadata),
+				new(models.TokenMetadataHistory), new(models.ContractMetadataHistory),
+			},
 		}); err != nil {
 			log.Err(err).Msg("hasura.Create")
 		}
@@ -196,6 +280,23 @@ func startIndexer(ctx context.Context, cfg config.Config, indexerConfig config.I
 	return result, nil
 }
 
+// newStorage - creates storage of indexer by `database.kind`: Elasticsearch or SQL database
+func newStorage(ctx context.Context, database golibConfig.Database) (*models.Storage, error) {
+	if isElastic(database) {
+		return elastic.NewStorage(ctx, database)
+	}
+
+	db, err := models.NewDatabase(ctx, database)
+	if err != nil {
+		return nil, err
+	}
+	return db.Storage(), nil
+}
+
+func isElastic(database golibConfig.Database) bool {
+	return database.Kind == golibConfig.DBKindElasticSearch
+}
+
 func createViews(ctx context.Context, database golibConfig.Database) ([]string, error) {
 	files, err := os.ReadDir("views")
 	if err != nil {
@@ -0,0 +1,358 @@
+package models
+
+import (
+	"context"
+	stdJSON "encoding/json"
+	"fmt"
+
+	"github.com/dipdup-net/go-lib/database"
+	"github.com/go-pg/pg/v10"
+	"github.com/go-pg/pg/v10/orm"
+	"github.com/shopspring/decimal"
+)
+
+// ChangeKind -
+type ChangeKind string
+
+// change kinds
+const (
+	ChangeKindContract ChangeKind = "contract"
+	ChangeKindToken    ChangeKind = "token"
+	ChangeKindTezosKey ChangeKind = "tezos_key"
+)
+
+// Change - state of the row before it was touched at `Level`. Empty `Previous` means the row was created at the level.
+type Change struct {
+	//nolint
+	tableName struct{} `pg:"changes"`
+
+	ID       uint64          `pg:",notnull"`
+	Network  string          `pg:",notnull"`
+	Level    uint64          `pg:",use_zero,notnull"`
+	Kind     ChangeKind      `pg:",notnull"`
+	Contract string          `pg:",use_zero"`
+	TokenID  decimal.Decimal `pg:",type:numeric,use_zero"`
+	Key      string          `pg:",use_zero"`
+	Previous JSONB           `pg:",type:json"`
+}
+
+// TableName -
+func (Change) TableName() string {
+	return "changes"
+}
+
+// Changes -
+type Changes struct {
+	db *database.PgGo
+}
+
+// NewChanges -
+func NewChanges(db *database.PgGo) *Changes {
+	return &Changes{db}
+}
+
+// TrackContracts - saves current state of contract metadata which will be overwritten at `level`
+func (changes *Changes) TrackContracts(ctx context.Context, network string, level uint64, metadata []*ContractMetadata) error {
+	if changes == nil || len(metadata) == 0 {
+		return nil
+	}
+
+	addresses := make([]string, 0, len(metadata))
+	has := make(map[string]struct{})
+	for i := range metadata {
+		if _, ok := has[metadata[i].Contract]; !ok {
+			has[metadata[i].Contract] = struct{}{}
+			addresses = append(addresses, metadata[i].Contract)
+		}
+	}
+
+	var existing []ContractMetadata
+	if err := conn(ctx, changes.db).ModelContext(ctx, &existing).
+		Where("network = ?", network).
+		WhereIn("contract IN (?)", addresses).
+		Select(); err != nil {
+		return err
+	}
+
+	previous := make(map[string]ContractMetadata, len(existing))
+	for i := range existing {
+		previous[existing[i].Contract] = existing[i]
+	}
+
+	items := make([]Change, 0, len(addresses))
+	for _, address := range addresses {
+		change := Change{
+			Network:  network,
+			Level:    level,
+			Kind:     ChangeKindContract,
+			Contract: address,
+		}
+		if cm, ok := previous[address]; ok {
+			data, err := stdJSON.Marshal(cm)
+			if err != nil {
+				return err
+			}
+			change.Previous = data
+		}
+		items = append(items, change)
+	}
+
+	_, err := conn(ctx, changes.db).ModelContext(ctx, &items).Insert()
+	return err
+}
+
+// TrackTokens - saves current state of token metadata which will be overwritten at `level`
+func (changes *Changes) TrackTokens(ctx context.Context, network string, level uint64, metadata []*TokenMetadata) error {
+	if changes == nil || len(metadata) == 0 {
+		return nil
+	}
+
+	tokens := make([]*TokenMetadata, 0, len(metadata))
+	has := make(map[string]struct{})
+	for i := range metadata {
+		id := tokenKey(metadata[i].Contract, metadata[i].TokenID)
+		if _, ok := has[id]; !ok {
+			has[id] = struct{}{}
+			tokens = append(tokens, metadata[i])
+		}
+	}
+
+	var existing []TokenMetadata
+	if err := conn(ctx, changes.db).ModelContext(ctx, &existing).
+		Where("network = ?", network).
+		WhereGroup(func(q *orm.Query) (*orm.Query, error) {
+			for i := range tokens {
+				contract, tokenID := tokens[i].Contract, tokens[i].TokenID
+				q.WhereOrGroup(func(q *orm.Query) (*orm.Query, error) {
+					return q.Where("contract = ?", contract).Where("token_id = ?", tokenID), nil
+				})
+			}
+			return q, nil
+		}).
+		Select(); err != nil {
+		return err
+	}
+
+	previous := make(map[string]TokenMetadata, len(existing))
+	for i := range existing {
+		previous[tokenKey(existing[i].Contract, existing[i].TokenID)] = existing[i]
+	}
+
+	items := make([]Change, 0, len(tokens))
+	for i := range tokens {
+		change := Change{
+			Network:  network,
+			Level:    level,
+			Kind:     ChangeKindToken,
+			Contract: tokens[i].Contract,
+			TokenID:  tokens[i].TokenID,
+		}
+		if tm, ok := previous[tokenKey(tokens[i].Contract, tokens[i].TokenID)]; ok {
+			data, err := stdJSON.Marshal(tm)
+			if err != nil {
+				return err
+			}
+			change.Previous = data
+		}
+		items = append(items, change)
+	}
+
+	_, err := conn(ctx, changes.db).ModelContext(ctx, &items).Insert()
+	return err
+}
+
+// TrackTezosKey - saves current state of tezos key which will be overwritten at `level`
+func (changes *Changes) TrackTezosKey(level uint64, key TezosKey) error {
+	if changes == nil {
+		return nil
+	}
+	change := Change{
+		Network:  key.Network,
+		Level:    level,
+		Kind:     ChangeKindTezosKey,
+		Contract: key.Address,
+		Key:      key.Key,
+	}
+
+	var existing TezosKey
+	err := changes.db.DB().Model(&existing).
+		Where("network = ?", key.Network).
+		Where("address = ?", key.Address).
+		Where("key = ?", key.Key).
+		First()
+	switch {
+	case err == nil:
+		data, err := stdJSON.Marshal(existing)
+		if err != nil {
+			return err
+		}
+		change.Previous = data
+	case err != pg.ErrNoRows:
+		return err
+	}
+
+	_, err = changes.db.DB().Model(&change).Insert()
+	return err
+}
+
+// Reverted - metadata written by rollback. Metadata created above rollback level gets `removed` status.
+type Reverted struct {
+	Changes   int
+	Contracts []*ContractMetadata
+	Tokens    []*TokenMetadata
+}
+
+func (r *Reverted) addContract(cm *ContractMetadata) {
+	for i := range r.Contracts {
+		if r.Contracts[i].Contract == cm.Contract {
+			r.Contracts[i] = cm
+			return
+		}
+	}
+	r.Contracts = append(r.Contracts, cm)
+}
+
+func (r *Reverted) addToken(tm *TokenMetadata) {
+	for i := range r.Tokens {
+		if r.Tokens[i].Contract == tm.Contract && r.Tokens[i].TokenID.Equal(tm.TokenID) {
+			r.Tokens[i] = tm
+			return
+		}
+	}
+	r.Tokens = append(r.Tokens, tm)
+}
+
+// Rollback - reverts all changes of `network` above `level` and returns reverted metadata. It joins transaction of context if it has one.
+func (changes *Changes) Rollback(ctx context.Context, network string, level uint64) (Reverted, error) {
+	var reverted Reverted
+	if changes == nil {
+		return reverted, nil
+	}
+	err := runInTransaction(ctx, changes.db, func(ctx context.Context) error {
+		for _, sequence := range []string{contractUpdateIDSequence, tokenUpdateIDSequence} {
+			if err := holdUpdateIDs(ctx, changes.db, sequence); err != nil {
+				return err
+			}
+		}
+		tx := conn(ctx, changes.db)
+
+		var items []Change
+		if err := tx.ModelContext(ctx, &items).
+			Where("network = ?", network).
+			Where("level > ?", level).
+			Order("id desc").
+			Select(); err != nil {
+			return err
+		}
+
+		// changes are reverted from the latest one, so the earliest state of row is returned
+		for i := range items {
+			if err := revert(ctx, tx, items[i], &reverted); err != nil {
+				return err
+			}
+		}
+
+		if _, err := tx.ModelContext(ctx, (*Change)(nil)).
+			Where("network = ?", network).
+			Where("level > ?", level).
+			Delete(); err != nil {
+			return err
+		}
+
+		reverted.Changes = len(items)
+		return nil
+	})
+	return reverted, err
+}
+
+// Prune - removes changes of `network` below `level` which can't be reverted anymore
+func (changes *Changes) Prune(network string, level uint64) error {
+	if changes == nil {
+		return nil
+	}
+	_, err := changes.db.DB().Model((*Change)(nil)).
+		Where("network = ?", network).
+		Where("level < ?", level).
+		Delete()
+	return err
+}
+
+func revert(ctx context.Context, tx orm.DB, change Change, reverted *Reverted) error {
+	switch change.Kind {
+	case ChangeKindContract:
+		ctx, err := takeUpdateIDs(ctx, tx, contractUpdateIDSequence, 1)
+		if err != nil {
+			return err
+		}
+		// metadata created above the level is kept with removed status like removed key of big map, so readers by update id receive the removal
+		cm := ContractMetadata{
+			Network:  change.Network,
+			Contract: change.Contract,
+			Status:   StatusRemoved,
+		}
+		if !change.Previous.IsNull() {
+			if err := stdJSON.Unmarshal(change.Previous, &cm); err != nil {
+				return err
+			}
+		}
+		_, err = tx.ModelContext(ctx, &cm).
+			OnConflict("(network, contract) DO UPDATE").
+			Set("metadata = excluded.metadata, link = excluded.link, updated_at = excluded.updated_at, update_id = excluded.update_id, status = excluded.status, retry_count = excluded.retry_count, error = excluded.error, sha256 = excluded.sha256, issues = excluded.issues, next_attempt_at = excluded.next_attempt_at, error_type = excluded.error_type").
+			Insert()
+		if err != nil {
+			return err
+		}
+		reverted.addContract(&cm)
+		return nil
+
+	case ChangeKindToken:
+		ctx, err := takeUpdateIDs(ctx, tx, tokenUpdateIDSequence, 1)
+		if err != nil {
+			return err
+		}
+		tm := TokenMetadata{
+			Network:  change.Network,
+			Contract: change.Contract,
+			TokenID:  change.TokenID,
+			Status:   StatusRemoved,
+		}
+		if !change.Previous.IsNull() {
+			if err := stdJSON.Unmarshal(change.Previous, &tm); err != nil {
+				return err
+			}
+		}
+		_, err = tx.ModelContext(ctx, &tm).
+			OnConflict("(network, contract, token_id) DO UPDATE").
+			Set("metadata = excluded.metadata, link = excluded.link, updated_at = excluded.updated_at, update_id = excluded.update_id, status = excluded.status, retry_count = excluded.retry_count, error = excluded.error, sha256 = excluded.sha256, image_processed = excluded.image_processed, source = excluded.source, issues = excluded.issues, on_chain_metadata = excluded.on_chain_metadata, off_chain_metadata = excluded.off_chain_metadata, next_attempt_at = excluded.next_attempt_at, error_type = excluded.error_type, " + excludedNormalizedTokenColumns).
+			Insert()
+		if err != nil {
+			return err
+		}
+		reverted.addToken(&tm)
+		return nil
+
+	case ChangeKindTezosKey:
+		if change.Previous.IsNull() {
+			_, err := tx.ModelContext(ctx, (*TezosKey)(nil)).
+				Where("network = ?", change.Network).
+				Where("address = ?", change.Contract).
+				Where("key = ?", change.Key).
+				Delete()
+			return err
+		}
+
+		var key TezosKey
+		if err := stdJSON.Unmarshal(change.Previous, &key); err != nil {
+			return err
+		}
+		_, err := tx.ModelContext(ctx, &key).OnConflict("(network, address, key) DO UPDATE").Set("value = excluded.value").Insert()
+		return err
+
+	default:
+		return fmt.Errorf("unknown change kind: %s", change.Kind)
+	}
+}
+
+func tokenKey(contract string, tokenID decimal.Decimal) string {
+	return fmt.Sprintf("%s_%s", contract, tokenID.String())
+}
@@ -0,0 +1,158 @@
+package models
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// saveTracked - writes metadata at `level` the way indexer does
+func saveTracked(t *testing.T, db *Database, level uint64, contract *ContractMetadata, token *TokenMetadata) {
+	err := db.Transactions.Run(context.Background(), func(ctx context.Context) error {
+		contracts := []*ContractMetadata{contract}
+		if err := db.Changes.TrackContracts(ctx, testJobsNetwork, level, contracts); err != nil {
+			return err
+		}
+		if err := db.Contracts.Save(ctx, contracts); err != nil {
+			return err
+		}
+		tokens := []*TokenMetadata{token}
+		if err := db.Changes.TrackTokens(ctx, testJobsNetwork, level, tokens); err != nil {
+			return err
+		}
+		return db.Tokens.Save(ctx, tokens)
+	})
+	require.NoError(t, err)
+}
+
+func testContract(metadata string) *ContractMetadata {
+	return &ContractMetadata{
+		Network:  testJobsNetwork,
+		Contract: "KT1Changes",
+		Status:   StatusApplied,
+		Metadata: JSONB(metadata),
+	}
+}
+
+func testToken(metadata string) *TokenMetadata {
+	return &TokenMetadata{
+		Network:  testJobsNetwork,
+		Contract: "KT1Changes",
+		TokenID:  decimal.NewFromInt(1),
+		Status:   StatusApplied,
+		Metadata: JSONB(metadata),
+	}
+}
+
+func getTracked(t *testing.T, db *Database) (*ContractMetadata, *TokenMetadata) {
+	var contracts []ContractMetadata
+	require.NoError(t, db.DB().Model(&contracts).Where("network = ?", testJobsNetwork).Select())
+	var tokens []TokenMetadata
+	require.NoError(t, db.DB().Model(&tokens).Where("network = ?", testJobsNetwork).Select())
+
+	var (
+		contract *ContractMetadata
+		token    *TokenMetadata
+	)
+	if len(contracts) > 0 {
+		contract = &contracts[0]
+	}
+	if len(tokens) > 0 {
+		token = &tokens[0]
+	}
+	return contract, token
+}
+
+func countChanges(t *testing.T, db *Database) int {
+	count, err := db.DB().Model((*Change)(nil)).Where("network = ?", testJobsNetwork).Count()
+	require.NoError(t, err)
+	return count
+}
+
+func TestIntegration_Changes_Rollback(t *testing.T) {
+	db := newTestDatabase(t)
+	ctx := context.Background()
+
+	saveTracked(t, db, 10, testContract(`{"name":"v1"}`), testToken(`{"name":"t1"}`))
+	saveTracked(t, db, 11, testContract(`{"name":"v2"}`), testToken(`{"name":"t2"}`))
+	require.Equal(t, 4, countChanges(t, db))
+
+	// update of level 11 is reverted to the state of level 10
+	reverted, err := db.Changes.Rollback(ctx, testJobsNetwork, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 2, reverted.Changes)
+	require.Len(t, reverted.Contracts, 1)
+	require.Len(t, reverted.Tokens, 1)
+	assert.JSONEq(t, `{"name":"v1"}`, string(reverted.Contracts[0].Metadata))
+
+	contract, token := getTracked(t, db)
+	require.NotNil(t, contract)
+	require.NotNil(t, token)
+	assert.JSONEq(t, `{"name":"v1"}`, string(contract.Metadata))
+	assert.JSONEq(t, `{"name":"t1"}`, string(token.Metadata))
+	assert.Equal(t, reverted.Contracts[0].UpdateID, contract.UpdateID, "reverted metadata gets new update id")
+	assert.Equal(t, 2, countChanges(t, db))
+
+	// insert of level 10 is reverted by removal
+	reverted, err = db.Changes.Rollback(ctx, testJobsNetwork, 9)
+	require.NoError(t, err)
+	assert.Equal(t, 2, reverted.Changes)
+	require.Len(t, reverted.Tokens, 1)
+	assert.Equal(t, StatusRemoved, reverted.Tokens[0].Status)
+	assert.NotZero(t, reverted.Tokens[0].UpdateID)
+
+	contract, token = getTracked(t, db)
+	require.NotNil(t, contract)
+	require.NotNil(t, token)
+	assert.Equal(t, StatusRemoved, contract.Status)
+	assert.Equal(t, StatusRemoved, token.Status)
+	assert.Equal(t, reverted.Tokens[0].UpdateID, token.UpdateID)
+	assert.Zero(t, countChanges(t, db))
+}
+
+func TestIntegration_Changes_Prune(t *testing.T) {
+	db := newTestDatabase(t)
+	ctx := context.Background()
+
+	saveTracked(t, db, 5, testContract(`{"name":"v1"}`), testToken(`{"name":"t1"}`))
+	saveTracked(t, db, 20, testContract(`{"name":"v2"}`), testToken(`{"name":"t2"}`))
+
+	require.NoError(t, db.Changes.Prune(testJobsNetwork, 10))
+	assert.Equal(t, 2, countChanges(t, db))
+
+	// pruned changes aren't reverted: rows created below pruned level stay
+	reverted, err := db.Changes.Rollback(ctx, testJobsNetwork, 0)
+	require.NoError(t, err)
+	assert.Equal(t, 2, reverted.Changes)
+
+	contract, token := getTracked(t, db)
+	require.NotNil(t, contract)
+	require.NotNil(t, token)
+	assert.JSONEq(t, `{"name":"v1"}`, string(contract.Metadata))
+	assert.JSONEq(t, `{"name":"t1"}`, string(token.Metadata))
+}
+
+func TestIntegration_Transactions_Run(t *testing.T) {
+	db := newTestDatabase(t)
+
+	errFailed := errors.New("failed")
+	err := db.Transactions.Run(context.Background(), func(ctx context.Context) error {
+		contracts := []*ContractMetadata{testContract(`{"name":"v1"}`)}
+		if err := db.Changes.TrackContracts(ctx, testJobsNetwork, 1, contracts); err != nil {
+			return err
+		}
+		if err := db.Contracts.Save(ctx, contracts); err != nil {
+			return err
+		}
+		return errFailed
+	})
+	require.ErrorIs(t, err, errFailed)
+
+	contract, _ := getTracked(t, db)
+	assert.Nil(t, contract, "metadata isn't saved if transaction fails")
+	assert.Zero(t, countChanges(t, db), "changes aren't tracked if transaction fails")
+}
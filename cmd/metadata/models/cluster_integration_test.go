@@ -0,0 +1,53 @@
+package models
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIntegration_Leaders(t *testing.T) {
+	first := newTestDatabase(t)
+	second := newTestDatabase(t)
+	ctx := context.Background()
+
+	leader, err := first.Leaders.TryAcquire(ctx, testJobsNetwork)
+	require.NoError(t, err)
+	require.NotNil(t, leader)
+
+	held, err := leader.Held(ctx)
+	require.NoError(t, err)
+	assert.True(t, held)
+
+	follower, err := second.Leaders.TryAcquire(ctx, testJobsNetwork)
+	require.NoError(t, err)
+	assert.Nil(t, follower, "lock is held by another instance")
+
+	require.NoError(t, leader.Release(ctx))
+
+	follower, err = second.Leaders.TryAcquire(ctx, testJobsNetwork)
+	require.NoError(t, err)
+	require.NotNil(t, follower)
+	require.NoError(t, follower.Release(ctx))
+}
+
+func TestIntegration_UpdateIDs(t *testing.T) {
+	first := newTestDatabase(t)
+	second := newTestDatabase(t)
+
+	// instances have own counters, ids are taken from sequence
+	saveTestTokens(t, first, "KT1first", 3)
+	saveTestTokens(t, second, "KT1second", 3)
+
+	var tokens []TokenMetadata
+	require.NoError(t, first.DB().Model(&tokens).Where("network = ?", testJobsNetwork).Select())
+	require.Len(t, tokens, 6)
+
+	ids := make(map[int64]struct{})
+	for i := range tokens {
+		ids[tokens[i].UpdateID] = struct{}{}
+	}
+	assert.Len(t, ids, 6)
+}
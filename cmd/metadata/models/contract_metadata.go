@@ -7,9 +7,10 @@ import (
 
 	"github.com/dipdup-net/go-lib/database"
 	"github.com/dipdup-net/metadata/cmd/metadata/helpers"
+	pg "github.com/go-pg/pg/v10"
 )
 
-// ContractUpdateID - incremental counter
+// ContractUpdateID - incremental counter of storages without sequences, see `nextUpdateID`
 var ContractUpdateID = helpers.NewCounter(0)
 
 // ContractMetadata -
@@ -28,6 +29,12 @@ type ContractMetadata struct {
 	RetryCount int8   `json:"retry_count" pg:",use_zero"`
 	Metadata   JSONB  `json:"metadata,omitempty" pg:",type:json,use_zero"`
 	Error      string `json:"error,omitempty"`
+	Sha256     string `json:"sha256,omitempty"`
+	Issues     JSONB  `json:"issues,omitempty" pg:",type:jsonb"`
+
+	// retry schedule of new metadata and type of the last error, see `resolver.Retrier`
+	NextAttemptAt int64  `json:"next_attempt_at" pg:",use_zero"`
+	ErrorType     string `json:"error_type,omitempty"`
 }
 
 // TableName -
@@ -49,14 +56,14 @@ func (cm *ContractMetadata) GetID() uint64 {
 func (cm *ContractMetadata) BeforeInsert(ctx context.Context) (context.Context, error) {
 	cm.UpdatedAt = time.Now().Unix()
 	cm.CreatedAt = cm.UpdatedAt
-	cm.UpdateID = ContractUpdateID.Increment()
+	cm.UpdateID = nextUpdateID(ctx, ContractUpdateID)
 	return ctx, nil
 }
 
 // BeforeUpdate -
 func (cm *ContractMetadata) BeforeUpdate(ctx context.Context) (context.Context, error) {
 	cm.UpdatedAt = time.Now().Unix()
-	cm.UpdateID = ContractUpdateID.Increment()
+	cm.UpdateID = nextUpdateID(ctx, ContractUpdateID)
 	return ctx, nil
 }
 
@@ -72,52 +79,64 @@ func NewContracts(db *database.PgGo) *Contracts {
 	return &Contracts{db: db}
 }
 
-// Get -
-func (contracts *Contracts) Get(network string, status Status, limit, offset, retryCount, delay int) (all []*ContractMetadata, err error) {
-	query := contracts.db.DB().Model(&all).Where("status = ?", status).Where("network = ?", network).Where("created_at < (extract(epoch from current_timestamp) - ? * retry_count)", delay)
+// Get - returns metadata with `status` which next attempt is due
+func (contracts *Contracts) Get(network string, status Status, limit, offset int) (all []*ContractMetadata, err error) {
+	query := contracts.db.DB().Model(&all).Where("status = ?", status).Where("network = ?", network).Where("next_attempt_at <= ?", time.Now().Unix())
 	if limit > 0 {
 		query.Limit(limit)
 	}
 	if offset > 0 {
 		query.Offset(offset)
 	}
-	if retryCount > 0 {
-		query.Where("retry_count < ?", retryCount)
-	}
 	err = query.OrderExpr("retry_count desc, updated_at desc").Select()
 	return
 }
 
-// Retry -
-func (contracts *Contracts) Retry(network string, retryCount int, window time.Duration) error {
-	_, err := contracts.db.DB().Model((*ContractMetadata)(nil)).
-		Set("retry_count = 0").
-		Set("status = ?", StatusNew).
-		Where("status = ?", StatusFailed).
-		Where("network = ?", network).
-		Where("retry_count >= ?", retryCount).
-		Where("error LIKE '%%context deadline exceeded%%'").
-		Where("link LIKE 'ipfs://%%'").
-		Where("created_at > (extract(epoch from current_timestamp) - ?)", window).
-		Update()
-	return err
+// Retry - returns failed metadata which was created within `window` seconds and failed with one of `errorTypes` to new status. Retried metadata gets new update id, so it's synced to job queue and sent to clients again.
+func (contracts *Contracts) Retry(network string, errorTypes []string, window time.Duration) error {
+	if len(errorTypes) == 0 {
+		return nil
+	}
+	return runInTransaction(context.Background(), contracts.db, func(ctx context.Context) error {
+		if err := holdUpdateIDs(ctx, contracts.db, contractUpdateIDSequence); err != nil {
+			return err
+		}
+		_, err := conn(ctx, contracts.db).ModelContext(ctx, (*ContractMetadata)(nil)).
+			Set("retry_count = 0").
+			Set("next_attempt_at = 0").
+			Set("status = ?", StatusNew).
+			Set("update_id = nextval(?)", contractUpdateIDSequence).
+			Where("status = ?", StatusFailed).
+			Where("network = ?", network).
+			Where("error_type IN (?)", pg.In(errorTypes)).
+			Where("created_at > (extract(epoch from current_timestamp) - ?)", window).
+			Update()
+		return err
+	})
 }
 
 // Update -
-func (contracts *Contracts) Update(metadata []*ContractMetadata) error {
+func (contracts *Contracts) Update(ctx context.Context, metadata []*ContractMetadata) error {
 	if len(metadata) == 0 {
 		return nil
 	}
 
-	contracts.mx.Lock()
-	defer contracts.mx.Unlock()
+	return runInTransaction(ctx, contracts.db, func(ctx context.Context) error {
+		ctx, err := reserveUpdateIDs(ctx, contracts.db, contractUpdateIDSequence, len(metadata))
+		if err != nil {
+			return err
+		}
+
+		contracts.mx.Lock()
+		defer contracts.mx.Unlock()
 
-	_, err := contracts.db.DB().Model(&metadata).Column("metadata", "update_id", "updated_at", "status", "retry_count", "error").WherePK().Update()
-	return err
+		_, err = conn(ctx, contracts.db).ModelContext(ctx, &metadata).Column("metadata", "update_id", "updated_at", "status", "retry_count", "error", "sha256", "issues", "next_attempt_at", "error_type").WherePK().Update()
+		return err
+	})
 }
 
 // Save -
-func (contracts *Contracts) Save(metadata []*ContractMetadata) error {
+func (contracts *Contracts) Save(ctx context.Context, metadata []*ContractMetadata) error {
 	if len(metadata) == 0 {
 		return nil
 	}
@@ -134,14 +153,21 @@ func (contracts *Contracts) Save(metadata []*ContractMetadata) error {
 		return nil
 	}
 
-	contracts.mx.Lock()
-	defer contracts.mx.Unlock()
+	return runInTransaction(ctx, contracts.db, func(ctx context.Context) error {
+		ctx, err := reserveUpdateIDs(ctx, contracts.db, contractUpdateIDSequence, len(savings))
+		if err != nil {
+			return err
+		}
+
+		contracts.mx.Lock()
+		defer contracts.mx.Unlock()
 
-	_, err := contracts.db.DB().Model(&savings).
-		OnConflict("(network, contract) DO UPDATE").
-		Set("metadata = excluded.metadata, link = excluded.link, updated_at = excluded.updated_at, update_id = excluded.update_id, status = excluded.status, retry_count = excluded.retry_count").
-		Insert()
-	return err
+		_, err = conn(ctx, contracts.db).ModelContext(ctx, &savings).
+			OnConflict("(network, contract) DO UPDATE").
+			Set("metadata = excluded.metadata, link = excluded.link, updated_at = excluded.updated_at, update_id = excluded.update_id, status = excluded.status, retry_count = excluded.retry_count, sha256 = excluded.sha256, issues = excluded.issues, next_attempt_at = excluded.next_attempt_at, error_type = excluded.error_type").
+			Insert()
+		return err
+	})
 }
 
 // LastUpdateID -
@@ -29,6 +29,15 @@ type Database struct {
 	Tokens    ModelRepository[*TokenMetadata]
 	Contracts ModelRepository[*ContractMetadata]
 	TezosKeys *TezosKeys
+	Changes   *Changes
+	Webhooks  *Webhooks
+	History   *History
+
+	Transactions *Transactions
+
+	TokenJobs    *JobQueue[*TokenMetadata]
+	ContractJobs *JobQueue[*ContractMetadata]
+	Leaders      *Leaders
 }
 
 // NewDatabase -
@@ -41,7 +50,8 @@ func NewDatabase(ctx context.Context, cfg config.Database) (*Database, error) {
 	database.Wait(ctx, db, 5*time.Second)
 
 	for _, data := range []any{
-		&database.State{}, &ContractMetadata{}, &TokenMetadata{}, &TezosKey{},
+		&database.State{}, &ContractMetadata{}, &TokenMetadata{}, &TezosKey{}, &Change{}, &WebhookDelivery{},
+		&ContractMetadataHistory{}, &TokenMetadataHistory{}, &Job{}, &UpdateIDHold{},
 	} {
 		if err := db.DB().WithContext(ctx).Model(data).CreateTable(&orm.CreateTableOptions{
 			IfNotExists: true,
@@ -52,6 +62,12 @@ func NewDatabase(ctx context.Context, cfg config.Database) (*Database, error) {
 			return nil, err
 		}
 	}
+	if err := createUpdateIDSequences(ctx, db.DB()); err != nil {
+		if err := db.Close(); err != nil {
+			return nil, err
+		}
+		return nil, err
+	}
 	db.DB().AddQueryHook(&dbLogger{})
 
 	return &Database{
@@ -59,6 +75,15 @@ func NewDatabase(ctx context.Context, cfg config.Database) (*Database, error) {
 		Tokens:    NewTokens(db),
 		Contracts: NewContracts(db),
 		TezosKeys: NewTezosKeys(db),
+		Changes:   NewChanges(db),
+		Webhooks:  NewWebhooks(db),
+		History:   NewHistory(db),
+
+		Transactions: NewTransactions(db),
+
+		TokenJobs:    NewTokenJobs(db),
+		ContractJobs: NewContractJobs(db),
+		Leaders:      NewLeaders(db),
 	}, nil
 }
 
@@ -89,6 +114,12 @@ func (db *Database) Close() error {
 	return db.PgGo.Close()
 }
 
+// UpdateState - state is written in transaction of context if it has one, e.g. on rollback
+func (db *Database) UpdateState(ctx context.Context, state *database.State) error {
+	_, err := conn(ctx, db.PgGo).ModelContext(ctx, state).Where("index_name = ?", state.IndexName).Update()
+	return err
+}
+
 // CreateIndices -
 func (db *Database) CreateIndices() error {
 	if _, err := db.DB().Exec(`
@@ -131,11 +162,66 @@ func (db *Database) CreateIndices() error {
 	`); err != nil {
 		return err
 	}
+	if _, err := db.DB().Exec(`
+		CREATE INDEX CONCURRENTLY IF NOT EXISTS token_metadata_name_idx ON token_metadata (name)
+	`); err != nil {
+		return err
+	}
+	if _, err := db.DB().Exec(`
+		CREATE INDEX CONCURRENTLY IF NOT EXISTS token_metadata_symbol_idx ON token_metadata (symbol)
+	`); err != nil {
+		return err
+	}
+	if _, err := db.DB().Exec(`
+		CREATE INDEX CONCURRENTLY IF NOT EXISTS token_metadata_creators_idx ON token_metadata USING GIN (creators)
+	`); err != nil {
+		return err
+	}
+	if _, err := db.DB().Exec(`
+		CREATE INDEX CONCURRENTLY IF NOT EXISTS token_metadata_tags_idx ON token_metadata USING GIN (tags)
+	`); err != nil {
+		return err
+	}
+	if _, err := db.DB().Exec(`
+		CREATE INDEX CONCURRENTLY IF NOT EXISTS token_metadata_search_idx ON token_metadata USING GIN (search_vector)
+	`); err != nil {
+		return err
+	}
+	if _, err := db.DB().Exec(`
+		CREATE INDEX CONCURRENTLY IF NOT EXISTS contract_metadata_search_idx ON contract_metadata USING GIN (search_vector)
+	`); err != nil {
+		return err
+	}
 	if _, err := db.DB().Exec(`
 		CREATE INDEX CONCURRENTLY IF NOT EXISTS tezos_key_idx ON tezos_keys (network, address, key)
 	`); err != nil {
 		return err
 	}
+	if _, err := db.DB().Exec(`
+		CREATE INDEX CONCURRENTLY IF NOT EXISTS changes_network_level_idx ON changes (network, level)
+	`); err != nil {
+		return err
+	}
+	if _, err := db.DB().Exec(`
+		CREATE INDEX CONCURRENTLY IF NOT EXISTS contract_metadata_history_idx ON contract_metadata_history (network, contract, level)
+	`); err != nil {
+		return err
+	}
+	if _, err := db.DB().Exec(`
+		CREATE INDEX CONCURRENTLY IF NOT EXISTS token_metadata_history_idx ON token_metadata_history (network, contract, token_id, level)
+	`); err != nil {
+		return err
+	}
+	if _, err := db.DB().Exec(`
+		CREATE INDEX CONCURRENTLY IF NOT EXISTS webhook_outbox_pending_idx ON webhook_outbox (network, next_attempt_at) WHERE status = 'pending'
+	`); err != nil {
+		return err
+	}
+	if _, err := db.DB().Exec(`
+		CREATE INDEX CONCURRENTLY IF NOT EXISTS metadata_jobs_lease_idx ON metadata_jobs (kind, network, priority DESC, id) INCLUDE (available_at, contract)
+	`); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -0,0 +1,189 @@
+package models
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"github.com/dipdup-net/go-lib/database"
+	"github.com/shopspring/decimal"
+)
+
+// ContractMetadataHistory - version of contract metadata. Version is created by every big map update and it's filled by resolved document later.
+type ContractMetadataHistory struct {
+	//nolint
+	tableName struct{} `pg:"contract_metadata_history"`
+
+	ID             int64     `json:"-"`
+	Network        string    `json:"network" pg:",notnull"`
+	Contract       string    `json:"contract" pg:",notnull"`
+	Level          uint64    `json:"level" pg:",use_zero"`
+	Timestamp      time.Time `json:"timestamp"`
+	BigMapUpdateID uint64    `json:"big_map_update_id" pg:",use_zero"`
+	Action         string    `json:"action"`
+	Link           string    `json:"link"`
+	Status         Status    `json:"status"`
+	Metadata       JSONB     `json:"metadata,omitempty" pg:",type:json"`
+	ContentHash    string    `json:"content_hash,omitempty"`
+	ResolvedAt     int64     `json:"resolved_at" pg:",use_zero"`
+}
+
+// TableName -
+func (ContractMetadataHistory) TableName() string {
+	return "contract_metadata_history"
+}
+
+// TokenMetadataHistory - version of token metadata. Version is created by every big map update or off-chain view call and it's filled by resolved document later.
+type TokenMetadataHistory struct {
+	//nolint
+	tableName struct{} `pg:"token_metadata_history"`
+
+	ID             int64           `json:"-"`
+	Network        string          `json:"network" pg:",notnull"`
+	Contract       string          `json:"contract" pg:",notnull"`
+	TokenID        decimal.Decimal `json:"token_id" pg:",type:numeric,use_zero"`
+	Level          uint64          `json:"level" pg:",use_zero"`
+	Timestamp      time.Time       `json:"timestamp"`
+	BigMapUpdateID uint64          `json:"big_map_update_id" pg:",use_zero"`
+	Action         string          `json:"action"`
+	Link           string          `json:"link"`
+	Status         Status          `json:"status"`
+	Metadata       JSONB           `json:"metadata,omitempty" pg:",type:json"`
+	ContentHash    string          `json:"content_hash,omitempty"`
+	ResolvedAt     int64           `json:"resolved_at" pg:",use_zero"`
+	Source         string          `json:"source"`
+}
+
+// TableName -
+func (TokenMetadataHistory) TableName() string {
+	return "token_metadata_history"
+}
+
+// ContentHash - hex-encoded sha256 of metadata document
+func ContentHash(metadata JSONB) string {
+	if metadata.IsNull() {
+		return ""
+	}
+	hash := sha256.Sum256(metadata)
+	return hex.EncodeToString(hash[:])
+}
+
+// History -
+type History struct {
+	db *database.PgGo
+}
+
+// NewHistory -
+func NewHistory(db *database.PgGo) *History {
+	return &History{db: db}
+}
+
+// AddContracts - saves new versions of contract metadata
+func (history *History) AddContracts(ctx context.Context, versions []*ContractMetadataHistory) error {
+	if history == nil || len(versions) == 0 {
+		return nil
+	}
+	_, err := conn(ctx, history.db).ModelContext(ctx, &versions).Insert()
+	return err
+}
+
+// AddTokens - saves new versions of token metadata
+func (history *History) AddTokens(ctx context.Context, versions []*TokenMetadataHistory) error {
+	if history == nil || len(versions) == 0 {
+		return nil
+	}
+	_, err := conn(ctx, history.db).ModelContext(ctx, &versions).Insert()
+	return err
+}
+
+// ResolveContracts - fills the latest versions of contract metadata with resolved documents
+func (history *History) ResolveContracts(ctx context.Context, contracts []*ContractMetadata) error {
+	if history == nil {
+		return nil
+	}
+	return runInTransaction(ctx, history.db, func(ctx context.Context) error {
+		tx := conn(ctx, history.db)
+		resolvedAt := time.Now().Unix()
+		for i := range contracts {
+			if !isResolved(contracts[i].Status) {
+				continue
+			}
+
+			latest := tx.Model((*ContractMetadataHistory)(nil)).
+				ColumnExpr("max(id)").
+				Where("network = ?", contracts[i].Network).
+				Where("contract = ?", contracts[i].Contract).
+				Where("link = ?", contracts[i].Link)
+
+			if _, err := tx.Model((*ContractMetadataHistory)(nil)).
+				Set("status = ?", contracts[i].Status).
+				Set("metadata = ?", contracts[i].Metadata).
+				Set("content_hash = ?", ContentHash(contracts[i].Metadata)).
+				Set("resolved_at = ?", resolvedAt).
+				Where("id = (?)", latest).
+				Update(); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ResolveTokens - fills the latest versions of token metadata with resolved documents
+func (history *History) ResolveTokens(ctx context.Context, tokens []*TokenMetadata) error {
+	if history == nil {
+		return nil
+	}
+	return runInTransaction(ctx, history.db, func(ctx context.Context) error {
+		tx := conn(ctx, history.db)
+		resolvedAt := time.Now().Unix()
+		for i := range tokens {
+			if !isResolved(tokens[i].Status) {
+				continue
+			}
+
+			latest := tx.Model((*TokenMetadataHistory)(nil)).
+				ColumnExpr("max(id)").
+				Where("network = ?", tokens[i].Network).
+				Where("contract = ?", tokens[i].Contract).
+				Where("token_id = ?", tokens[i].TokenID).
+				Where("link = ?", tokens[i].Link)
+
+			if _, err := tx.Model((*TokenMetadataHistory)(nil)).
+				Set("status = ?", tokens[i].Status).
+				Set("metadata = ?", tokens[i].Metadata).
+				Set("content_hash = ?", ContentHash(tokens[i].Metadata)).
+				Set("resolved_at = ?", resolvedAt).
+				Where("id = (?)", latest).
+				Update(); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Rollback - removes versions created after level
+func (history *History) Rollback(ctx context.Context, network string, level uint64) error {
+	if history == nil {
+		return nil
+	}
+	return runInTransaction(ctx, history.db, func(ctx context.Context) error {
+		if _, err := conn(ctx, history.db).ModelContext(ctx, (*ContractMetadataHistory)(nil)).
+			Where("network = ?", network).
+			Where("level > ?", level).
+			Delete(); err != nil {
+			return err
+		}
+		_, err := conn(ctx, history.db).ModelContext(ctx, (*TokenMetadataHistory)(nil)).
+			Where("network = ?", network).
+			Where("level > ?", level).
+			Delete()
+		return err
+	})
+}
+
+func isResolved(status Status) bool {
+	return status == StatusApplied || status == StatusFailed
+}
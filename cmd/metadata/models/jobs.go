@@ -0,0 +1,270 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/dipdup-net/go-lib/database"
+	pg "github.com/go-pg/pg/v10"
+)
+
+// job priorities: fresh metadata is resolved before retries and backfill of old metadata
+const (
+	JobPriorityBackfill = iota
+	JobPriorityRetry
+	JobPriorityFresh
+)
+
+// Job - durable task of metadata resolving. Leased job is invisible for other workers till `available_at`, so jobs of crashed workers are leased again after timeout.
+type Job struct {
+	//nolint
+	tableName struct{} `pg:"metadata_jobs"`
+
+	ID          int64  `json:"-"`
+	Kind        string `json:"kind" pg:",unique:job,notnull"`
+	MetadataID  uint64 `json:"metadata_id" pg:",unique:job,use_zero"`
+	Network     string `json:"network" pg:",notnull"`
+	Contract    string `json:"contract" pg:",notnull"`
+	Priority    int    `json:"priority" pg:",use_zero"`
+	AvailableAt int64  `json:"available_at" pg:",use_zero"`
+	LeasedBy    string `json:"leased_by" pg:",use_zero"`
+	Attempts    int    `json:"attempts" pg:",use_zero"`
+	CreatedAt   int64  `json:"created_at" pg:",use_zero"`
+}
+
+// JobQueue - durable queue of metadata resolving in Postgres. Jobs are leased by `SELECT ... FOR UPDATE SKIP LOCKED`, so many replicas may share the queue.
+type JobQueue[T Model] struct {
+	db *database.PgGo
+
+	// kind - table of metadata
+	kind  string
+	owner string
+
+	leaseTimeout time.Duration
+	perContract  int
+
+	nextAttemptAt func(T) int64
+}
+
+// JobQueueOption -
+type JobQueueOption func(*jobQueueSettings)
+
+type jobQueueSettings struct {
+	owner        string
+	leaseTimeout time.Duration
+	perContract  int
+}
+
+// WithJobOwner - name of worker in leases. Default: hostname and process id.
+func WithJobOwner(owner string) JobQueueOption {
+	return func(s *jobQueueSettings) {
+		s.owner = owner
+	}
+}
+
+// WithLeaseTimeout - time after which leased job is available again if it isn't completed. Default: 5 minutes.
+func WithLeaseTimeout(timeout time.Duration) JobQueueOption {
+	return func(s *jobQueueSettings) {
+		if timeout > 0 {
+			s.leaseTimeout = timeout
+		}
+	}
+}
+
+// WithContractLimit - maximum count of leased jobs of one contract. Default: 10.
+func WithContractLimit(limit int) JobQueueOption {
+	return func(s *jobQueueSettings) {
+		if limit > 0 {
+			s.perContract = limit
+		}
+	}
+}
+
+func defaultJobOwner() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "metadata"
+	}
+	return fmt.Sprintf("%s:%d", hostname, os.Getpid())
+}
+
+// NewTokenJobs -
+func NewTokenJobs(db *database.PgGo, opts ...JobQueueOption) *JobQueue[*TokenMetadata] {
+	return newJobQueue(db, TokenMetadata{}.TableName(), func(tm *TokenMetadata) int64 {
+		return tm.NextAttemptAt
+	}, opts...)
+}
+
+// NewContractJobs -
+func NewContractJobs(db *database.PgGo, opts ...JobQueueOption) *JobQueue[*ContractMetadata] {
+	return newJobQueue(db, ContractMetadata{}.TableName(), func(cm *ContractMetadata) int64 {
+		return cm.NextAttemptAt
+	}, opts...)
+}
+
+func newJobQueue[T Model](db *database.PgGo, kind string, nextAttemptAt func(T) int64, opts ...JobQueueOption) *JobQueue[T] {
+	settings := jobQueueSettings{
+		leaseTimeout: 5 * time.Minute,
+		perContract:  10,
+	}
+	for i := range opts {
+		opts[i](&settings)
+	}
+	if settings.owner == "" {
+		settings.owner = defaultJobOwner()
+	}
+	return &JobQueue[T]{
+		db:            db,
+		kind:          kind,
+		owner:         settings.owner,
+		leaseTimeout:  settings.leaseTimeout,
+		perContract:   settings.perContract,
+		nextAttemptAt: nextAttemptAt,
+	}
+}
+
+// Owner - name of worker which leases jobs
+func (q *JobQueue[T]) Owner() string {
+	return q.owner
+}
+
+// Sync - enqueues new metadata of network which `update_id` is greater than `afterUpdateID`. Queued job of metadata which isn't leased is reset: it gets `priority`, `next_attempt_at` of metadata and zero attempts. Leased jobs are kept. Returns the greatest checked update id.
+func (q *JobQueue[T]) Sync(ctx context.Context, network string, afterUpdateID int64, priority int) (lastUpdateID int64, err error) {
+	// rows which are committed after the max is taken have greater update id, so they are synced next time
+	if _, err = q.db.DB().QueryOneContext(ctx, pg.Scan(&lastUpdateID),
+		fmt.Sprintf(`SELECT coalesce(max(update_id), 0) FROM %s WHERE network = ? AND %s`, q.kind, committedUpdateIDs(updateIDSequences[q.kind])), network,
+	); err != nil {
+		return afterUpdateID, err
+	}
+	if lastUpdateID <= afterUpdateID {
+		return afterUpdateID, nil
+	}
+
+	_, err = q.db.DB().ExecContext(ctx, fmt.Sprintf(`
+		INSERT INTO metadata_jobs (kind, metadata_id, network, contract, priority, available_at, leased_by, attempts, created_at)
+		SELECT ?0, id, network, contract, ?1, next_attempt_at, '', 0, ?2 FROM %s
+		WHERE network = ?3 AND status = ?4 AND update_id > ?5 AND update_id <= ?6
+		ON CONFLICT (kind, metadata_id) DO UPDATE
+		SET priority = excluded.priority, available_at = excluded.available_at, leased_by = '', attempts = 0
+		WHERE metadata_jobs.leased_by = '' OR metadata_jobs.available_at <= ?2
+	`, q.kind), q.kind, priority, time.Now().Unix(), network, StatusNew, afterUpdateID, lastUpdateID)
+	if err != nil {
+		return afterUpdateID, err
+	}
+	return lastUpdateID, nil
+}
+
+// Lease - takes up to `limit` available jobs of network by priority and returns their metadata. Jobs of one contract are interleaved with other contracts and a contract can't hold more than `perContract` leases at once.
+func (q *JobQueue[T]) Lease(ctx context.Context, network string, limit int) ([]T, error) {
+	if limit <= 0 {
+		return nil, nil
+	}
+
+	jobs, err := q.lease(ctx, network, limit, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if len(jobs) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]uint64, len(jobs))
+	for i := range jobs {
+		ids[i] = jobs[i].MetadataID
+	}
+
+	var metadata []T
+	if err := q.db.DB().ModelContext(ctx, &metadata).Where("id IN (?)", pg.In(ids)).Select(); err != nil {
+		return nil, err
+	}
+
+	// metadata may be resolved or removed while job waited
+	result := make([]T, 0, len(metadata))
+	stale := make([]uint64, 0)
+	for i := range metadata {
+		if metadata[i].GetStatus() == StatusNew {
+			result = append(result, metadata[i])
+		} else {
+			stale = append(stale, metadata[i].GetID())
+		}
+	}
+	if len(metadata) < len(ids) {
+		found := make(map[uint64]struct{}, len(metadata))
+		for i := range metadata {
+			found[metadata[i].GetID()] = struct{}{}
+		}
+		for _, id := range ids {
+			if _, ok := found[id]; !ok {
+				stale = append(stale, id)
+			}
+		}
+	}
+	if err := q.delete(ctx, stale); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// lease - active leases of contract are subtracted from its `perContract` share. Instances which lease at the same moment don't see leases of each other, so a contract may exceed the limit by their jobs till the leases expire.
+func (q *JobQueue[T]) lease(ctx context.Context, network string, limit int, now time.Time) (jobs []Job, err error) {
+	_, err = q.db.DB().QueryContext(ctx, &jobs, `
+		WITH leased AS (
+			SELECT contract, count(*) AS count FROM metadata_jobs
+			WHERE kind = ?0 AND network = ?1 AND leased_by != '' AND available_at > ?2
+			GROUP BY contract
+		), candidates AS (
+			SELECT id, contract, priority FROM metadata_jobs
+			WHERE kind = ?0 AND network = ?1 AND available_at <= ?2 AND contract NOT IN (SELECT contract FROM leased WHERE count >= ?3)
+			ORDER BY priority DESC, id
+			LIMIT ?4
+			FOR UPDATE SKIP LOCKED
+		), fair AS (
+			SELECT id FROM (
+				SELECT candidates.id, candidates.priority, coalesce(leased.count, 0) AS leased,
+					row_number() OVER (PARTITION BY candidates.contract ORDER BY candidates.priority DESC, candidates.id) AS rn
+				FROM candidates LEFT JOIN leased ON leased.contract = candidates.contract
+			) AS ranked
+			WHERE rn + leased <= ?3
+			ORDER BY priority DESC, rn, id
+			LIMIT ?5
+		)
+		UPDATE metadata_jobs AS job SET available_at = ?6, leased_by = ?7, attempts = job.attempts + 1
+		FROM fair WHERE job.id = fair.id
+		RETURNING job.*
+	`, q.kind, network, now.Unix(), q.perContract, limit*q.perContract, limit, now.Add(q.leaseTimeout).Unix(), q.owner)
+	return
+}
+
+// Complete - removes jobs of handled metadata. Metadata which is still new is retried at its `next_attempt_at`.
+func (q *JobQueue[T]) Complete(ctx context.Context, metadata []T) error {
+	done := make([]uint64, 0, len(metadata))
+	for i := range metadata {
+		if metadata[i].GetStatus() != StatusNew {
+			done = append(done, metadata[i].GetID())
+			continue
+		}
+		if _, err := conn(ctx, q.db).ModelContext(ctx, (*Job)(nil)).
+			Set("priority = ?", JobPriorityRetry).
+			Set("available_at = ?", q.nextAttemptAt(metadata[i])).
+			Set("leased_by = ''").
+			Where("kind = ?", q.kind).
+			Where("metadata_id = ?", metadata[i].GetID()).
+			Update(); err != nil {
+			return err
+		}
+	}
+	return q.delete(ctx, done)
+}
+
+func (q *JobQueue[T]) delete(ctx context.Context, ids []uint64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	_, err := conn(ctx, q.db).ModelContext(ctx, (*Job)(nil)).
+		Where("kind = ?", q.kind).
+		Where("metadata_id IN (?)", pg.In(ids)).
+		Delete()
+	return err
+}
@@ -0,0 +1,227 @@
+package models
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/dipdup-net/go-lib/config"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testJobsNetwork = "jobs_test"
+
+func getenv(name, fallback string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// newTestDatabase - connects to Postgres from `POSTGRES_*` environment variables. Rows of test network are removed before and after test.
+func newTestDatabase(t *testing.T) *Database {
+	if os.Getenv("INTEGRATION") == "" {
+		t.Skip("Skipping testing in CI environment")
+	}
+
+	port, err := strconv.Atoi(getenv("POSTGRES_PORT", "5432"))
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	db, err := NewDatabase(ctx, config.Database{
+		Kind:     config.DBKindPostgres,
+		Host:     getenv("POSTGRES_HOST", "127.0.0.1"),
+		Port:     port,
+		User:     getenv("POSTGRES_USER", "postgres"),
+		Password: getenv("POSTGRES_PASSWORD", "postgres"),
+		Database: getenv("POSTGRES_DB", "metadata_test"),
+	})
+	require.NoError(t, err)
+
+	clean := func() {
+		for _, model := range []any{(*Job)(nil), (*TokenMetadata)(nil), (*ContractMetadata)(nil), (*Change)(nil)} {
+			_, err := db.DB().Model(model).Where("network = ?", testJobsNetwork).Delete()
+			require.NoError(t, err)
+		}
+	}
+	clean()
+	t.Cleanup(func() {
+		clean()
+		require.NoError(t, db.Close())
+	})
+	return db
+}
+
+func saveTestTokens(t *testing.T, db *Database, contract string, count int) {
+	tokens := make([]*TokenMetadata, count)
+	for i := range tokens {
+		tokens[i] = &TokenMetadata{
+			Network:  testJobsNetwork,
+			Contract: contract,
+			TokenID:  decimal.NewFromInt(int64(i)),
+			Status:   StatusNew,
+		}
+	}
+	require.NoError(t, db.Tokens.Save(context.Background(), tokens))
+}
+
+func TestIntegration_JobQueue_leaseExpiry(t *testing.T) {
+	db := newTestDatabase(t)
+	ctx := context.Background()
+	saveTestTokens(t, db, "KT1", 1)
+
+	crashed := NewTokenJobs(db.PgGo, WithJobOwner("crashed"), WithLeaseTimeout(time.Minute))
+	alive := NewTokenJobs(db.PgGo, WithJobOwner("alive"), WithLeaseTimeout(time.Minute))
+
+	_, err := crashed.Sync(ctx, testJobsNetwork, 0, JobPriorityFresh)
+	require.NoError(t, err)
+
+	now := time.Now()
+	jobs, err := crashed.lease(ctx, testJobsNetwork, 10, now)
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+	assert.Equal(t, "crashed", jobs[0].LeasedBy)
+	assert.Equal(t, 1, jobs[0].Attempts)
+
+	// worker crashed without completion: job is invisible till lease expires
+	jobs, err = alive.lease(ctx, testJobsNetwork, 10, now.Add(30*time.Second))
+	require.NoError(t, err)
+	assert.Empty(t, jobs)
+
+	jobs, err = alive.lease(ctx, testJobsNetwork, 10, now.Add(2*time.Minute))
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+	assert.Equal(t, "alive", jobs[0].LeasedBy)
+	assert.Equal(t, 2, jobs[0].Attempts)
+}
+
+func TestIntegration_JobQueue_crashRecovery(t *testing.T) {
+	db := newTestDatabase(t)
+	ctx := context.Background()
+	saveTestTokens(t, db, "KT1", 3)
+
+	queue := NewTokenJobs(db.PgGo, WithLeaseTimeout(time.Second))
+	_, err := queue.Sync(ctx, testJobsNetwork, 0, JobPriorityFresh)
+	require.NoError(t, err)
+
+	leased, err := queue.Lease(ctx, testJobsNetwork, 10)
+	require.NoError(t, err)
+	require.Len(t, leased, 3)
+
+	// restarted process syncs the whole queue again: leased jobs aren't duplicated
+	restarted := NewTokenJobs(db.PgGo, WithLeaseTimeout(time.Second))
+	_, err = restarted.Sync(ctx, testJobsNetwork, 0, JobPriorityBackfill)
+	require.NoError(t, err)
+
+	count, err := db.DB().Model((*Job)(nil)).Where("network = ?", testJobsNetwork).Count()
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+
+	time.Sleep(1100 * time.Millisecond)
+	recovered, err := restarted.Lease(ctx, testJobsNetwork, 10)
+	require.NoError(t, err)
+	assert.Len(t, recovered, 3)
+
+	// resolved metadata is removed from queue, metadata which is still new is retried later
+	recovered[0].Status = StatusApplied
+	recovered[1].NextAttemptAt = time.Now().Unix() + 60
+	require.NoError(t, restarted.Complete(ctx, recovered[:2]))
+
+	var jobs []Job
+	require.NoError(t, db.DB().Model(&jobs).Where("network = ?", testJobsNetwork).Order("metadata_id").Select())
+	require.Len(t, jobs, 2)
+	assert.Equal(t, recovered[1].ID, jobs[0].MetadataID)
+	assert.Equal(t, JobPriorityRetry, jobs[0].Priority)
+	assert.Empty(t, jobs[0].LeasedBy)
+	assert.Greater(t, jobs[0].AvailableAt, time.Now().Unix()+30)
+}
+
+func TestIntegration_JobQueue_priorityAndFairness(t *testing.T) {
+	db := newTestDatabase(t)
+	ctx := context.Background()
+
+	queue := NewTokenJobs(db.PgGo, WithContractLimit(2))
+
+	saveTestTokens(t, db, "KT1spam", 10)
+	lastUpdateID, err := queue.Sync(ctx, testJobsNetwork, 0, JobPriorityBackfill)
+	require.NoError(t, err)
+
+	saveTestTokens(t, db, "KT1fresh", 1)
+	_, err = queue.Sync(ctx, testJobsNetwork, lastUpdateID, JobPriorityFresh)
+	require.NoError(t, err)
+
+	jobs, err := queue.lease(ctx, testJobsNetwork, 10, time.Now())
+	require.NoError(t, err)
+	require.Len(t, jobs, 3, "spammy contract is limited by 2 leases")
+	assert.Equal(t, "KT1fresh", jobs[0].Contract)
+	assert.Equal(t, JobPriorityFresh, jobs[0].Priority)
+
+	jobs, err = queue.lease(ctx, testJobsNetwork, 10, time.Now())
+	require.NoError(t, err)
+	assert.Empty(t, jobs, "contract holds all its leases")
+}
+
+func TestIntegration_JobQueue_partiallyLeasedContract(t *testing.T) {
+	db := newTestDatabase(t)
+	ctx := context.Background()
+
+	queue := NewTokenJobs(db.PgGo, WithContractLimit(3))
+
+	saveTestTokens(t, db, "KT1spam", 10)
+	_, err := queue.Sync(ctx, testJobsNetwork, 0, JobPriorityFresh)
+	require.NoError(t, err)
+
+	jobs, err := queue.lease(ctx, testJobsNetwork, 1, time.Now())
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+
+	jobs, err = queue.lease(ctx, testJobsNetwork, 10, time.Now())
+	require.NoError(t, err)
+	assert.Len(t, jobs, 2, "active lease is subtracted from contract limit")
+}
+
+func TestIntegration_JobQueue_syncRetried(t *testing.T) {
+	db := newTestDatabase(t)
+	ctx := context.Background()
+
+	queue := NewTokenJobs(db.PgGo)
+
+	saveTestTokens(t, db, "KT1", 2)
+	lastUpdateID, err := queue.Sync(ctx, testJobsNetwork, 0, JobPriorityFresh)
+	require.NoError(t, err)
+
+	leased, err := queue.Lease(ctx, testJobsNetwork, 10)
+	require.NoError(t, err)
+	require.Len(t, leased, 2)
+
+	// the first token failed, the second one is still leased
+	leased[0].Status = StatusFailed
+	leased[0].ErrorType = "timeout"
+	require.NoError(t, db.Tokens.Update(ctx, leased[:1]))
+	require.NoError(t, queue.Complete(ctx, leased[:1]))
+
+	require.NoError(t, db.Tokens.Retry(testJobsNetwork, []string{"timeout"}, time.Hour))
+
+	synced, err := queue.Sync(ctx, testJobsNetwork, lastUpdateID, JobPriorityBackfill)
+	require.NoError(t, err)
+	assert.Greater(t, synced, lastUpdateID, "retried metadata gets new update id")
+
+	var jobs []Job
+	require.NoError(t, db.DB().Model(&jobs).Where("network = ?", testJobsNetwork).Order("metadata_id").Select())
+	require.Len(t, jobs, 2)
+	assert.Equal(t, JobPriorityBackfill, jobs[0].Priority)
+	assert.Empty(t, jobs[0].LeasedBy)
+	assert.Zero(t, jobs[0].Attempts)
+
+	// leased job isn't reset by sync
+	_, err = queue.Sync(ctx, testJobsNetwork, 0, JobPriorityBackfill)
+	require.NoError(t, err)
+	require.NoError(t, db.DB().Model(&jobs).Where("network = ?", testJobsNetwork).Order("metadata_id").Select())
+	assert.Equal(t, JobPriorityFresh, jobs[1].Priority)
+	assert.Equal(t, queue.Owner(), jobs[1].LeasedBy)
+	assert.Equal(t, 1, jobs[1].Attempts)
+}
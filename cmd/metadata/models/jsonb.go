@@ -36,3 +36,21 @@ func (j *JSONB) Scan(value interface{}) error {
 func (j JSONB) IsNull() bool {
 	return len(j) == 0 || string(j) == "null"
 }
+
+// MarshalJSON - JSONB is written as is, like `json.RawMessage`
+func (j JSONB) MarshalJSON() ([]byte, error) {
+	if j.IsNull() {
+		return []byte("null"), nil
+	}
+	return j, nil
+}
+
+// UnmarshalJSON -
+func (j *JSONB) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*j = nil
+		return nil
+	}
+	*j = append((*j)[0:0], data...)
+	return nil
+}
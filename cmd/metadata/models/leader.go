@@ -0,0 +1,78 @@
+package models
+
+import (
+	"context"
+	"hash/fnv"
+
+	"github.com/dipdup-net/go-lib/database"
+	pg "github.com/go-pg/pg/v10"
+)
+
+// Leaders - leader election of instances sharing database. Leader holds session advisory lock of Postgres, so lock is released when its connection is closed.
+type Leaders struct {
+	db *database.PgGo
+}
+
+// NewLeaders -
+func NewLeaders(db *database.PgGo) *Leaders {
+	return &Leaders{db: db}
+}
+
+// Leadership - advisory lock held by dedicated connection
+type Leadership struct {
+	name string
+	key  int64
+	conn *pg.Conn
+}
+
+func leaderKey(name string) int64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte("metadata:" + name))
+	return int64(h.Sum64())
+}
+
+// TryAcquire - takes leadership of `name` without waiting. Returns nil if another instance is leader.
+func (leaders *Leaders) TryAcquire(ctx context.Context, name string) (*Leadership, error) {
+	conn := leaders.db.DB().Conn()
+	key := leaderKey(name)
+
+	var acquired bool
+	if _, err := conn.QueryOneContext(ctx, pg.Scan(&acquired), `SELECT pg_try_advisory_lock(?)`, key); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	if !acquired {
+		return nil, conn.Close()
+	}
+	return &Leadership{
+		name: name,
+		key:  key,
+		conn: conn,
+	}, nil
+}
+
+// Name -
+func (l *Leadership) Name() string {
+	return l.name
+}
+
+// Held - checks that lock is still held by the connection. It's lost if connection was broken and reconnected.
+func (l *Leadership) Held(ctx context.Context) (held bool, err error) {
+	_, err = l.conn.QueryOneContext(ctx, pg.Scan(&held), `
+		SELECT EXISTS (
+			SELECT 1 FROM pg_locks
+			WHERE locktype = 'advisory' AND granted AND pid = pg_backend_pid()
+				AND objsubid = 1 AND ((classid::bigint << 32) | objid::bigint) = ?
+		)
+	`, l.key)
+	return
+}
+
+// Release - unlocks advisory lock and closes connection
+func (l *Leadership) Release(ctx context.Context) error {
+	if _, err := l.conn.ExecContext(ctx, `SELECT pg_advisory_unlock(?)`, l.key); err != nil {
+		_ = l.conn.Close()
+		return err
+	}
+	return l.conn.Close()
+}
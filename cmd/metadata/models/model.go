@@ -1,15 +1,18 @@
 package models
 
-import "time"
+import (
+	"context"
+	"time"
+)
 
 // ModelRepository -
 type ModelRepository[T Model] interface {
-	Get(network string, status Status, limit, offset, retryCount, delay int) ([]T, error)
-	Update(metadata []T) error
-	Save(metadata []T) error
+	Get(network string, status Status, limit, offset int) ([]T, error)
+	Update(ctx context.Context, metadata []T) error
+	Save(ctx context.Context, metadata []T) error
 	LastUpdateID() (int64, error)
 	CountByStatus(network string, status Status) (int, error)
-	Retry(network string, retryCount int, window time.Duration) error
+	Retry(network string, errorTypes []string, window time.Duration) error
 }
 
 // Model -
@@ -0,0 +1,147 @@
+package models
+
+import (
+	"context"
+
+	"github.com/go-pg/pg/v10/orm"
+	"github.com/shopspring/decimal"
+)
+
+// TokenKey - unique key of the token in the network
+type TokenKey struct {
+	Contract string          `json:"contract"`
+	TokenID  decimal.Decimal `json:"token_id"`
+}
+
+// GetContract - returns contract metadata by address. Returns `pg.ErrNoRows` if it's not found.
+func (db *Database) GetContract(ctx context.Context, network, address string) (cm ContractMetadata, err error) {
+	err = db.DB().ModelContext(ctx, &cm).
+		Where("network = ?", network).
+		Where("contract = ?", address).
+		First()
+	return
+}
+
+// GetToken - returns token metadata by contract and token id. Returns `pg.ErrNoRows` if it's not found.
+func (db *Database) GetToken(ctx context.Context, network, contract string, tokenID decimal.Decimal) (tm TokenMetadata, err error) {
+	err = db.DB().ModelContext(ctx, &tm).
+		Where("network = ?", network).
+		Where("contract = ?", contract).
+		Where("token_id = ?", tokenID).
+		First()
+	return
+}
+
+// GetTokens - returns token metadata by list of keys. Tokens which are not found are skipped.
+func (db *Database) GetTokens(ctx context.Context, network string, keys []TokenKey) (tokens []TokenMetadata, err error) {
+	if len(keys) == 0 {
+		return
+	}
+
+	err = db.DB().ModelContext(ctx, &tokens).
+		Where("network = ?", network).
+		WhereGroup(func(q *orm.Query) (*orm.Query, error) {
+			for i := range keys {
+				key := keys[i]
+				q.WhereOrGroup(func(q *orm.Query) (*orm.Query, error) {
+					return q.Where("contract = ?", key.Contract).Where("token_id = ?", key.TokenID), nil
+				})
+			}
+			return q, nil
+		}).
+		Order("update_id asc").
+		Select()
+	return
+}
+
+// ContractsAfter - returns contract metadata updated after `updateID` sorted by update id. Empty `contract` means all contracts of the network. Rows of transactions in progress and rows after them aren't returned, so `updateID` of the last returned row is a safe cursor.
+func (db *Database) ContractsAfter(ctx context.Context, network, contract string, updateID int64, limit int) (contracts []ContractMetadata, err error) {
+	query := db.DB().ModelContext(ctx, &contracts).
+		Where("network = ?", network).
+		Where("update_id > ?", updateID).
+		Where(committedUpdateIDs(contractUpdateIDSequence))
+	if contract != "" {
+		query.Where("contract = ?", contract)
+	}
+	err = query.Order("update_id asc").Limit(limit).Select()
+	return
+}
+
+// TokensAfter - returns token metadata updated after `updateID` sorted by update id. Empty `contract` means all contracts of the network. Rows of transactions in progress and rows after them aren't returned, so `updateID` of the last returned row is a safe cursor.
+func (db *Database) TokensAfter(ctx context.Context, network, contract string, updateID int64, limit int) (tokens []TokenMetadata, err error) {
+	query := db.DB().ModelContext(ctx, &tokens).
+		Where("network = ?", network).
+		Where("update_id > ?", updateID).
+		Where(committedUpdateIDs(tokenUpdateIDSequence))
+	if contract != "" {
+		query.Where("contract = ?", contract)
+	}
+	err = query.Order("update_id asc").Limit(limit).Select()
+	return
+}
+
+// LastUpdateIDs - returns the greatest update ids of contract and token metadata of network which are safe cursors, see `ContractsAfter` and `TokensAfter`
+func (db *Database) LastUpdateIDs(ctx context.Context, network string) (contracts int64, tokens int64, err error) {
+	if err = db.DB().ModelContext(ctx, (*ContractMetadata)(nil)).
+		ColumnExpr("coalesce(max(update_id), 0)").
+		Where("network = ?", network).
+		Where(committedUpdateIDs(contractUpdateIDSequence)).
+		Select(&contracts); err != nil {
+		return
+	}
+	err = db.DB().ModelContext(ctx, (*TokenMetadata)(nil)).
+		ColumnExpr("coalesce(max(update_id), 0)").
+		Where("network = ?", network).
+		Where(committedUpdateIDs(tokenUpdateIDSequence)).
+		Select(&tokens)
+	return
+}
+
+// ScanContracts - returns contract metadata of all networks after (`updateID`, `id`) pair sorted by update id and id. It's used to copy the table.
+func (db *Database) ScanContracts(ctx context.Context, updateID int64, id uint64, limit int) (contracts []ContractMetadata, err error) {
+	err = db.DB().ModelContext(ctx, &contracts).
+		Where("(update_id, id) > (?, ?)", updateID, id).
+		Where(committedUpdateIDs(contractUpdateIDSequence)).
+		Order("update_id asc", "id asc").
+		Limit(limit).
+		Select()
+	return
+}
+
+// ScanTokens - returns token metadata of all networks after (`updateID`, `id`) pair sorted by update id and id. It's used to copy the table.
+func (db *Database) ScanTokens(ctx context.Context, updateID int64, id uint64, limit int) (tokens []TokenMetadata, err error) {
+	err = db.DB().ModelContext(ctx, &tokens).
+		Where("(update_id, id) > (?, ?)", updateID, id).
+		Where(committedUpdateIDs(tokenUpdateIDSequence)).
+		Order("update_id asc", "id asc").
+		Limit(limit).
+		Select()
+	return
+}
+
+// CountTokensByContracts - returns count of token metadata of contracts. Contracts without tokens are skipped.
+func (db *Database) CountTokensByContracts(ctx context.Context, network string, contracts []string) (map[string]int, error) {
+	result := make(map[string]int)
+	if len(contracts) == 0 {
+		return result, nil
+	}
+
+	var counts []struct {
+		Contract string
+		Count    int
+	}
+	if err := db.DB().ModelContext(ctx, (*TokenMetadata)(nil)).
+		Column("contract").
+		ColumnExpr("count(*) AS count").
+		Where("network = ?", network).
+		WhereIn("contract IN (?)", contracts).
+		Group("contract").
+		Select(&counts); err != nil {
+		return nil, err
+	}
+
+	for i := range counts {
+		result[counts[i].Contract] = counts[i].Count
+	}
+	return result, nil
+}
@@ -0,0 +1,159 @@
+package models
+
+import (
+	"context"
+	stdJSON "encoding/json"
+	"strings"
+
+	pg "github.com/go-pg/pg/v10"
+	"github.com/go-pg/pg/v10/orm"
+)
+
+// search sort fields
+const (
+	SearchSortRelevance = "relevance"
+	SearchSortCreatedAt = "created_at"
+	SearchSortUpdatedAt = "updated_at"
+)
+
+// SearchParams - parameters of full text search over `search_vector` columns. Empty fields aren't filtered.
+type SearchParams struct {
+	Query    string
+	Network  string
+	Contract string
+	Status   Status
+
+	// token filters
+	Creator string
+	Tag     string
+	Mime    string
+
+	Sort  string
+	Desc  bool
+	Limit int
+	After *SearchCursor
+}
+
+// SearchCursor - sort values of the last item of previous page. `UpdateID` is a tiebreaker because it's unique.
+type SearchCursor struct {
+	Value    float64
+	UpdateID int64
+}
+
+// rank - expression of relevance. Without query all rows are equally relevant.
+func (params SearchParams) rank() string {
+	if params.Query == "" {
+		return "0::real"
+	}
+	return "ts_rank(search_vector, plainto_tsquery('simple', ?0))"
+}
+
+func (params SearchParams) sortExpr() string {
+	switch params.Sort {
+	case SearchSortCreatedAt, SearchSortUpdatedAt:
+		return params.Sort
+	default:
+		return params.rank()
+	}
+}
+
+func (params SearchParams) direction() string {
+	if params.Desc {
+		return "desc"
+	}
+	return "asc"
+}
+
+// apply - filters, order and keyset pagination which are common for tokens and contracts
+func (params SearchParams) apply(query *orm.Query) *orm.Query {
+	query.ColumnExpr("*").
+		ColumnExpr(params.rank()+" AS rank", params.Query)
+
+	if params.Query != "" {
+		query.Where("search_vector @@ plainto_tsquery('simple', ?)", params.Query)
+	}
+	if params.Network != "" {
+		query.Where("network = ?", params.Network)
+	}
+	if params.Contract != "" {
+		query.Where("contract = ?", params.Contract)
+	}
+	if params.Status > 0 {
+		query.Where("status = ?", params.Status)
+	}
+
+	sortExpr := params.sortExpr()
+	if params.After != nil {
+		op := ">"
+		if params.Desc {
+			op = "<"
+		}
+		cast := "real"
+		if params.Sort == SearchSortCreatedAt || params.Sort == SearchSortUpdatedAt {
+			cast = "bigint"
+		}
+		query.Where("("+sortExpr+", update_id) "+op+" (?1::"+cast+", ?2)", params.Query, params.After.Value, params.After.UpdateID)
+	}
+
+	direction := params.direction()
+	return query.
+		OrderExpr(strings.Join([]string{sortExpr, direction}, " "), params.Query).
+		OrderExpr("update_id " + direction).
+		Limit(params.Limit)
+}
+
+// SearchedToken - token metadata found by full text search with its relevance
+type SearchedToken struct {
+	//nolint
+	tableName struct{} `pg:"token_metadata,discard_unknown_columns"`
+
+	TokenMetadata
+
+	Rank float64 `pg:"rank"`
+}
+
+// SearchedContract - contract metadata found by full text search with its relevance
+type SearchedContract struct {
+	//nolint
+	tableName struct{} `pg:"contract_metadata,discard_unknown_columns"`
+
+	ContractMetadata
+
+	Rank float64 `pg:"rank"`
+}
+
+// SearchTokens - full text search of token metadata by name, symbol, description and tags
+func (db *Database) SearchTokens(ctx context.Context, params SearchParams) (tokens []SearchedToken, err error) {
+	query, err := params.tokens(db.DB().ModelContext(ctx, &tokens))
+	if err != nil {
+		return nil, err
+	}
+	err = query.Select()
+	return
+}
+
+func (params SearchParams) tokens(query *orm.Query) (*orm.Query, error) {
+	params.apply(query)
+	if params.Creator != "" {
+		query.Where("creators @> ?", pg.Array([]string{params.Creator}))
+	}
+	if params.Tag != "" {
+		query.Where("tags @> ?", pg.Array([]string{params.Tag}))
+	}
+	if params.Mime != "" {
+		formats, err := stdJSON.Marshal([]map[string]string{
+			{"mimeType": params.Mime},
+		})
+		if err != nil {
+			return nil, err
+		}
+		query.Where("metadata::jsonb->'formats' @> ?::jsonb", string(formats))
+	}
+	return query, nil
+}
+
+// SearchContracts - full text search of contract metadata by name, description and tags
+func (db *Database) SearchContracts(ctx context.Context, params SearchParams) (contracts []SearchedContract, err error) {
+	err = params.apply(db.DB().ModelContext(ctx, &contracts)).Select()
+	return
+}
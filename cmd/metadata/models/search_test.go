@@ -0,0 +1,62 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/go-pg/pg/v10/orm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearchParams_tokens(t *testing.T) {
+	tests := []struct {
+		name   string
+		params SearchParams
+		want   string
+	}{
+		{
+			name: "filters without query",
+			params: SearchParams{
+				Network: "mainnet",
+				Creator: "tz1",
+				Tag:     "art",
+				Mime:    "image/png",
+				Status:  StatusApplied,
+				Sort:    SearchSortCreatedAt,
+				Desc:    true,
+				Limit:   25,
+			},
+			want: `SELECT *, 0::real AS rank FROM "token_metadata" AS "searched_token" WHERE (network = 'mainnet') AND (status = 3) AND (creators @> '{"tz1"}') AND (tags @> '{"art"}') AND (metadata::jsonb->'formats' @> '[{"mimeType":"image/png"}]'::jsonb) ORDER BY created_at desc, update_id desc LIMIT 25`,
+		}, {
+			name: "relevance with cursor",
+			params: SearchParams{
+				Query: "hic et nunc",
+				Sort:  SearchSortRelevance,
+				Desc:  true,
+				Limit: 10,
+				After: &SearchCursor{Value: 0.0607927, UpdateID: 12},
+			},
+			want: `SELECT *, ts_rank(search_vector, plainto_tsquery('simple', 'hic et nunc')) AS rank FROM "token_metadata" AS "searched_token" WHERE (search_vector @@ plainto_tsquery('simple', 'hic et nunc')) AND ((ts_rank(search_vector, plainto_tsquery('simple', 'hic et nunc')), update_id) < (0.0607927::real, 12)) ORDER BY ts_rank(search_vector, plainto_tsquery('simple', 'hic et nunc')) desc, update_id desc LIMIT 10`,
+		}, {
+			name: "ascending with cursor",
+			params: SearchParams{
+				Query: "o'hara",
+				Sort:  SearchSortUpdatedAt,
+				Limit: 10,
+				After: &SearchCursor{Value: 1690000000, UpdateID: 12},
+			},
+			want: `SELECT *, ts_rank(search_vector, plainto_tsquery('simple', 'o''hara')) AS rank FROM "token_metadata" AS "searched_token" WHERE (search_vector @@ plainto_tsquery('simple', 'o''hara')) AND ((updated_at, update_id) > (1690000000::bigint, 12)) ORDER BY updated_at asc, update_id asc LIMIT 10`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var tokens []SearchedToken
+			query, err := tt.params.tokens(orm.NewQuery(nil, &tokens))
+			require.NoError(t, err)
+
+			sql, err := orm.NewSelectQuery(query).AppendQuery(orm.NewFormatter(), nil)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, string(sql))
+		})
+	}
+}
@@ -1,5 +1,7 @@
 package models
 
+import "github.com/pkg/errors"
+
 // Status - metadata status
 type Status int8
 
@@ -7,6 +9,7 @@ const (
 	StatusNew Status = iota + 1
 	StatusFailed
 	StatusApplied
+	StatusRemoved
 )
 
 // String -
@@ -18,7 +21,19 @@ func (s Status) String() string {
 		return "failed"
 	case StatusNew:
 		return "new"
+	case StatusRemoved:
+		return "removed"
 	default:
 		return "unknown"
 	}
 }
+
+// ParseStatus - returns status by its string representation
+func ParseStatus(value string) (Status, error) {
+	for _, status := range []Status{StatusNew, StatusFailed, StatusApplied, StatusRemoved} {
+		if status.String() == value {
+			return status, nil
+		}
+	}
+	return 0, errors.Errorf("unknown status: %s", value)
+}
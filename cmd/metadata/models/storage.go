@@ -0,0 +1,74 @@
+package models
+
+import (
+	"context"
+
+	"github.com/dipdup-net/go-lib/database"
+)
+
+// Backend - database which keeps indexer state
+type Backend interface {
+	database.StateRepository
+
+	CreateIndices() error
+	Close() error
+}
+
+// TezosKeysRepository -
+type TezosKeysRepository interface {
+	Get(network, address, key string) (TezosKey, error)
+	Save(tk TezosKey) error
+	Delete(tk TezosKey) error
+}
+
+// UpdatesRepository - committed metadata of network sorted by update id
+type UpdatesRepository interface {
+	LastUpdateIDs(ctx context.Context, network string) (contracts int64, tokens int64, err error)
+	ContractsAfter(ctx context.Context, network, contract string, updateID int64, limit int) ([]ContractMetadata, error)
+	TokensAfter(ctx context.Context, network, contract string, updateID int64, limit int) ([]TokenMetadata, error)
+}
+
+// Storage - repositories of indexer. Postgres implements all of them. Other backends may not support changes tracking, versions history and webhooks outbox, so they're nil then.
+type Storage struct {
+	Backend
+
+	Tokens    ModelRepository[*TokenMetadata]
+	Contracts ModelRepository[*ContractMetadata]
+	TezosKeys TezosKeysRepository
+	Changes   *Changes
+	History   *History
+	Webhooks  *Webhooks
+
+	// writes of several repositories are committed at once, other backends write immediately
+	Transactions *Transactions
+
+	// durable queues of metadata resolving, services poll repositories if they're nil
+	TokenJobs    *JobQueue[*TokenMetadata]
+	ContractJobs *JobQueue[*ContractMetadata]
+
+	// leader election of instances sharing database, indexer runs as the only instance if it's nil
+	Leaders *Leaders
+
+	// reading of committed metadata by update id, off-chain views aren't executed if it's nil
+	Updates UpdatesRepository
+}
+
+// Storage - returns storage backed by Postgres
+func (db *Database) Storage() *Storage {
+	return &Storage{
+		Backend:   db,
+		Tokens:    db.Tokens,
+		Contracts: db.Contracts,
+		TezosKeys: db.TezosKeys,
+		Changes:   db.Changes,
+		History:   db.History,
+		Webhooks:  db.Webhooks,
+
+		Transactions: db.Transactions,
+
+		TokenJobs:    db.TokenJobs,
+		ContractJobs: db.ContractJobs,
+		Leaders:      db.Leaders,
+		Updates:      db,
+	}
+}
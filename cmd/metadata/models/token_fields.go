@@ -0,0 +1,177 @@
+package models
+
+import (
+	"bytes"
+	"context"
+	stdJSON "encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/go-pg/pg/v10"
+)
+
+// NormalizedTokenColumns - columns filled by `TokenMetadata.Normalize`
+var NormalizedTokenColumns = []string{
+	"name", "symbol", "decimals", "artifact_uri", "display_uri", "thumbnail_uri", "creators", "tags", "is_boolean_amount", "royalties",
+}
+
+var excludedNormalizedTokenColumns = func() string {
+	sets := make([]string, len(NormalizedTokenColumns))
+	for i := range NormalizedTokenColumns {
+		sets[i] = NormalizedTokenColumns[i] + " = excluded." + NormalizedTokenColumns[i]
+	}
+	return strings.Join(sets, ", ")
+}()
+
+// Normalize - extracts common TZIP-21 fields of metadata to separate columns. Fields which have unexpected types are left empty. Metadata of not applied tokens is not normalized.
+func (tm *TokenMetadata) Normalize() {
+	tm.Name = ""
+	tm.Symbol = ""
+	tm.Decimals = nil
+	tm.ArtifactURI = ""
+	tm.DisplayURI = ""
+	tm.ThumbnailURI = ""
+	tm.Creators = nil
+	tm.Tags = nil
+	tm.IsBooleanAmount = nil
+	tm.Royalties = nil
+
+	if tm.Status != StatusApplied || tm.Metadata.IsNull() {
+		return
+	}
+
+	var document map[string]stdJSON.RawMessage
+	if err := stdJSON.Unmarshal(tm.Metadata, &document); err != nil {
+		return
+	}
+
+	tm.Name = normalizedString(document["name"])
+	tm.Symbol = normalizedString(document["symbol"])
+	tm.Decimals = normalizedInt(document["decimals"])
+	tm.ArtifactURI = normalizedString(document["artifactUri"])
+	tm.DisplayURI = normalizedString(document["displayUri"])
+	tm.ThumbnailURI = normalizedString(document["thumbnailUri"])
+	tm.Creators = normalizedStrings(document["creators"])
+	tm.Tags = normalizedStrings(document["tags"])
+	tm.IsBooleanAmount = normalizedBool(document["isBooleanAmount"])
+
+	if royalties := bytes.TrimSpace(document["royalties"]); len(royalties) > 0 && royalties[0] == '{' {
+		tm.Royalties = JSONB(royalties)
+	}
+}
+
+func normalizedString(raw stdJSON.RawMessage) string {
+	var value string
+	if err := stdJSON.Unmarshal(raw, &value); err != nil {
+		return ""
+	}
+	return value
+}
+
+// nullString - empty strings are stored as NULL on insert, so they should be on update too
+func nullString(value string) any {
+	if value == "" {
+		return nil
+	}
+	return value
+}
+
+// normalizedInt - TZIP-12 numbers are often encoded as strings, so both representations are accepted
+func normalizedInt(raw stdJSON.RawMessage) *int {
+	if len(raw) == 0 {
+		return nil
+	}
+
+	str := normalizedString(raw)
+	if str == "" {
+		str = string(raw)
+	}
+
+	value, err := strconv.Atoi(strings.TrimSpace(str))
+	if err != nil || value < 0 {
+		return nil
+	}
+	return &value
+}
+
+// normalizedBool - booleans encoded as strings are accepted too
+func normalizedBool(raw stdJSON.RawMessage) *bool {
+	if len(raw) == 0 {
+		return nil
+	}
+
+	var value bool
+	if err := stdJSON.Unmarshal(raw, &value); err == nil {
+		return &value
+	}
+
+	value, err := strconv.ParseBool(normalizedString(raw))
+	if err != nil {
+		return nil
+	}
+	return &value
+}
+
+// normalizedStrings - skips non-string items of array
+func normalizedStrings(raw stdJSON.RawMessage) []string {
+	var items []stdJSON.RawMessage
+	if err := stdJSON.Unmarshal(raw, &items); err != nil {
+		return nil
+	}
+
+	values := make([]string, 0, len(items))
+	for i := range items {
+		var value string
+		if err := stdJSON.Unmarshal(items[i], &value); err != nil {
+			continue
+		}
+		values = append(values, value)
+	}
+	if len(values) == 0 {
+		return nil
+	}
+	return values
+}
+
+// GetApplied - returns applied token metadata with id greater than `from` sorted by id
+func (tokens *Tokens) GetApplied(ctx context.Context, from uint64, limit int) (all []*TokenMetadata, err error) {
+	err = tokens.db.DB().ModelContext(ctx, &all).
+		Where("status = ?", StatusApplied).
+		Where("id > ?", from).
+		Order("id asc").
+		Limit(limit).
+		Select()
+	return
+}
+
+// SetNormalized - updates normalized columns only. `update_id` is not changed because metadata itself is not changed.
+func (tokens *Tokens) SetNormalized(ctx context.Context, metadata []*TokenMetadata) error {
+	if len(metadata) == 0 {
+		return nil
+	}
+
+	tokens.mx.Lock()
+	defer tokens.mx.Unlock()
+
+	return tokens.db.DB().RunInTransaction(ctx, func(tx *pg.Tx) error {
+		for i := range metadata {
+			// nil model doesn't call update hooks
+			if _, err := tx.ModelContext(ctx, (*TokenMetadata)(nil)).
+				Set("name = ?", nullString(metadata[i].Name)).
+				Set("symbol = ?", nullString(metadata[i].Symbol)).
+				Set("decimals = ?", metadata[i].Decimals).
+				Set("artifact_uri = ?", nullString(metadata[i].ArtifactURI)).
+				Set("display_uri = ?", nullString(metadata[i].DisplayURI)).
+				Set("thumbnail_uri = ?", nullString(metadata[i].ThumbnailURI)).
+				Set("creators = ?", pg.Array(metadata[i].Creators)).
+				Set("tags = ?", pg.Array(metadata[i].Tags)).
+				Set("is_boolean_amount = ?", metadata[i].IsBooleanAmount).
+				Set("royalties = ?", metadata[i].Royalties).
+				Where("id = ?", metadata[i].ID).
+				Update(); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
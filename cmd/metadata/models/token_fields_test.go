@@ -0,0 +1,89 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenMetadata_Normalize(t *testing.T) {
+	intPtr := func(value int) *int { return &value }
+	boolPtr := func(value bool) *bool { return &value }
+
+	tests := []struct {
+		name  string
+		token TokenMetadata
+		want  TokenMetadata
+	}{
+		{
+			name: "TZIP-21 document",
+			token: TokenMetadata{
+				Status:   StatusApplied,
+				Metadata: JSONB(`{"name":"Tezzardz #1","symbol":"FKR","decimals":0,"artifactUri":"ipfs://QmArtifact","displayUri":"ipfs://QmDisplay","thumbnailUri":"ipfs://QmThumbnail","creators":["tz1creator",7,"tz1second"],"tags":["pixel","lizard"],"isBooleanAmount":true,"royalties":{"decimals":3,"shares":{"tz1creator":"50"}}}`),
+			},
+			want: TokenMetadata{
+				Name:            "Tezzardz #1",
+				Symbol:          "FKR",
+				Decimals:        intPtr(0),
+				ArtifactURI:     "ipfs://QmArtifact",
+				DisplayURI:      "ipfs://QmDisplay",
+				ThumbnailURI:    "ipfs://QmThumbnail",
+				Creators:        []string{"tz1creator", "tz1second"},
+				Tags:            []string{"pixel", "lizard"},
+				IsBooleanAmount: boolPtr(true),
+				Royalties:       JSONB(`{"decimals":3,"shares":{"tz1creator":"50"}}`),
+			},
+		}, {
+			name: "on-chain token info with string values",
+			token: TokenMetadata{
+				Status:   StatusApplied,
+				Metadata: JSONB(`{"name":"tzBTC","symbol":"tzBTC","decimals":"8","isBooleanAmount":"false"}`),
+			},
+			want: TokenMetadata{
+				Name:            "tzBTC",
+				Symbol:          "tzBTC",
+				Decimals:        intPtr(8),
+				IsBooleanAmount: boolPtr(false),
+			},
+		}, {
+			name: "invalid types",
+			token: TokenMetadata{
+				Status:   StatusApplied,
+				Metadata: JSONB(`{"name":1,"decimals":"-1","creators":"tz1creator","tags":[1,2],"isBooleanAmount":"yes","royalties":[]}`),
+			},
+			want: TokenMetadata{},
+		}, {
+			name: "not JSON object",
+			token: TokenMetadata{
+				Status:   StatusApplied,
+				Metadata: JSONB(`["name"]`),
+			},
+			want: TokenMetadata{},
+		}, {
+			name: "not applied token is cleared",
+			token: TokenMetadata{
+				Status:   StatusFailed,
+				Metadata: JSONB(`{"name":"tzBTC"}`),
+				Name:     "stale",
+				Decimals: intPtr(8),
+			},
+			want: TokenMetadata{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.token.Normalize()
+
+			assert.Equal(t, tt.want.Name, tt.token.Name, "Name")
+			assert.Equal(t, tt.want.Symbol, tt.token.Symbol, "Symbol")
+			assert.Equal(t, tt.want.Decimals, tt.token.Decimals, "Decimals")
+			assert.Equal(t, tt.want.ArtifactURI, tt.token.ArtifactURI, "ArtifactURI")
+			assert.Equal(t, tt.want.DisplayURI, tt.token.DisplayURI, "DisplayURI")
+			assert.Equal(t, tt.want.ThumbnailURI, tt.token.ThumbnailURI, "ThumbnailURI")
+			assert.Equal(t, tt.want.Creators, tt.token.Creators, "Creators")
+			assert.Equal(t, tt.want.Tags, tt.token.Tags, "Tags")
+			assert.Equal(t, tt.want.IsBooleanAmount, tt.token.IsBooleanAmount, "IsBooleanAmount")
+			assert.Equal(t, tt.want.Royalties, tt.token.Royalties, "Royalties")
+		})
+	}
+}
@@ -8,12 +8,19 @@ import (
 
 	"github.com/dipdup-net/go-lib/database"
 	"github.com/dipdup-net/metadata/cmd/metadata/helpers"
+	pg "github.com/go-pg/pg/v10"
 	"github.com/shopspring/decimal"
 )
 
-// TokenUpdateID - incremental counter
+// TokenUpdateID - incremental counter of storages without sequences, see `nextUpdateID`
 var TokenUpdateID = helpers.NewCounter(0)
 
+// token metadata sources
+const (
+	TokenSourceBigMap       = "big_map"
+	TokenSourceOffChainView = "off_chain_view"
+)
+
 // TokenMetadata -
 type TokenMetadata struct {
 	//nolint
@@ -32,6 +39,29 @@ type TokenMetadata struct {
 	Status         Status          `json:"status"`
 	ImageProcessed bool            `json:"image_processed" pg:",use_zero,notnull"`
 	Error          string          `json:"error,omitempty"`
+	Sha256         string          `json:"sha256,omitempty"`
+	Source         string          `json:"source"`
+	Issues         JSONB           `json:"issues,omitempty" pg:",type:jsonb"`
+
+	// retry schedule of new metadata and type of the last error, see `resolver.Retrier`
+	NextAttemptAt int64  `json:"next_attempt_at" pg:",use_zero"`
+	ErrorType     string `json:"error_type,omitempty"`
+
+	// sources of `Metadata`: decoded `token_info` and resolved document. On-chain fields take precedence by TZIP-12.
+	OnChainMetadata  JSONB `json:"on_chain_metadata,omitempty" pg:",type:json"`
+	OffChainMetadata JSONB `json:"off_chain_metadata,omitempty" pg:",type:json"`
+
+	// normalized fields of applied metadata, see `Normalize`
+	Name            string   `json:"name,omitempty"`
+	Symbol          string   `json:"symbol,omitempty"`
+	Decimals        *int     `json:"decimals,omitempty"`
+	ArtifactURI     string   `json:"artifact_uri,omitempty"`
+	DisplayURI      string   `json:"display_uri,omitempty"`
+	ThumbnailURI    string   `json:"thumbnail_uri,omitempty"`
+	Creators        []string `json:"creators,omitempty" pg:",array"`
+	Tags            []string `json:"tags,omitempty" pg:",array"`
+	IsBooleanAmount *bool    `json:"is_boolean_amount,omitempty"`
+	Royalties       JSONB    `json:"royalties,omitempty" pg:",type:jsonb"`
 }
 
 // Table -
@@ -53,14 +83,14 @@ func (tm TokenMetadata) GetID() uint64 {
 func (tm *TokenMetadata) BeforeInsert(ctx context.Context) (context.Context, error) {
 	tm.UpdatedAt = time.Now().Unix()
 	tm.CreatedAt = tm.UpdatedAt
-	tm.UpdateID = TokenUpdateID.Increment()
+	tm.UpdateID = nextUpdateID(ctx, TokenUpdateID)
 	return ctx, nil
 }
 
 // BeforeUpdate -
 func (tm *TokenMetadata) BeforeUpdate(ctx context.Context) (context.Context, error) {
 	tm.UpdatedAt = time.Now().Unix()
-	tm.UpdateID = TokenUpdateID.Increment()
+	tm.UpdateID = nextUpdateID(ctx, TokenUpdateID)
 	return ctx, nil
 }
 
@@ -84,18 +114,14 @@ func NewTokens(db *database.PgGo) *Tokens {
 	return &Tokens{db: db}
 }
 
-// Get -
-func (tokens *Tokens) Get(network string, status Status, limit, offset, retryCount, delay int) (all []*TokenMetadata, err error) {
+// Get - returns metadata with `status` which next attempt is due
+func (tokens *Tokens) Get(network string, status Status, limit, offset int) (all []*TokenMetadata, err error) {
 	subQuery := tokens.db.DB().Model((*TokenMetadata)(nil)).Column("id").
 		Where("status = ?", status).
 		Where("network = ?", network).
-		Where("created_at < (extract(epoch from current_timestamp) - ? * retry_count)", delay).
+		Where("next_attempt_at <= ?", time.Now().Unix()).
 		OrderExpr("retry_count desc, updated_at desc")
 
-	if retryCount > 0 {
-		subQuery.Where("retry_count < ?", retryCount)
-	}
-
 	query := tokens.db.DB().Model(&all).Where("id IN (?)", subQuery)
 	if limit > 0 {
 		query.Limit(limit)
@@ -107,36 +133,51 @@ func (tokens *Tokens) Get(network string, status Status, limit, offset, retryCou
 	return
 }
 
-// Retry -
-func (tokens *Tokens) Retry(network string, retryCount int, window time.Duration) error {
-	_, err := tokens.db.DB().Model((*TokenMetadata)(nil)).
-		Set("retry_count = 0").
-		Set("status = ?", StatusNew).
-		Where("status = ?", StatusFailed).
-		Where("network = ?", network).
-		Where("retry_count >= ?", retryCount).
-		Where("error LIKE '%%context deadline exceeded%%'").
-		Where("link LIKE 'ipfs://%%'").
-		Where("created_at > (extract(epoch from current_timestamp) - ?)", window).
-		Update()
-	return err
+// Retry - returns failed metadata which was created within `window` seconds and failed with one of `errorTypes` to new status. Retried metadata gets new update id, so it's synced to job queue and sent to clients again.
+func (tokens *Tokens) Retry(network string, errorTypes []string, window time.Duration) error {
+	if len(errorTypes) == 0 {
+		return nil
+	}
+	return runInTransaction(context.Background(), tokens.db, func(ctx context.Context) error {
+		if err := holdUpdateIDs(ctx, tokens.db, tokenUpdateIDSequence); err != nil {
+			return err
+		}
+		_, err := conn(ctx, tokens.db).ModelContext(ctx, (*TokenMetadata)(nil)).
+			Set("retry_count = 0").
+			Set("next_attempt_at = 0").
+			Set("status = ?", StatusNew).
+			Set("update_id = nextval(?)", tokenUpdateIDSequence).
+			Where("status = ?", StatusFailed).
+			Where("network = ?", network).
+			Where("error_type IN (?)", pg.In(errorTypes)).
+			Where("created_at > (extract(epoch from current_timestamp) - ?)", window).
+			Update()
+		return err
+	})
 }
 
 // Update -
-func (tokens *Tokens) Update(metadata []*TokenMetadata) error {
+func (tokens *Tokens) Update(ctx context.Context, metadata []*TokenMetadata) error {
 	if len(metadata) == 0 {
 		return nil
 	}
 
-	tokens.mx.Lock()
-	defer tokens.mx.Unlock()
+	return runInTransaction(ctx, tokens.db, func(ctx context.Context) error {
+		ctx, err := reserveUpdateIDs(ctx, tokens.db, tokenUpdateIDSequence, len(metadata))
+		if err != nil {
+			return err
+		}
+
+		tokens.mx.Lock()
+		defer tokens.mx.Unlock()
 
-	_, err := tokens.db.DB().Model(&metadata).Column("metadata", "update_id", "updated_at", "status", "retry_count", "error").WherePK().Update()
-	return err
+		_, err = conn(ctx, tokens.db).ModelContext(ctx, &metadata).Column(append([]string{"metadata", "update_id", "updated_at", "status", "retry_count", "error", "sha256", "issues", "on_chain_metadata", "off_chain_metadata", "next_attempt_at", "error_type"}, NormalizedTokenColumns...)...).WherePK().Update()
+		return err
+	})
 }
 
 // Save -
-func (tokens *Tokens) Save(metadata []*TokenMetadata) error {
+func (tokens *Tokens) Save(ctx context.Context, metadata []*TokenMetadata) error {
 	if len(metadata) == 0 {
 		return nil
 	}
@@ -155,14 +196,21 @@ func (tokens *Tokens) Save(metadata []*TokenMetadata) error {
 		return nil
 	}
 
-	tokens.mx.Lock()
-	defer tokens.mx.Unlock()
+	return runInTransaction(ctx, tokens.db, func(ctx context.Context) error {
+		ctx, err := reserveUpdateIDs(ctx, tokens.db, tokenUpdateIDSequence, len(savings))
+		if err != nil {
+			return err
+		}
 
-	_, err := tokens.db.DB().Model(&savings).
-		OnConflict("(network, contract, token_id) DO UPDATE").
-		Set("metadata = excluded.metadata, link = excluded.link, updated_at = excluded.updated_at, update_id = excluded.update_id, status = excluded.status, retry_count = excluded.retry_count").
-		Insert()
-	return err
+		tokens.mx.Lock()
+		defer tokens.mx.Unlock()
+
+		_, err = conn(ctx, tokens.db).ModelContext(ctx, &savings).
+			OnConflict("(network, contract, token_id) DO UPDATE").
+			Set("metadata = excluded.metadata, link = excluded.link, updated_at = excluded.updated_at, update_id = excluded.update_id, status = excluded.status, retry_count = excluded.retry_count, sha256 = excluded.sha256, source = excluded.source, issues = excluded.issues, on_chain_metadata = excluded.on_chain_metadata, off_chain_metadata = excluded.off_chain_metadata, next_attempt_at = excluded.next_attempt_at, error_type = excluded.error_type, " + excludedNormalizedTokenColumns).
+			Insert()
+		return err
+	})
 }
 
 // LastUpdateID -
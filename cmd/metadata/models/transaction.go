@@ -0,0 +1,47 @@
+package models
+
+import (
+	"context"
+
+	"github.com/dipdup-net/go-lib/database"
+	pg "github.com/go-pg/pg/v10"
+	"github.com/go-pg/pg/v10/orm"
+)
+
+type txKey struct{}
+
+// conn - returns transaction started by `Transactions.Run` if context has it. Otherwise queries are sent to the database.
+func conn(ctx context.Context, db *database.PgGo) orm.DB {
+	if tx, ok := ctx.Value(txKey{}).(*pg.Tx); ok {
+		return tx
+	}
+	return db.DB()
+}
+
+// Transactions - runs writes of several repositories in one transaction
+type Transactions struct {
+	db *database.PgGo
+}
+
+// NewTransactions -
+func NewTransactions(db *database.PgGo) *Transactions {
+	return &Transactions{db: db}
+}
+
+// Run - repositories write in transaction when they're called with context passed to `fn`. It's committed if `fn` succeeds.
+// Nested calls join the outer transaction. Backends without transactions (nil `Transactions`) write immediately.
+func (t *Transactions) Run(ctx context.Context, fn func(ctx context.Context) error) error {
+	if t == nil {
+		return fn(ctx)
+	}
+	return runInTransaction(ctx, t.db, fn)
+}
+
+func runInTransaction(ctx context.Context, db *database.PgGo, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txKey{}).(*pg.Tx); ok {
+		return fn(ctx)
+	}
+	return db.DB().RunInTransaction(ctx, func(tx *pg.Tx) error {
+		return fn(context.WithValue(ctx, txKey{}, tx))
+	})
+}
@@ -0,0 +1,132 @@
+package models
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/dipdup-net/go-lib/database"
+	"github.com/dipdup-net/metadata/cmd/metadata/helpers"
+	pg "github.com/go-pg/pg/v10"
+	"github.com/go-pg/pg/v10/orm"
+)
+
+// sequences of update ids. Values are shared by all instances which write to database, so ids don't collide.
+const (
+	tokenUpdateIDSequence    = "token_metadata_update_id_seq"
+	contractUpdateIDSequence = "contract_metadata_update_id_seq"
+)
+
+// updateIDSequences - sequences of update ids by table
+var updateIDSequences = map[string]string{
+	TokenMetadata{}.TableName():    tokenUpdateIDSequence,
+	ContractMetadata{}.TableName(): contractUpdateIDSequence,
+}
+
+type updateIDsKey struct{}
+
+// updateIDs - update ids reserved in sequence for one query
+type updateIDs struct {
+	values []int64
+}
+
+func (ids *updateIDs) next() (int64, bool) {
+	if len(ids.values) == 0 {
+		return 0, false
+	}
+	value := ids.values[0]
+	ids.values = ids.values[1:]
+	return value, true
+}
+
+// UpdateIDHold - transaction in progress which takes update ids of sequence greater than `floor`. Holds are committed at once, so readers see them before the transaction is finished.
+type UpdateIDHold struct {
+	//nolint
+	tableName struct{} `pg:"update_id_holds"`
+
+	ID       int64  `json:"-"`
+	Sequence string `json:"sequence" pg:",notnull"`
+	Xid      string `json:"xid" pg:",type:xid8,notnull"`
+	Floor    int64  `json:"floor" pg:",use_zero"`
+}
+
+// reserveUpdateIDs - holds sequence by transaction of context and takes `count` values of it. Hooks of models take update ids from returned context.
+func reserveUpdateIDs(ctx context.Context, db *database.PgGo, sequence string, count int) (context.Context, error) {
+	if err := holdUpdateIDs(ctx, db, sequence); err != nil {
+		return ctx, err
+	}
+	return takeUpdateIDs(ctx, conn(ctx, db), sequence, count)
+}
+
+// takeUpdateIDs - takes `count` values of sequence which is held by transaction already, see `holdUpdateIDs`
+func takeUpdateIDs(ctx context.Context, tx orm.DB, sequence string, count int) (context.Context, error) {
+	values := make([]int64, 0, count)
+	if _, err := tx.QueryContext(ctx, &values, `SELECT nextval(?) FROM generate_series(1, ?)`, sequence, count); err != nil {
+		return ctx, err
+	}
+	return context.WithValue(ctx, updateIDsKey{}, &updateIDs{values: values}), nil
+}
+
+// holdUpdateIDs - registers transaction of context as a writer of sequence. Update ids taken by it later are greater than the registered floor,
+// so readers which paginate by `update_id` (REST API, streams, job queue) stop below the floor till the transaction is finished, see `committedUpdateIDs`.
+// Transactions aren't serialized: they take ids and commit in any order, but rows with lower ids committed after greater ones aren't skipped by cursors.
+// Holds of finished transactions are removed by next holds.
+func holdUpdateIDs(ctx context.Context, db *database.PgGo, sequence string) error {
+	// id of transaction is assigned before the hold, so the hold can't outlive it unnoticed
+	var xid string
+	if _, err := conn(ctx, db).QueryOneContext(ctx, pg.Scan(&xid), `SELECT pg_current_xact_id()::text`); err != nil {
+		return err
+	}
+
+	// hold is written out of transaction to be visible to readers while the transaction is in progress
+	_, err := db.DB().ExecContext(ctx, `
+		WITH finished AS (
+			DELETE FROM update_id_holds WHERE id IN (
+				SELECT id FROM update_id_holds WHERE pg_visible_in_snapshot(xid, pg_current_snapshot()) FOR UPDATE SKIP LOCKED
+			)
+		)
+		INSERT INTO update_id_holds (sequence, xid, floor) VALUES (?0, ?1::xid8, nextval(?0))
+	`, sequence, xid)
+	return err
+}
+
+// committedUpdateIDs - SQL condition of rows which `update_id` is below floors of transactions in progress when query started.
+// Rows committed later get greater update ids, so the greatest returned update id is a safe cursor. Sequence is one of constants and is inlined.
+func committedUpdateIDs(sequence string) string {
+	return fmt.Sprintf(`update_id < (
+		SELECT coalesce(min(floor), 9223372036854775807) FROM update_id_holds
+		WHERE sequence = '%s' AND NOT pg_visible_in_snapshot(xid, pg_current_snapshot())
+	)`, sequence)
+}
+
+// nextUpdateID - returns reserved update id if query has them. Otherwise process-local counter is used, e.g. by Elasticsearch storage.
+func nextUpdateID(ctx context.Context, counter *helpers.Counter) int64 {
+	if ids, ok := ctx.Value(updateIDsKey{}).(*updateIDs); ok {
+		if value, ok := ids.next(); ok {
+			return value
+		}
+	}
+	return counter.Increment()
+}
+
+// createUpdateIDSequences - creates sequences and moves them after update ids written by previous versions
+func createUpdateIDSequences(ctx context.Context, db *pg.DB) error {
+	return db.RunInTransaction(ctx, func(tx *pg.Tx) error {
+		// instances may start at once
+		if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(?)`, leaderKey("update_id_sequences")); err != nil {
+			return err
+		}
+		for table, sequence := range updateIDSequences {
+			if _, err := tx.ExecContext(ctx, fmt.Sprintf(`CREATE SEQUENCE IF NOT EXISTS %s`, sequence)); err != nil {
+				return err
+			}
+			// sequence is moved only if it's behind, running instances may take values meanwhile
+			if _, err := tx.ExecContext(ctx, fmt.Sprintf(`
+				SELECT setval(?0, last) FROM (SELECT coalesce(max(update_id), 0) AS last FROM %s) AS t
+				WHERE last >= (SELECT last_value FROM %s)
+			`, table, sequence), sequence); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
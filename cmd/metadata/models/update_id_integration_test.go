@@ -0,0 +1,58 @@
+package models
+
+import (
+	"context"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIntegration_UpdateIDs_commitOrder(t *testing.T) {
+	db := newTestDatabase(t)
+	ctx := context.Background()
+
+	newToken := func(id int64) *TokenMetadata {
+		return &TokenMetadata{
+			Network:  testJobsNetwork,
+			Contract: "KT1",
+			TokenID:  decimal.NewFromInt(id),
+			Status:   StatusNew,
+		}
+	}
+	first, second := newToken(0), newToken(1)
+
+	saved := make(chan struct{})
+	commit := make(chan struct{})
+	done := make(chan error, 1)
+	go func() {
+		done <- db.Transactions.Run(ctx, func(ctx context.Context) error {
+			if err := db.Tokens.Save(ctx, []*TokenMetadata{first}); err != nil {
+				return err
+			}
+			close(saved)
+			<-commit
+			return nil
+		})
+	}()
+	<-saved
+
+	// writers aren't serialized
+	require.NoError(t, db.Tokens.Save(ctx, []*TokenMetadata{second}))
+	assert.Greater(t, second.UpdateID, first.UpdateID)
+
+	// committed row is hidden till transaction with lower update id is finished
+	tokens, err := db.TokensAfter(ctx, testJobsNetwork, "", 0, 10)
+	require.NoError(t, err)
+	assert.Empty(t, tokens)
+
+	close(commit)
+	require.NoError(t, <-done)
+
+	tokens, err = db.TokensAfter(ctx, testJobsNetwork, "", 0, 10)
+	require.NoError(t, err)
+	require.Len(t, tokens, 2)
+	assert.Equal(t, first.UpdateID, tokens[0].UpdateID)
+	assert.Equal(t, second.UpdateID, tokens[1].UpdateID)
+}
@@ -0,0 +1,45 @@
+package models
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dipdup-net/metadata/cmd/metadata/helpers"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNextUpdateID(t *testing.T) {
+	tests := []struct {
+		name     string
+		reserved []int64
+		want     []int64
+	}{
+		{
+			name: "counter without reserved ids",
+			want: []int64{11, 12},
+		}, {
+			name:     "reserved ids",
+			reserved: []int64{100, 205},
+			want:     []int64{100, 205},
+		}, {
+			name:     "counter after reserved ids",
+			reserved: []int64{100},
+			want:     []int64{100, 11},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.reserved != nil {
+				ctx = context.WithValue(ctx, updateIDsKey{}, &updateIDs{values: tt.reserved})
+			}
+			counter := helpers.NewCounter(10)
+
+			got := make([]int64, len(tt.want))
+			for i := range got {
+				got[i] = nextUpdateID(ctx, counter)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"github.com/dipdup-net/go-lib/database"
+)
+
+// webhook delivery statuses
+const (
+	WebhookStatusPending = "pending"
+	WebhookStatusFailed  = "failed"
+)
+
+// WebhookDelivery - outbox record of webhook notification. Delivered records are removed.
+type WebhookDelivery struct {
+	//nolint
+	tableName struct{} `pg:"webhook_outbox"`
+
+	ID            int64  `json:"-"`
+	CreatedAt     int64  `json:"created_at" pg:",use_zero"`
+	UpdatedAt     int64  `json:"updated_at" pg:",use_zero"`
+	Network       string `json:"network" pg:",notnull"`
+	Endpoint      string `json:"endpoint" pg:",notnull"`
+	Event         string `json:"event" pg:",notnull"`
+	Payload       JSONB  `json:"payload" pg:",type:jsonb,use_zero"`
+	Status        string `json:"status" pg:",notnull"`
+	Attempts      int    `json:"attempts" pg:",use_zero"`
+	NextAttemptAt int64  `json:"next_attempt_at" pg:",use_zero"`
+	Error         string `json:"error,omitempty"`
+}
+
+// BeforeInsert -
+func (wd *WebhookDelivery) BeforeInsert(ctx context.Context) (context.Context, error) {
+	wd.UpdatedAt = time.Now().Unix()
+	wd.CreatedAt = wd.UpdatedAt
+	return ctx, nil
+}
+
+// BeforeUpdate -
+func (wd *WebhookDelivery) BeforeUpdate(ctx context.Context) (context.Context, error) {
+	wd.UpdatedAt = time.Now().Unix()
+	return ctx, nil
+}
+
+// Webhooks -
+type Webhooks struct {
+	db *database.PgGo
+}
+
+// NewWebhooks -
+func NewWebhooks(db *database.PgGo) *Webhooks {
+	return &Webhooks{db: db}
+}
+
+// Add -
+func (webhooks *Webhooks) Add(ctx context.Context, deliveries []*WebhookDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+	_, err := conn(ctx, webhooks.db).ModelContext(ctx, &deliveries).Insert()
+	return err
+}
+
+// Pending - returns pending deliveries which next attempt time has come
+func (webhooks *Webhooks) Pending(ctx context.Context, network string, now int64, limit int) (deliveries []WebhookDelivery, err error) {
+	err = webhooks.db.DB().ModelContext(ctx, &deliveries).
+		Where("network = ?", network).
+		Where("status = ?", WebhookStatusPending).
+		Where("next_attempt_at <= ?", now).
+		Order("id asc").
+		Limit(limit).
+		Select()
+	return
+}
+
+// Update -
+func (webhooks *Webhooks) Update(ctx context.Context, delivery *WebhookDelivery) error {
+	_, err := webhooks.db.DB().ModelContext(ctx, delivery).
+		Column("updated_at", "status", "attempts", "next_attempt_at", "error").
+		WherePK().
+		Update()
+	return err
+}
+
+// Delete -
+func (webhooks *Webhooks) Delete(ctx context.Context, id int64) error {
+	_, err := webhooks.db.DB().ModelContext(ctx, (*WebhookDelivery)(nil)).Where("id = ?", id).Delete()
+	return err
+}
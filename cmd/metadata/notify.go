@@ -0,0 +1,18 @@
+package main
+
+import (
+	"context"
+
+	"github.com/dipdup-net/metadata/cmd/metadata/models"
+	"github.com/pkg/errors"
+)
+
+// notifyContracts - adds webhook notifications about saved contract metadata to outbox. It's called in the transaction which saves metadata, so notifications are written only with it.
+func (indexer *Indexer) notifyContracts(ctx context.Context, contracts []*models.ContractMetadata) error {
+	return errors.Wrap(indexer.webhooks.Contracts(ctx, contracts), "add contract webhooks")
+}
+
+// notifyTokens - adds webhook notifications about saved token metadata to outbox. It's called in the transaction which saves metadata, so notifications are written only with it.
+func (indexer *Indexer) notifyTokens(ctx context.Context, tokens []*models.TokenMetadata) error {
+	return errors.Wrap(indexer.webhooks.Tokens(ctx, tokens), "add token webhooks")
+}
@@ -0,0 +1,9 @@
+package offchainviews
+
+import "errors"
+
+// Errors
+var (
+	ErrInvalidViewResult = errors.New("invalid view result")
+	ErrInvalidScript     = errors.New("invalid contract script")
+)
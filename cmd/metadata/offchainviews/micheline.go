@@ -0,0 +1,102 @@
+package offchainviews
+
+import (
+	"bytes"
+	stdJSON "encoding/json"
+
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+)
+
+type node struct {
+	Prim   string               `json:"prim,omitempty"`
+	Args   []stdJSON.RawMessage `json:"args,omitempty"`
+	Int    *string              `json:"int,omitempty"`
+	String *string              `json:"string,omitempty"`
+	Bytes  *string              `json:"bytes,omitempty"`
+}
+
+func isSequence(data stdJSON.RawMessage) bool {
+	return bytes.HasPrefix(bytes.TrimSpace(data), []byte("["))
+}
+
+// parseTokenMetadata - parses `Some (Pair nat (map string bytes))` which is returned by token_metadata view wrapper
+func parseTokenMetadata(data stdJSON.RawMessage) (Token, error) {
+	var token Token
+
+	var some node
+	if err := stdJSON.Unmarshal(data, &some); err != nil {
+		return token, err
+	}
+	if some.Prim != "Some" || len(some.Args) != 1 {
+		return token, errors.Wrapf(ErrInvalidViewResult, "expected Some, got %s", string(data))
+	}
+
+	pair, err := pairArgs(some.Args[0])
+	if err != nil {
+		return token, err
+	}
+
+	var id node
+	if err := stdJSON.Unmarshal(pair[0], &id); err != nil {
+		return token, err
+	}
+	if id.Int == nil {
+		return token, errors.Wrap(ErrInvalidViewResult, "token_id is not an integer")
+	}
+	token.TokenID, err = decimal.NewFromString(*id.Int)
+	if err != nil {
+		return token, err
+	}
+
+	var elts []node
+	if err := stdJSON.Unmarshal(pair[1], &elts); err != nil {
+		return token, errors.Wrap(ErrInvalidViewResult, "token_info is not a map")
+	}
+
+	token.Info = make(map[string]string, len(elts))
+	for i := range elts {
+		if elts[i].Prim != "Elt" || len(elts[i].Args) != 2 {
+			return token, errors.Wrap(ErrInvalidViewResult, "invalid token_info element")
+		}
+		var key, value node
+		if err := stdJSON.Unmarshal(elts[i].Args[0], &key); err != nil {
+			return token, err
+		}
+		if err := stdJSON.Unmarshal(elts[i].Args[1], &value); err != nil {
+			return token, err
+		}
+		if key.String == nil || value.Bytes == nil {
+			return token, errors.Wrap(ErrInvalidViewResult, "token_info must be map string bytes")
+		}
+		token.Info[*key.String] = *value.Bytes
+	}
+
+	return token, nil
+}
+
+func pairArgs(data stdJSON.RawMessage) ([]stdJSON.RawMessage, error) {
+	if isSequence(data) {
+		var args []stdJSON.RawMessage
+		if err := stdJSON.Unmarshal(data, &args); err != nil {
+			return nil, err
+		}
+		if len(args) == 2 {
+			var first node
+			// sequence of Elt is a map, not a pair
+			if err := stdJSON.Unmarshal(args[0], &first); err == nil && first.Prim != "Elt" {
+				return args, nil
+			}
+		}
+		return nil, errors.Wrap(ErrInvalidViewResult, "expected pair")
+	}
+
+	var pair node
+	if err := stdJSON.Unmarshal(data, &pair); err != nil {
+		return nil, err
+	}
+	if pair.Prim != "Pair" || len(pair.Args) != 2 {
+		return nil, errors.Wrap(ErrInvalidViewResult, "expected pair")
+	}
+	return pair.Args, nil
+}
@@ -0,0 +1,247 @@
+package offchainviews
+
+import (
+	"bytes"
+	"context"
+	stdJSON "encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Runner - executes michelson storage view of the contract with the parameter and returns result in Micheline
+type Runner interface {
+	Run(ctx context.Context, contract string, view MichelsonStorageView, parameter stdJSON.RawMessage) (stdJSON.RawMessage, error)
+}
+
+// RPC - runner which executes views via `run_code` endpoint of Tezos node
+type RPC struct {
+	baseURL string
+	client  *http.Client
+
+	chainID   string
+	scripts   map[string]cachedScript
+	scriptTTL time.Duration
+	mx        sync.Mutex
+}
+
+// cachedScript - storage type and storage of the contract. Storage is changed by operations, so it's refetched after TTL.
+type cachedScript struct {
+	storageType stdJSON.RawMessage
+	storage     stdJSON.RawMessage
+	expiresAt   time.Time
+}
+
+// RPCOption -
+type RPCOption func(*RPC)
+
+// WithScriptTTL - time while contract script is reused by views of its tokens. Default: 30 seconds.
+func WithScriptTTL(ttl time.Duration) RPCOption {
+	return func(rpc *RPC) {
+		if ttl > 0 {
+			rpc.scriptTTL = ttl
+		}
+	}
+}
+
+// NewRPC -
+func NewRPC(baseURL string, timeout time.Duration, opts ...RPCOption) *RPC {
+	rpc := &RPC{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		client: &http.Client{
+			Timeout: timeout,
+		},
+		scripts:   make(map[string]cachedScript),
+		scriptTTL: 30 * time.Second,
+	}
+
+	for i := range opts {
+		opts[i](rpc)
+	}
+
+	return rpc
+}
+
+type script struct {
+	Code    []stdJSON.RawMessage `json:"code"`
+	Storage stdJSON.RawMessage   `json:"storage"`
+}
+
+type runCodeRequest struct {
+	Script  []stdJSON.RawMessage `json:"script"`
+	Storage stdJSON.RawMessage   `json:"storage"`
+	Input   stdJSON.RawMessage   `json:"input"`
+	Amount  string               `json:"amount"`
+	ChainID string               `json:"chain_id"`
+	Self    string               `json:"self,omitempty"`
+}
+
+type runCodeResponse struct {
+	Storage stdJSON.RawMessage `json:"storage"`
+}
+
+// Run - wraps view code into the script `parameter (pair param storage); storage (option return_type); code { CAR; view; SOME; NIL operation; PAIR }` and runs it with current contract storage
+func (rpc *RPC) Run(ctx context.Context, contract string, view MichelsonStorageView, parameter stdJSON.RawMessage) (stdJSON.RawMessage, error) {
+	chainID, err := rpc.getChainID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	contractScript, err := rpc.getScript(ctx, contract)
+	if err != nil {
+		return nil, err
+	}
+
+	request, err := wrapView(view, contractScript.storageType, parameter, contractScript.storage)
+	if err != nil {
+		return nil, err
+	}
+	request.ChainID = chainID
+	request.Self = contract
+
+	var response runCodeResponse
+	if err := rpc.post(ctx, "chains/main/blocks/head/helpers/scripts/run_code", request, &response); err != nil {
+		return nil, err
+	}
+	return response.Storage, nil
+}
+
+func (rpc *RPC) getChainID(ctx context.Context) (string, error) {
+	rpc.mx.Lock()
+	defer rpc.mx.Unlock()
+
+	if rpc.chainID != "" {
+		return rpc.chainID, nil
+	}
+
+	if err := rpc.get(ctx, "chains/main/chain_id", &rpc.chainID); err != nil {
+		return "", err
+	}
+	return rpc.chainID, nil
+}
+
+// getScript - returns cached script of the contract, so views of all its tokens don't request it
+func (rpc *RPC) getScript(ctx context.Context, contract string) (cachedScript, error) {
+	now := time.Now()
+
+	rpc.mx.Lock()
+	cached, ok := rpc.scripts[contract]
+	rpc.mx.Unlock()
+	if ok && now.Before(cached.expiresAt) {
+		return cached, nil
+	}
+
+	var contractScript script
+	if err := rpc.get(ctx, fmt.Sprintf("chains/main/blocks/head/context/contracts/%s/script", contract), &contractScript); err != nil {
+		return cached, err
+	}
+
+	storageType, err := storageType(contractScript.Code)
+	if err != nil {
+		return cached, err
+	}
+
+	cached = cachedScript{
+		storageType: storageType,
+		storage:     contractScript.Storage,
+		expiresAt:   now.Add(rpc.scriptTTL),
+	}
+
+	rpc.mx.Lock()
+	defer rpc.mx.Unlock()
+
+	// scripts of contracts which aren't executed anymore are dropped
+	for address, item := range rpc.scripts {
+		if !now.Before(item.expiresAt) {
+			delete(rpc.scripts, address)
+		}
+	}
+	rpc.scripts[contract] = cached
+	return cached, nil
+}
+
+func (rpc *RPC) get(ctx context.Context, path string, output any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rpc.url(path), nil)
+	if err != nil {
+		return err
+	}
+	return rpc.do(req, output)
+}
+
+func (rpc *RPC) post(ctx context.Context, path string, body, output any) error {
+	data, err := stdJSON.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, rpc.url(path), bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return rpc.do(req, output)
+}
+
+func (rpc *RPC) url(path string) string {
+	u, err := url.JoinPath(rpc.baseURL, path)
+	if err != nil {
+		return rpc.baseURL + "/" + path
+	}
+	return u
+}
+
+func (rpc *RPC) do(req *http.Request, output any) error {
+	resp, err := rpc.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return errors.Errorf("%s %s: %s %s", req.Method, req.URL.Path, resp.Status, string(data))
+	}
+
+	return stdJSON.NewDecoder(resp.Body).Decode(output)
+}
+
+func storageType(code []stdJSON.RawMessage) (stdJSON.RawMessage, error) {
+	for i := range code {
+		var section node
+		if err := stdJSON.Unmarshal(code[i], &section); err != nil {
+			return nil, err
+		}
+		if section.Prim == "storage" && len(section.Args) == 1 {
+			return section.Args[0], nil
+		}
+	}
+	return nil, errors.Wrap(ErrInvalidScript, "storage section is not found")
+}
+
+func wrapView(view MichelsonStorageView, storageType, parameter, storage stdJSON.RawMessage) (runCodeRequest, error) {
+	paramType := view.Parameter
+	if len(paramType) == 0 {
+		paramType = stdJSON.RawMessage(`{"prim":"unit"}`)
+		parameter = stdJSON.RawMessage(`{"prim":"Unit"}`)
+	}
+
+	script := fmt.Sprintf(
+		`[{"prim":"parameter","args":[{"prim":"pair","args":[%s,%s]}]},{"prim":"storage","args":[{"prim":"option","args":[%s]}]},{"prim":"code","args":[[{"prim":"CAR"},%s,{"prim":"SOME"},{"prim":"NIL","args":[{"prim":"operation"}]},{"prim":"PAIR"}]]}]`,
+		paramType, storageType, view.ReturnType, view.Code,
+	)
+
+	request := runCodeRequest{
+		Storage: stdJSON.RawMessage(`{"prim":"None"}`),
+		Input:   stdJSON.RawMessage(fmt.Sprintf(`{"prim":"Pair","args":[%s,%s]}`, parameter, storage)),
+		Amount:  "0",
+	}
+	if err := stdJSON.Unmarshal([]byte(script), &request.Script); err != nil {
+		return request, errors.Wrap(ErrInvalidScript, err.Error())
+	}
+	return request, nil
+}
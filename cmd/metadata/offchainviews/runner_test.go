@@ -0,0 +1,61 @@
+package offchainviews
+
+import (
+	"context"
+	stdJSON "encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRPC_Run(t *testing.T) {
+	var (
+		request       runCodeRequest
+		scriptQueries int
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/chains/main/chain_id":
+			_, _ = w.Write([]byte(`"NetXdQprcVkpaWU"`))
+		case "/chains/main/blocks/head/context/contracts/KT1/script":
+			scriptQueries++
+			_, _ = w.Write([]byte(`{"code":[{"prim":"parameter","args":[{"prim":"unit"}]},{"prim":"storage","args":[{"prim":"big_map","args":[{"prim":"nat"},{"prim":"bytes"}]}]},{"prim":"code","args":[[]]}],"storage":{"int":"42"}}`))
+		case "/chains/main/blocks/head/helpers/scripts/run_code":
+			body, err := io.ReadAll(r.Body)
+			require.NoError(t, err)
+			require.NoError(t, stdJSON.Unmarshal(body, &request))
+			_, _ = w.Write([]byte(`{"storage":{"prim":"Some","args":[{"prim":"Pair","args":[{"int":"5"},[]]}]},"operations":[]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	view, ok := FindView([]byte(testContractMetadata), ViewTokenMetadata)
+	require.True(t, ok)
+
+	rpc := NewRPC(server.URL, time.Second)
+	result, err := rpc.Run(context.Background(), "KT1", view, stdJSON.RawMessage(`{"int":"5"}`))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"prim":"Some","args":[{"prim":"Pair","args":[{"int":"5"},[]]}]}`, string(result))
+
+	// script is cached for views of other tokens
+	_, err = rpc.Run(context.Background(), "KT1", view, stdJSON.RawMessage(`{"int":"5"}`))
+	require.NoError(t, err)
+	assert.Equal(t, 1, scriptQueries)
+
+	assert.Equal(t, "NetXdQprcVkpaWU", request.ChainID)
+	assert.Equal(t, "KT1", request.Self)
+	assert.Equal(t, "0", request.Amount)
+	assert.JSONEq(t, `{"prim":"None"}`, string(request.Storage))
+	assert.JSONEq(t, `{"prim":"Pair","args":[{"int":"5"},{"int":"42"}]}`, string(request.Input))
+	require.Len(t, request.Script, 3)
+	assert.JSONEq(t, `{"prim":"parameter","args":[{"prim":"pair","args":[{"prim":"nat"},{"prim":"big_map","args":[{"prim":"nat"},{"prim":"bytes"}]}]}]}`, string(request.Script[0]))
+	assert.JSONEq(t, `{"prim":"code","args":[[{"prim":"CAR"},[{"prim":"UNPAIR"},{"prim":"DROP"}],{"prim":"SOME"},{"prim":"NIL","args":[{"prim":"operation"}]},{"prim":"PAIR"}]]}`, string(request.Script[2]))
+}
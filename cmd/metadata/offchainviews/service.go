@@ -0,0 +1,284 @@
+package offchainviews
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"github.com/shopspring/decimal"
+)
+
+// Token - result of token_metadata view. Values of `Info` are hex-encoded bytes.
+type Token struct {
+	TokenID decimal.Decimal
+	Info    map[string]string
+}
+
+// Handler - receives tokens of the contract produced by token_metadata view. `level` and `timestamp` are of indexer head when the view was scheduled.
+type Handler func(ctx context.Context, contract string, level uint64, timestamp time.Time, tokens []Token) error
+
+type task struct {
+	contract  string
+	version   uint64
+	view      MichelsonStorageView
+	after     uint64
+	level     uint64
+	timestamp time.Time
+}
+
+// contract - contract with token_metadata view. Tokens which appeared at `seen` level or below are processed already.
+type contract struct {
+	// version is changed by every `Add`, results of older versions are dropped
+	version   uint64
+	view      MichelsonStorageView
+	seen      uint64
+	bigMap    bool
+	queued    bool
+	checkedAt time.Time
+
+	// head of indexer when contract was scheduled
+	level     uint64
+	timestamp time.Time
+}
+
+// Service - executes token_metadata off-chain views of contracts. Contracts are scheduled by `Add` when their metadata is committed and by `Refresh` on new blocks to process minted tokens.
+type Service struct {
+	runner  Runner
+	source  Source
+	handler Handler
+
+	workersCount    int
+	refreshInterval time.Duration
+
+	contracts map[string]*contract
+	queue     []string
+	wake      chan struct{}
+	mx        sync.Mutex
+	wg        *sync.WaitGroup
+}
+
+// ServiceOption -
+type ServiceOption func(*Service)
+
+// WithWorkers -
+func WithWorkers(workersCount int) ServiceOption {
+	return func(s *Service) {
+		if workersCount > 0 {
+			s.workersCount = workersCount
+		}
+	}
+}
+
+// WithRefreshInterval - minimal interval between checks of new tokens of one contract. Default: 1 minute.
+func WithRefreshInterval(interval time.Duration) ServiceOption {
+	return func(s *Service) {
+		if interval > 0 {
+			s.refreshInterval = interval
+		}
+	}
+}
+
+// New -
+func New(runner Runner, source Source, handler Handler, opts ...ServiceOption) *Service {
+	service := &Service{
+		runner:          runner,
+		source:          source,
+		handler:         handler,
+		workersCount:    2,
+		refreshInterval: time.Minute,
+		contracts:       make(map[string]*contract),
+		queue:           make([]string, 0),
+		wake:            make(chan struct{}, 1),
+		wg:              new(sync.WaitGroup),
+	}
+
+	for i := range opts {
+		opts[i](service)
+	}
+
+	return service
+}
+
+// Start -
+func (s *Service) Start(ctx context.Context) {
+	for i := 0; i < s.workersCount; i++ {
+		s.wg.Add(1)
+		go s.worker(ctx)
+	}
+}
+
+// Close - waits till workers are stopped by context
+func (s *Service) Close() error {
+	s.wg.Wait()
+	return nil
+}
+
+// Add - schedules execution of token_metadata view for all tokens of the contract if view is declared in committed contract metadata. Returns true if view was found.
+// Contract without the view is forgotten. Results are bound to `level` and `timestamp` of indexer head. It doesn't block.
+func (s *Service) Add(address string, metadata []byte, level uint64, timestamp time.Time) bool {
+	view, ok := FindView(metadata, ViewTokenMetadata)
+
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	if !ok {
+		delete(s.contracts, address)
+		return false
+	}
+
+	// metadata may be changed, so all tokens are processed again
+	c, exists := s.contracts[address]
+	if !exists {
+		c = new(contract)
+		s.contracts[address] = c
+	}
+	c.version++
+	c.view = view
+	c.seen = 0
+	c.bigMap = false
+	c.checkedAt = time.Time{}
+	s.schedule(address, c, level, timestamp)
+	return true
+}
+
+// Refresh - schedules processing of tokens which appeared since the last check of contracts. Contracts are checked not more often than refresh interval.
+func (s *Service) Refresh(level uint64, timestamp time.Time) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	now := time.Now()
+	for address, c := range s.contracts {
+		if c.bigMap || now.Sub(c.checkedAt) < s.refreshInterval {
+			continue
+		}
+		s.schedule(address, c, level, timestamp)
+	}
+}
+
+func (s *Service) schedule(address string, c *contract, level uint64, timestamp time.Time) {
+	c.level = level
+	c.timestamp = timestamp
+	if c.queued {
+		return
+	}
+	c.queued = true
+	s.queue = append(s.queue, address)
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (s *Service) next() (task, bool) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	for len(s.queue) > 0 {
+		address := s.queue[0]
+		s.queue = s.queue[1:]
+
+		c, ok := s.contracts[address]
+		if !ok {
+			continue
+		}
+		c.queued = false
+		c.checkedAt = time.Now()
+
+		// other workers are woken up while queue isn't empty
+		if len(s.queue) > 0 {
+			select {
+			case s.wake <- struct{}{}:
+			default:
+			}
+		}
+		return task{address, c.version, c.view, c.seen, c.level, c.timestamp}, true
+	}
+	return task{}, false
+}
+
+// done - marks tokens till task level as processed if contract isn't changed meanwhile
+func (s *Service) done(t task, bigMap bool) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	c, ok := s.contracts[t.contract]
+	if !ok || c.version != t.version {
+		return
+	}
+	c.bigMap = bigMap
+	if t.level > c.seen {
+		c.seen = t.level
+	}
+}
+
+func (s *Service) worker(ctx context.Context) {
+	defer s.wg.Done()
+
+	for {
+		t, ok := s.next()
+		if !ok {
+			select {
+			case <-ctx.Done():
+				return
+			case <-s.wake:
+				continue
+			}
+		}
+
+		bigMap, err := s.work(ctx, t)
+		if err != nil {
+			if !errors.Is(err, context.Canceled) {
+				log.Err(err).Str("contract", t.contract).Msg("token_metadata view")
+			}
+			continue
+		}
+		s.done(t, bigMap)
+	}
+}
+
+func (s *Service) work(ctx context.Context, t task) (bool, error) {
+	hasBigMap, err := s.source.HasTokenMetadataBigMap(ctx, t.contract)
+	if err != nil {
+		return false, errors.Wrap(err, "HasTokenMetadataBigMap")
+	}
+	if hasBigMap {
+		return true, nil
+	}
+
+	ids, err := s.source.TokenIDs(ctx, t.contract, t.after)
+	if err != nil {
+		return false, errors.Wrap(err, "TokenIDs")
+	}
+
+	tokens := make([]Token, 0, len(ids))
+	for i := range ids {
+		select {
+		case <-ctx.Done():
+			return false, ctx.Err()
+		default:
+		}
+
+		parameter := []byte(fmt.Sprintf(`{"int":"%s"}`, ids[i].String()))
+		result, err := s.runner.Run(ctx, t.contract, t.view, parameter)
+		if err != nil {
+			log.Warn().Err(err).Str("contract", t.contract).Str("token_id", ids[i].String()).Msg("run token_metadata view")
+			continue
+		}
+
+		token, err := parseTokenMetadata(result)
+		if err != nil {
+			log.Warn().Err(err).Str("contract", t.contract).Str("token_id", ids[i].String()).Msg("parse token_metadata view result")
+			continue
+		}
+		tokens = append(tokens, token)
+	}
+
+	if len(tokens) == 0 {
+		return false, nil
+	}
+	return false, s.handler(ctx, t.contract, t.level, t.timestamp, tokens)
+}
@@ -0,0 +1,320 @@
+package offchainviews
+
+import (
+	"context"
+	stdJSON "encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testContractMetadata = `{
+	"name": "test",
+	"views": [
+		{
+			"name": "get_balance",
+			"implementations": [{"michelsonStorageView": {"returnType": {"prim": "nat"}, "code": [{"prim": "CDR"}]}}]
+		},
+		{
+			"name": "token_metadata",
+			"implementations": [
+				{"restApiQuery": {"specificationUri": "https://example.com", "path": "/token_metadata"}},
+				{"michelsonStorageView": {
+					"parameter": {"prim": "nat"},
+					"returnType": {"prim": "pair", "args": [{"prim": "nat"}, {"prim": "map", "args": [{"prim": "string"}, {"prim": "bytes"}]}]},
+					"code": [{"prim": "UNPAIR"}, {"prim": "DROP"}]
+				}}
+			]
+		}
+	]
+}`
+
+func TestFindView(t *testing.T) {
+	tests := []struct {
+		name     string
+		metadata string
+		wantOk   bool
+	}{
+		{
+			name:     "michelson storage view",
+			metadata: testContractMetadata,
+			wantOk:   true,
+		}, {
+			name:     "without views",
+			metadata: `{"name":"test"}`,
+		}, {
+			name:     "rest api only",
+			metadata: `{"views":[{"name":"token_metadata","implementations":[{"restApiQuery":{"path":"/"}}]}]}`,
+		}, {
+			name:     "invalid json",
+			metadata: `{`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			view, ok := FindView([]byte(tt.metadata), ViewTokenMetadata)
+			assert.Equal(t, tt.wantOk, ok)
+			if tt.wantOk {
+				assert.JSONEq(t, `{"prim":"nat"}`, string(view.Parameter))
+				assert.JSONEq(t, `[{"prim":"UNPAIR"},{"prim":"DROP"}]`, string(view.Code))
+			}
+		})
+	}
+}
+
+func Test_parseTokenMetadata(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    Token
+		wantErr bool
+	}{
+		{
+			name: "pair",
+			data: `{"prim":"Some","args":[{"prim":"Pair","args":[{"int":"7"},[{"prim":"Elt","args":[{"string":""},{"bytes":"697066733a2f2f516d"}]},{"prim":"Elt","args":[{"string":"decimals"},{"bytes":"30"}]}]]}]}`,
+			want: Token{
+				TokenID: decimal.NewFromInt(7),
+				Info: map[string]string{
+					"":         "697066733a2f2f516d",
+					"decimals": "30",
+				},
+			},
+		}, {
+			name: "pair as sequence",
+			data: `{"prim":"Some","args":[[{"int":"1"},[{"prim":"Elt","args":[{"string":"name"},{"bytes":"74657374"}]}]]]}`,
+			want: Token{
+				TokenID: decimal.NewFromInt(1),
+				Info: map[string]string{
+					"name": "74657374",
+				},
+			},
+		}, {
+			name: "empty map",
+			data: `{"prim":"Some","args":[{"prim":"Pair","args":[{"int":"1"},[]]}]}`,
+			want: Token{
+				TokenID: decimal.NewFromInt(1),
+				Info:    map[string]string{},
+			},
+		}, {
+			name:    "none",
+			data:    `{"prim":"None"}`,
+			wantErr: true,
+		}, {
+			name:    "invalid value type",
+			data:    `{"prim":"Some","args":[{"prim":"Pair","args":[{"int":"1"},[{"prim":"Elt","args":[{"string":"name"},{"int":"1"}]}]]}]}`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseTokenMetadata(stdJSON.RawMessage(tt.data))
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.True(t, tt.want.TokenID.Equal(got.TokenID))
+			assert.Equal(t, tt.want.Info, got.Info)
+		})
+	}
+}
+
+type stubRunner struct{}
+
+func (stubRunner) Run(ctx context.Context, contract string, view MichelsonStorageView, parameter stdJSON.RawMessage) (stdJSON.RawMessage, error) {
+	var id node
+	if err := stdJSON.Unmarshal(parameter, &id); err != nil {
+		return nil, err
+	}
+	if *id.Int == "2" {
+		return nil, errors.New("script failed")
+	}
+	return stdJSON.RawMessage(`{"prim":"Some","args":[{"prim":"Pair","args":[{"int":"` + *id.Int + `"},[{"prim":"Elt","args":[{"string":"name"},{"bytes":"74657374"}]}]]}]}`), nil
+}
+
+type stubSource struct {
+	bigMap bool
+
+	mx sync.Mutex
+	// first levels of tokens by id
+	levels map[int64]uint64
+}
+
+func newStubSource(bigMap bool) *stubSource {
+	return &stubSource{
+		bigMap: bigMap,
+		levels: map[int64]uint64{1: 10, 2: 20, 3: 30},
+	}
+}
+
+func (s *stubSource) HasTokenMetadataBigMap(ctx context.Context, contract string) (bool, error) {
+	return s.bigMap, nil
+}
+
+func (s *stubSource) TokenIDs(ctx context.Context, contract string, afterLevel uint64) ([]decimal.Decimal, error) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	ids := make([]decimal.Decimal, 0)
+	for id := int64(1); id <= int64(len(s.levels)); id++ {
+		if s.levels[id] > afterLevel {
+			ids = append(ids, decimal.NewFromInt(id))
+		}
+	}
+	return ids, nil
+}
+
+func (s *stubSource) mint(id int64, level uint64) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	s.levels[id] = level
+}
+
+type receiver struct {
+	mx     sync.Mutex
+	tokens []Token
+	levels []uint64
+}
+
+func (r *receiver) handle(ctx context.Context, contract string, level uint64, ts time.Time, tokens []Token) error {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+
+	r.tokens = append(r.tokens, tokens...)
+	for range tokens {
+		r.levels = append(r.levels, level)
+	}
+	return nil
+}
+
+func (r *receiver) count() int {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+
+	return len(r.tokens)
+}
+
+func TestService(t *testing.T) {
+	tests := []struct {
+		name     string
+		bigMap   bool
+		metadata string
+		wantIDs  []int64
+	}{
+		{
+			name:     "off-chain view",
+			metadata: testContractMetadata,
+			wantIDs:  []int64{1, 3},
+		}, {
+			name:     "contract with token_metadata big map",
+			bigMap:   true,
+			metadata: testContractMetadata,
+		}, {
+			name:     "contract without view",
+			metadata: `{"name":"test"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				mx       sync.Mutex
+				received []Token
+			)
+			timestamp := time.Unix(1700000000, 0).UTC()
+			handler := func(ctx context.Context, contract string, level uint64, ts time.Time, tokens []Token) error {
+				mx.Lock()
+				defer mx.Unlock()
+				assert.Equal(t, "KT1", contract)
+				assert.EqualValues(t, 100, level)
+				assert.Equal(t, timestamp, ts)
+				received = append(received, tokens...)
+				return nil
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			service := New(stubRunner{}, newStubSource(tt.bigMap), handler, WithWorkers(1))
+			service.Start(ctx)
+
+			added := service.Add("KT1", []byte(tt.metadata), 100, timestamp)
+			assert.Equal(t, tt.metadata == testContractMetadata, added)
+
+			if len(tt.wantIDs) > 0 {
+				require.Eventually(t, func() bool {
+					mx.Lock()
+					defer mx.Unlock()
+					return len(received) == len(tt.wantIDs)
+				}, 5*time.Second, 10*time.Millisecond)
+			} else {
+				time.Sleep(50 * time.Millisecond)
+			}
+
+			cancel()
+			require.NoError(t, service.Close())
+
+			require.Len(t, received, len(tt.wantIDs))
+			for i := range tt.wantIDs {
+				assert.True(t, decimal.NewFromInt(tt.wantIDs[i]).Equal(received[i].TokenID))
+				assert.Equal(t, map[string]string{"name": "74657374"}, received[i].Info)
+			}
+		})
+	}
+}
+
+func TestService_Refresh(t *testing.T) {
+	source := newStubSource(false)
+	r := new(receiver)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	service := New(stubRunner{}, source, r.handle, WithWorkers(1), WithRefreshInterval(time.Millisecond))
+	service.Start(ctx)
+
+	require.True(t, service.Add("KT1", []byte(testContractMetadata), 100, time.Now()))
+	require.Eventually(t, func() bool { return r.count() == 2 }, 5*time.Second, 10*time.Millisecond)
+
+	// tokens minted later are processed on refresh, processed ones aren't executed again
+	source.mint(4, 150)
+	require.Eventually(t, func() bool {
+		service.Refresh(200, time.Now())
+		return r.count() == 3
+	}, 5*time.Second, 10*time.Millisecond)
+
+	cancel()
+	require.NoError(t, service.Close())
+
+	assert.True(t, decimal.NewFromInt(4).Equal(r.tokens[2].TokenID))
+	assert.EqualValues(t, 200, r.levels[2])
+}
+
+func TestService_Add(t *testing.T) {
+	service := New(stubRunner{}, newStubSource(false), new(receiver).handle)
+
+	// scheduling doesn't wait for workers
+	for i := 0; i < 1000; i++ {
+		assert.True(t, service.Add(fmt.Sprintf("KT%d", i), []byte(testContractMetadata), 100, time.Now()))
+	}
+	assert.Len(t, service.queue, 1000)
+
+	// repeated scheduling of queued contract doesn't duplicate it, contract without view is forgotten
+	assert.True(t, service.Add("KT0", []byte(testContractMetadata), 101, time.Now()))
+	assert.False(t, service.Add("KT1", []byte(`{"name":"test"}`), 101, time.Now()))
+	assert.Len(t, service.queue, 1000)
+	assert.Len(t, service.contracts, 999)
+
+	task, ok := service.next()
+	require.True(t, ok)
+	assert.Equal(t, "KT0", task.contract)
+	assert.EqualValues(t, 101, task.level)
+
+	task, ok = service.next()
+	require.True(t, ok)
+	assert.Equal(t, "KT2", task.contract)
+}
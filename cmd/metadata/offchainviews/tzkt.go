@@ -0,0 +1,78 @@
+package offchainviews
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/dipdup-net/go-lib/tzkt/api"
+	"github.com/shopspring/decimal"
+)
+
+const pageSize = 1000
+
+// Source - source of tokens of the contract
+type Source interface {
+	HasTokenMetadataBigMap(ctx context.Context, contract string) (bool, error)
+	TokenIDs(ctx context.Context, contract string, afterLevel uint64) ([]decimal.Decimal, error)
+}
+
+// TzKT - tokens source based on TzKT API
+type TzKT struct {
+	api *api.API
+}
+
+// NewTzKT -
+func NewTzKT(api *api.API) TzKT {
+	return TzKT{api}
+}
+
+// HasTokenMetadataBigMap - returns true if contract stores token metadata in big map. In that case views are not executed.
+func (t TzKT) HasTokenMetadataBigMap(ctx context.Context, contract string) (bool, error) {
+	bigMaps, err := t.api.GetBigmaps(ctx, map[string]string{
+		"contract": contract,
+		"tags.any": "token_metadata",
+		"limit":    "1",
+	})
+	if err != nil {
+		return false, err
+	}
+	return len(bigMaps) > 0, nil
+}
+
+// TokenIDs - returns ids of tokens of the contract which were seen by TzKT first time above `afterLevel`
+func (t TzKT) TokenIDs(ctx context.Context, contract string, afterLevel uint64) ([]decimal.Decimal, error) {
+	ids := make([]decimal.Decimal, 0)
+
+	var lastID uint64
+	for {
+		filters := map[string]string{
+			"contract": contract,
+			"sort.asc": "id",
+			"limit":    strconv.Itoa(pageSize),
+		}
+		if afterLevel > 0 {
+			filters["firstLevel.gt"] = strconv.FormatUint(afterLevel, 10)
+		}
+		if lastID > 0 {
+			filters["offset.cr"] = strconv.FormatUint(lastID, 10)
+		}
+
+		tokens, err := t.api.GetTokens(ctx, filters)
+		if err != nil {
+			return nil, err
+		}
+
+		for i := range tokens {
+			id, err := decimal.NewFromString(tokens[i].TokenID)
+			if err != nil {
+				return nil, err
+			}
+			ids = append(ids, id)
+			lastID = tokens[i].ID
+		}
+
+		if len(tokens) < pageSize {
+			return ids, nil
+		}
+	}
+}
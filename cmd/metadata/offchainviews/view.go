@@ -0,0 +1,50 @@
+package offchainviews
+
+import (
+	stdJSON "encoding/json"
+)
+
+// view names
+const (
+	ViewTokenMetadata = "token_metadata"
+)
+
+// MichelsonStorageView - TZIP-16 off-chain view implementation which is executed against contract storage
+type MichelsonStorageView struct {
+	Parameter  stdJSON.RawMessage `json:"parameter,omitempty"`
+	ReturnType stdJSON.RawMessage `json:"returnType"`
+	Code       stdJSON.RawMessage `json:"code"`
+}
+
+type viewImplementation struct {
+	MichelsonStorageView *MichelsonStorageView `json:"michelsonStorageView,omitempty"`
+}
+
+type view struct {
+	Name            string               `json:"name"`
+	Implementations []viewImplementation `json:"implementations"`
+}
+
+type contractMetadata struct {
+	Views []view `json:"views"`
+}
+
+// FindView - returns michelson storage view with `name` declared in TZIP-16 contract metadata
+func FindView(metadata []byte, name string) (MichelsonStorageView, bool) {
+	var cm contractMetadata
+	if err := stdJSON.Unmarshal(metadata, &cm); err != nil {
+		return MichelsonStorageView{}, false
+	}
+
+	for i := range cm.Views {
+		if cm.Views[i].Name != name {
+			continue
+		}
+		for _, impl := range cm.Views[i].Implementations {
+			if impl.MichelsonStorageView != nil && len(impl.MichelsonStorageView.Code) > 0 {
+				return *impl.MichelsonStorageView, true
+			}
+		}
+	}
+	return MichelsonStorageView{}, false
+}
@@ -6,15 +6,23 @@ import (
 	golibConfig "github.com/dipdup-net/go-lib/config"
 	"github.com/dipdup-net/go-lib/prometheus"
 	"github.com/dipdup-net/metadata/cmd/metadata/resolver"
+	"github.com/dipdup-net/metadata/internal/ipfs"
 )
 
 // metric names
 const (
-	MetricMetadataCounter           = "metadata_counter"
-	MetricMetadataNew               = "metadata_new"
-	MetricsMetadataHttpErrors       = "metadata_http_errors"
-	MetricsMetadataIPFSResponseTime = "metadata_ipfs_response_time"
-	MetricsMetadataMimeType         = "metadata_mime_type"
+	MetricMetadataCounter            = "metadata_counter"
+	MetricMetadataNew                = "metadata_new"
+	MetricsMetadataHttpErrors        = "metadata_http_errors"
+	MetricsMetadataIPFSResponseTime  = "metadata_ipfs_response_time"
+	MetricsMetadataMimeType          = "metadata_mime_type"
+	MetricsMetadataWebhooks          = "metadata_webhook_deliveries"
+	MetricsMetadataValidation        = "metadata_validation_issues"
+	MetricsMetadataHTTPCircuits      = "metadata_http_open_circuits"
+	MetricsMetadataGatewayScore      = "metadata_ipfs_gateway_score"
+	MetricsMetadataGatewayLatency    = "metadata_ipfs_gateway_latency"
+	MetricsMetadataGatewayErrors     = "metadata_ipfs_gateway_error_rate"
+	MetricsMetadataGatewayQuarantine = "metadata_ipfs_gateway_quarantined"
 )
 
 // metadata types
@@ -42,6 +50,13 @@ func NewPrometheus(cfg *golibConfig.Prometheus) *Prometheus {
 	prometheusService.RegisterCounter(MetricsMetadataHttpErrors, "Count of HTTP errors in metadata", "network", "code", "type")
 	prometheusService.RegisterHistogram(MetricsMetadataIPFSResponseTime, "Histogram showing received bytes from IPFS per millisecons", "network", "node")
 	prometheusService.RegisterCounter(MetricsMetadataMimeType, "Count of metadata mime types", "network", "mime")
+	prometheusService.RegisterCounter(MetricsMetadataWebhooks, "Count of webhook delivery attempts by result", "network", "endpoint", "result")
+	prometheusService.RegisterCounter(MetricsMetadataValidation, "Count of metadata schema violations by kind", "network", "type", "kind")
+	prometheusService.RegisterGauge(MetricsMetadataHTTPCircuits, "HTTP hosts parked by circuit breaker: 1 if circuit is open", "network", "host")
+	prometheusService.RegisterGauge(MetricsMetadataGatewayScore, "Score of IPFS gateway which it's picked by", "gateway")
+	prometheusService.RegisterGauge(MetricsMetadataGatewayLatency, "Weighted time to response headers of IPFS gateway in milliseconds", "gateway")
+	prometheusService.RegisterGauge(MetricsMetadataGatewayErrors, "Weighted share of failed requests to IPFS gateway", "gateway")
+	prometheusService.RegisterGauge(MetricsMetadataGatewayQuarantine, "IPFS gateways which aren't used after failures: 1 if gateway is quarantined", "gateway")
 
 	return &Prometheus{prometheusService}
 }
@@ -140,3 +155,60 @@ func (p *Prometheus) SetMetadataNew(network, typ string, value float64) {
 		"type":    typ,
 	}, value)
 }
+
+// IncrementWebhookCounter -
+func (p *Prometheus) IncrementWebhookCounter(network, endpoint, result string) {
+	if p == nil || p.service == nil {
+		return
+	}
+	p.service.IncrementCounter(MetricsMetadataWebhooks, map[string]string{
+		"network":  network,
+		"endpoint": endpoint,
+		"result":   result,
+	})
+}
+
+// IncrementValidationIssue -
+func (p *Prometheus) IncrementValidationIssue(network, typ, kind string) {
+	if p == nil || p.service == nil {
+		return
+	}
+	p.service.IncrementCounter(MetricsMetadataValidation, map[string]string{
+		"network": network,
+		"type":    typ,
+		"kind":    kind,
+	})
+}
+
+// SetHTTPCircuit -
+func (p *Prometheus) SetHTTPCircuit(network, host string, open bool) {
+	if p == nil || p.service == nil {
+		return
+	}
+	var value float64
+	if open {
+		value = 1
+	}
+	p.service.SetGaugeValue(MetricsMetadataHTTPCircuits, map[string]string{
+		"network": network,
+		"host":    host,
+	}, value)
+}
+
+// SetGatewayScore -
+func (p *Prometheus) SetGatewayScore(score ipfs.GatewayScore) {
+	if p == nil || p.service == nil {
+		return
+	}
+	labels := map[string]string{
+		"gateway": score.Gateway,
+	}
+	var quarantined float64
+	if score.Quarantined {
+		quarantined = 1
+	}
+	p.service.SetGaugeValue(MetricsMetadataGatewayScore, labels, score.Score)
+	p.service.SetGaugeValue(MetricsMetadataGatewayLatency, labels, score.Latency)
+	p.service.SetGaugeValue(MetricsMetadataGatewayErrors, labels, score.ErrorRate)
+	p.service.SetGaugeValue(MetricsMetadataGatewayQuarantine, labels, quarantined)
+}
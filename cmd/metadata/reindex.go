@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	golibConfig "github.com/dipdup-net/go-lib/config"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/dipdup-net/metadata/cmd/metadata/config"
+	"github.com/dipdup-net/metadata/cmd/metadata/elastic"
+	"github.com/dipdup-net/metadata/cmd/metadata/models"
+)
+
+func newReindexCmd(configPath *string) *cobra.Command {
+	var (
+		addresses    string
+		mappings     string
+		index        string
+		batchSize    int
+		fresh        bool
+		replaceIndex bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "es-reindex",
+		Short: "Rebuild Elasticsearch indices of metadata from Postgres with the current mappings",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.Load(*configPath)
+			if err != nil {
+				return err
+			}
+
+			if cfg.Database.Kind != golibConfig.DBKindPostgres {
+				return errors.Errorf("source database should be %s, got %s", golibConfig.DBKindPostgres, cfg.Database.Kind)
+			}
+			switch index {
+			case "", elastic.IndexTokens, elastic.IndexContracts:
+			default:
+				return errors.Errorf("invalid index: %s. Should be %s or %s", index, elastic.IndexTokens, elastic.IndexContracts)
+			}
+
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			db, err := models.NewDatabase(ctx, cfg.Database)
+			if err != nil {
+				return errors.Wrap(err, "models.NewDatabase")
+			}
+			defer db.Close()
+
+			es, err := elastic.New(addresses, elastic.WithMappings(mappings))
+			if err != nil {
+				return errors.Wrap(err, "elastic.New")
+			}
+			if err := es.Ping(ctx); err != nil {
+				return errors.Wrap(err, "ping")
+			}
+
+			opts := []elastic.ReindexOption{
+				elastic.WithBatchSize(batchSize),
+			}
+			if fresh {
+				opts = append(opts, elastic.WithFresh())
+			}
+			if replaceIndex {
+				opts = append(opts, elastic.WithReplaceIndex())
+			}
+
+			if index == "" || index == elastic.IndexTokens {
+				if err := elastic.NewTokens(es).Reindex(ctx, tokensSource{reindexSource{db}}, opts...); err != nil {
+					return errors.Wrap(err, elastic.IndexTokens)
+				}
+			}
+			if index == "" || index == elastic.IndexContracts {
+				if err := elastic.NewContracts(es).Reindex(ctx, contractsSource{reindexSource{db}}, opts...); err != nil {
+					return errors.Wrap(err, elastic.IndexContracts)
+				}
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&addresses, "elastic", "http://127.0.0.1:9200", "comma-separated list of Elasticsearch nodes")
+	cmd.Flags().StringVar(&mappings, "mappings", "mappings", "directory with index mapping files")
+	cmd.Flags().StringVar(&index, "index", "", "index to rebuild: token_metadata or contract_metadata. Both by default")
+	cmd.Flags().IntVar(&batchSize, "batch", 1000, "count of rows in bulk request")
+	cmd.Flags().BoolVar(&fresh, "fresh", false, "start a new version of index instead of resuming interrupted reindexing")
+	cmd.Flags().BoolVar(&replaceIndex, "replace-index", false, "drop index created before aliases were introduced on alias switch")
+	return cmd
+}
+
+type reindexSource struct {
+	db *models.Database
+}
+
+// Popularity - weight of suggestions is count of all tokens of contract like in index
+func (s reindexSource) Popularity(ctx context.Context, network string, contracts []string) (map[string]int, error) {
+	return s.db.CountTokensByContracts(ctx, network, contracts)
+}
+
+// tokensSource - token metadata table as source of reindexing
+type tokensSource struct {
+	reindexSource
+}
+
+// Count -
+func (s tokensSource) Count(ctx context.Context) (int, error) {
+	return s.db.DB().ModelContext(ctx, (*models.TokenMetadata)(nil)).Count()
+}
+
+// Scan -
+func (s tokensSource) Scan(ctx context.Context, updateID int64, id uint64, limit int) ([]*models.TokenMetadata, error) {
+	tokens, err := s.db.ScanTokens(ctx, updateID, id, limit)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*models.TokenMetadata, len(tokens))
+	for i := range tokens {
+		result[i] = &tokens[i]
+	}
+	return result, nil
+}
+
+// contractsSource - contract metadata table as source of reindexing
+type contractsSource struct {
+	reindexSource
+}
+
+// Count -
+func (s contractsSource) Count(ctx context.Context) (int, error) {
+	return s.db.DB().ModelContext(ctx, (*models.ContractMetadata)(nil)).Count()
+}
+
+// Scan -
+func (s contractsSource) Scan(ctx context.Context, updateID int64, id uint64, limit int) ([]*models.ContractMetadata, error) {
+	contracts, err := s.db.ScanContracts(ctx, updateID, id, limit)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*models.ContractMetadata, len(contracts))
+	for i := range contracts {
+		result[i] = &contracts[i]
+	}
+	return result, nil
+}
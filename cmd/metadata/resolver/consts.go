@@ -1,5 +1,6 @@
 package resolver
 
 const (
-	defaultTimeout = 10
+	defaultTimeout  = 10
+	maxNestingDepth = 3
 )
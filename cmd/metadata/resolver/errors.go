@@ -14,4 +14,7 @@ var (
 	ErrJSONDecoding              = errors.New("JSON decoding error")
 	ErrNoIPFSResponse            = errors.New("can't load document from IPFS")
 	ErrTezosStorageKeyNotFound   = errors.New("key not found in tezos storage")
+	ErrHashMismatch              = errors.New("sha256 hash mismatch")
+	ErrTooDeepNesting            = errors.New("too deep nesting of sha256 URIs")
+	ErrHostUnavailable           = errors.New("host is unavailable")
 )
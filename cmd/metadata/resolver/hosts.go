@@ -0,0 +1,209 @@
+package resolver
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/dipdup-net/metadata/cmd/metadata/config"
+	"github.com/pkg/errors"
+	"golang.org/x/time/rate"
+)
+
+// default limits of requests to one host
+const (
+	defaultHostRateLimit        = 10
+	defaultHostBurst            = 10
+	defaultHostFailureThreshold = 5
+	defaultHostOpenTimeout      = time.Minute
+
+	// maxIdleHosts - states of healthy hosts are dropped when there are more hosts
+	maxIdleHosts = 10000
+)
+
+// HostObserver - is called when circuit of host is opened or closed
+type HostObserver func(host string, open bool)
+
+// HostLimits - throttling and circuit breaking of requests to one host
+type HostLimits struct {
+	Rate             rate.Limit
+	Burst            int
+	FailureThreshold int
+	OpenTimeout      time.Duration
+}
+
+// NewHostLimits - unset fields of config are defaults
+func NewHostLimits(cfg config.HTTP) HostLimits {
+	limits := HostLimits{
+		Rate:             defaultHostRateLimit,
+		Burst:            defaultHostBurst,
+		FailureThreshold: defaultHostFailureThreshold,
+		OpenTimeout:      defaultHostOpenTimeout,
+	}
+	if cfg.RateLimit > 0 {
+		limits.Rate = rate.Limit(cfg.RateLimit)
+	}
+	if cfg.Burst > 0 {
+		limits.Burst = cfg.Burst
+	}
+	if cfg.FailureThreshold > 0 {
+		limits.FailureThreshold = cfg.FailureThreshold
+	}
+	if cfg.OpenTimeout > 0 {
+		limits.OpenTimeout = time.Duration(cfg.OpenTimeout) * time.Second
+	}
+	return limits
+}
+
+type hostState struct {
+	limiter  *rate.Limiter
+	failures int
+
+	// open - requests are rejected till `openUntil`. Then one probe request is sent: circuit is closed if it succeeds.
+	open      bool
+	openUntil time.Time
+	probing   bool
+}
+
+// hosts - states of requested hosts
+type hosts struct {
+	limits   HostLimits
+	observer HostObserver
+
+	mx     sync.Mutex
+	states map[string]*hostState
+}
+
+func newHosts(limits HostLimits) *hosts {
+	return &hosts{
+		limits: limits,
+		states: make(map[string]*hostState),
+	}
+}
+
+func (h *hosts) state(host string) *hostState {
+	if st, ok := h.states[host]; ok {
+		return st
+	}
+	if len(h.states) >= maxIdleHosts {
+		for name, st := range h.states {
+			if !st.open && st.failures == 0 {
+				delete(h.states, name)
+			}
+		}
+	}
+	st := &hostState{
+		limiter: rate.NewLimiter(h.limits.Rate, h.limits.Burst),
+	}
+	h.states[host] = st
+	return st
+}
+
+// acquire - waits for a token of host's bucket. Returns error without waiting if circuit of host is open.
+func (h *hosts) acquire(ctx context.Context, host string, now time.Time) error {
+	h.mx.Lock()
+	st := h.state(host)
+	if st.open {
+		switch {
+		case now.Before(st.openUntil):
+			h.mx.Unlock()
+			return circuitError(host, st.openUntil.Sub(now))
+		case st.probing:
+			h.mx.Unlock()
+			return circuitError(host, 0)
+		default:
+			st.probing = true
+		}
+	}
+	limiter := st.limiter
+	h.mx.Unlock()
+
+	if err := limiter.Wait(ctx); err != nil {
+		h.report(host, context.Canceled, now)
+		if errors.Is(ctx.Err(), context.Canceled) {
+			return ctx.Err()
+		}
+		// token isn't available before deadline of request
+		return newResolvingError(0, ErrorTypeRateLimited, errors.Wrap(err, host))
+	}
+	return nil
+}
+
+// report - records result of request to host. Circuit is opened after `FailureThreshold` consecutive failures or by `Retry-After` of response.
+func (h *hosts) report(host string, err error, now time.Time) {
+	h.mx.Lock()
+	st := h.state(host)
+	st.probing = false
+
+	if errors.Is(err, context.Canceled) {
+		h.mx.Unlock()
+		return
+	}
+
+	failed, retryAfter := isHostFailure(err)
+	if !failed {
+		wasOpen := st.open
+		st.failures = 0
+		st.open = false
+		h.mx.Unlock()
+
+		if wasOpen {
+			h.notify(host, false)
+		}
+		return
+	}
+
+	st.failures++
+	tripped := st.open || st.failures >= h.limits.FailureThreshold
+	if !tripped && retryAfter == 0 {
+		h.mx.Unlock()
+		return
+	}
+
+	timeout := retryAfter
+	if tripped && h.limits.OpenTimeout > timeout {
+		timeout = h.limits.OpenTimeout
+	}
+	wasOpen := st.open
+	st.open = true
+	st.openUntil = now.Add(timeout)
+	h.mx.Unlock()
+
+	if !wasOpen {
+		h.notify(host, true)
+	}
+}
+
+func (h *hosts) notify(host string, open bool) {
+	if h.observer != nil {
+		h.observer(host, open)
+	}
+}
+
+// isHostFailure - host is considered broken if it doesn't respond, rate limits or fails with server error. Missing documents don't break host.
+func isHostFailure(err error) (bool, time.Duration) {
+	if err == nil {
+		return false, 0
+	}
+	var e ResolvingError
+	if !errors.As(err, &e) {
+		return true, 0
+	}
+	switch e.Type {
+	case ErrorTypeTimeout, ErrorTypeReceiving:
+		return true, 0
+	case ErrorTypeRateLimited:
+		return true, e.RetryAfter
+	case ErrorTypeHttpRequest:
+		return e.Code >= http.StatusInternalServerError, 0
+	default:
+		return false, 0
+	}
+}
+
+func circuitError(host string, retryAfter time.Duration) ResolvingError {
+	e := newResolvingError(0, ErrorTypeCircuitOpen, errors.Wrap(ErrHostUnavailable, host))
+	e.RetryAfter = retryAfter
+	return e
+}
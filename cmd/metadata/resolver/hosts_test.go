@@ -0,0 +1,181 @@
+package resolver
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+)
+
+const testHost = "example.com"
+
+func newTestHosts(events *[]bool) *hosts {
+	h := newHosts(HostLimits{
+		Rate:             rate.Inf,
+		Burst:            1,
+		FailureThreshold: 2,
+		OpenTimeout:      time.Minute,
+	})
+	h.observer = func(host string, open bool) {
+		*events = append(*events, open)
+	}
+	return h
+}
+
+func assertCircuitOpen(t *testing.T, err error, retryAfter time.Duration) {
+	var e ResolvingError
+	require.True(t, errors.As(err, &e), "error is %v", err)
+	assert.Equal(t, ErrorTypeCircuitOpen, e.Type)
+	assert.Equal(t, retryAfter, e.RetryAfter)
+	assert.ErrorIs(t, e.Err, ErrHostUnavailable)
+}
+
+func TestHosts_circuit(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(1700000000, 0)
+	failure := newResolvingError(http.StatusBadGateway, ErrorTypeHttpRequest, errors.New("bad gateway"))
+
+	var events []bool
+	h := newTestHosts(&events)
+
+	require.NoError(t, h.acquire(ctx, testHost, now))
+	h.report(testHost, failure, now)
+	require.NoError(t, h.acquire(ctx, testHost, now), "one failure doesn't open circuit")
+	h.report(testHost, failure, now)
+	assert.Equal(t, []bool{true}, events)
+
+	assertCircuitOpen(t, h.acquire(ctx, testHost, now.Add(10*time.Second)), 50*time.Second)
+	require.NoError(t, h.acquire(ctx, "other.com", now), "other hosts aren't affected")
+
+	// after timeout one probe is sent
+	probeAt := now.Add(time.Minute)
+	require.NoError(t, h.acquire(ctx, testHost, probeAt))
+	assertCircuitOpen(t, h.acquire(ctx, testHost, probeAt), 0)
+
+	// failed probe parks host again
+	h.report(testHost, failure, probeAt)
+	assertCircuitOpen(t, h.acquire(ctx, testHost, probeAt.Add(time.Second)), 59*time.Second)
+	assert.Equal(t, []bool{true}, events)
+
+	probeAt = probeAt.Add(time.Minute)
+	require.NoError(t, h.acquire(ctx, testHost, probeAt))
+	h.report(testHost, nil, probeAt)
+	require.NoError(t, h.acquire(ctx, testHost, probeAt))
+	assert.Equal(t, []bool{true, false}, events)
+}
+
+func TestHosts_report(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	tests := []struct {
+		name     string
+		errs     []error
+		wantOpen bool
+		wantTill time.Duration
+	}{
+		{
+			name: "timeouts",
+			errs: []error{
+				newResolvingError(0, ErrorTypeTimeout, ErrHTTPRequest),
+				newResolvingError(0, ErrorTypeReceiving, ErrHTTPRequest),
+			},
+			wantOpen: true,
+			wantTill: time.Minute,
+		}, {
+			name: "missing documents",
+			errs: []error{
+				newResolvingError(http.StatusNotFound, ErrorTypeNotFound, ErrHTTPRequest),
+				newResolvingError(http.StatusNotFound, ErrorTypeNotFound, ErrHTTPRequest),
+			},
+		}, {
+			name: "success resets failures",
+			errs: []error{
+				newResolvingError(0, ErrorTypeTimeout, ErrHTTPRequest),
+				nil,
+				newResolvingError(0, ErrorTypeTimeout, ErrHTTPRequest),
+			},
+		}, {
+			name: "canceled requests are ignored",
+			errs: []error{
+				newResolvingError(0, ErrorTypeTimeout, ErrHTTPRequest),
+				context.Canceled,
+				context.Canceled,
+			},
+		}, {
+			name: "client errors",
+			errs: []error{
+				newResolvingError(http.StatusForbidden, ErrorTypeHttpRequest, ErrHTTPRequest),
+				newResolvingError(http.StatusForbidden, ErrorTypeHttpRequest, ErrHTTPRequest),
+			},
+		}, {
+			name: "retry after parks host at once",
+			errs: []error{
+				ResolvingError{Code: http.StatusTooManyRequests, Type: ErrorTypeRateLimited, Err: ErrHTTPRequest, RetryAfter: 10 * time.Second},
+			},
+			wantOpen: true,
+			wantTill: 10 * time.Second,
+		}, {
+			name: "retry after is longer than timeout",
+			errs: []error{
+				newResolvingError(0, ErrorTypeTimeout, ErrHTTPRequest),
+				ResolvingError{Code: http.StatusServiceUnavailable, Type: ErrorTypeRateLimited, Err: ErrHTTPRequest, RetryAfter: time.Hour},
+			},
+			wantOpen: true,
+			wantTill: time.Hour,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var events []bool
+			h := newTestHosts(&events)
+			for _, err := range tt.errs {
+				h.report(testHost, err, now)
+			}
+			st := h.states[testHost]
+			assert.Equal(t, tt.wantOpen, st.open)
+			if tt.wantOpen {
+				assert.Equal(t, now.Add(tt.wantTill), st.openUntil)
+			}
+		})
+	}
+}
+
+func TestHttp_retryAfter(t *testing.T) {
+	var calls int
+	s := NewHttp(WithHostLimits(HostLimits{
+		Rate:             rate.Inf,
+		Burst:            1,
+		FailureThreshold: 5,
+		OpenTimeout:      time.Minute,
+	}))
+	s.client.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		calls++
+		header := make(http.Header)
+		header.Set("Retry-After", "120")
+		return &http.Response{
+			StatusCode: http.StatusTooManyRequests,
+			Status:     "429 Too Many Requests",
+			Body:       io.NopCloser(strings.NewReader("")),
+			Header:     header,
+			Request:    req,
+		}, nil
+	})
+
+	_, err := s.get(context.Background(), "https://example.com/1.json")
+	var e ResolvingError
+	require.True(t, errors.As(err, &e))
+	assert.Equal(t, ErrorTypeRateLimited, e.Type)
+	assert.Equal(t, 2*time.Minute, e.RetryAfter)
+
+	_, err = s.get(context.Background(), "https://example.com/2.json")
+	require.True(t, errors.As(err, &e))
+	assert.Equal(t, ErrorTypeCircuitOpen, e.Type)
+	assert.InDelta(t, float64(2*time.Minute), float64(e.RetryAfter), float64(time.Second))
+	assert.Equal(t, 1, calls, "parked host isn't requested")
+}
@@ -7,9 +7,11 @@ import (
 	"net"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/dipdup-net/metadata/cmd/metadata/config"
 	"github.com/dipdup-net/metadata/cmd/metadata/helpers"
 	"github.com/pkg/errors"
 )
@@ -23,6 +25,7 @@ const (
 type Http struct {
 	timeout time.Duration
 	client  http.Client
+	hosts   *hosts
 }
 
 // HttpOption -
@@ -37,10 +40,25 @@ func WithTimeoutHttp(timeout uint64) HttpOption {
 	}
 }
 
+// WithHostLimits - throttling and circuit breaking of requests to every host
+func WithHostLimits(limits HostLimits) HttpOption {
+	return func(s *Http) {
+		s.hosts.limits = limits
+	}
+}
+
+// WithHostObserver - sets function which is called when host is parked after failures or becomes available again
+func WithHostObserver(observer HostObserver) HttpOption {
+	return func(s *Http) {
+		s.hosts.observer = observer
+	}
+}
+
 // NewHttp -
 func NewHttp(opts ...HttpOption) Http {
 	s := Http{
 		timeout: time.Duration(defaultTimeout) * time.Second,
+		hosts:   newHosts(NewHostLimits(config.HTTP{})),
 	}
 
 	for i := range opts {
@@ -62,6 +80,14 @@ func NewHttp(opts ...HttpOption) Http {
 
 // Resolve -
 func (s Http) Resolve(ctx context.Context, network, address, link string) ([]byte, error) {
+	data, err := s.get(ctx, link)
+	if err != nil {
+		return nil, err
+	}
+	return helpers.Escape(data), nil
+}
+
+func (s Http) get(ctx context.Context, link string) ([]byte, error) {
 	parsed, err := url.ParseRequestURI(link)
 	if err != nil {
 		return nil, ErrInvalidURI
@@ -76,17 +102,29 @@ func (s Http) Resolve(ctx context.Context, network, address, link string) ([]byt
 		return nil, err
 	}
 
+	if err := s.hosts.acquire(ctx, parsed.Host, time.Now()); err != nil {
+		return nil, err
+	}
+	data, err := s.do(req)
+	s.hosts.report(parsed.Host, err, time.Now())
+	return data, err
+}
+
+func (s Http) do(req *http.Request) ([]byte, error) {
 	resp, err := s.client.Do(req)
 	if err != nil {
 		if errors.Is(err, context.Canceled) {
 			return nil, err
 		}
+		if isTimeout(err) {
+			return nil, newResolvingError(0, ErrorTypeTimeout, errors.Wrap(ErrHTTPRequest, err.Error()))
+		}
 		return nil, newResolvingError(0, ErrorTypeReceiving, errors.Wrap(ErrHTTPRequest, err.Error()))
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, newResolvingError(resp.StatusCode, ErrorTypeHttpRequest, errors.Errorf("invalid status: %s", resp.Status))
+		return nil, statusError(resp.StatusCode, resp.Status, resp.Header.Get("Retry-After"), time.Now())
 	}
 
 	data, err := io.ReadAll(io.LimitReader(resp.Body, 20971520)) // 20 MB limit for metadata
@@ -94,7 +132,7 @@ func (s Http) Resolve(ctx context.Context, network, address, link string) ([]byt
 		return nil, newResolvingError(0, ErrorTypeTooBig, err)
 	}
 
-	return helpers.Escape(data), nil
+	return data, nil
 }
 
 // Is -
@@ -141,3 +179,43 @@ func (s Http) ValidateURL(link *url.URL) error {
 	}
 	return nil
 }
+
+// statusError - classifies unsuccessful response. Delay of rate limited response is taken from `Retry-After` header.
+func statusError(code int, status, retryAfter string, now time.Time) ResolvingError {
+	err := errors.Errorf("invalid status: %s", status)
+	switch code {
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		e := newResolvingError(code, ErrorTypeRateLimited, err)
+		e.RetryAfter = parseRetryAfter(retryAfter, now)
+		return e
+	case http.StatusNotFound, http.StatusGone:
+		return newResolvingError(code, ErrorTypeNotFound, err)
+	default:
+		return newResolvingError(code, ErrorTypeHttpRequest, err)
+	}
+}
+
+// parseRetryAfter - `Retry-After` is either count of seconds or HTTP date
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil && date.After(now) {
+		return date.Sub(now)
+	}
+	return 0
+}
+
+func isTimeout(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
@@ -19,6 +19,7 @@ const (
 type Ipfs struct {
 	pinning  []*shell.Shell
 	pool     *ipfs.Pool
+	scores   *ipfs.Scores
 	timeout  time.Duration
 	fallback string
 }
@@ -54,21 +55,33 @@ func WithFallbackIpfs(fallback string) IpfsOption {
 	}
 }
 
+// WithScoresIpfs - gateways are picked by scores shared with thumbnails and admin endpoint
+func WithScoresIpfs(scores *ipfs.Scores) IpfsOption {
+	return func(s *Ipfs) {
+		s.scores = scores
+	}
+}
+
 // NewIPFS -
 func NewIPFS(gateways []string, opts ...IpfsOption) (Ipfs, error) {
-	pool, err := ipfs.NewPool(gateways, 1024*1024)
-	if err != nil {
-		return Ipfs{}, err
-	}
 	s := Ipfs{
 		pinning: make([]*shell.Shell, 0),
-		pool:    pool,
 	}
 
 	for i := range opts {
 		opts[i](&s)
 	}
 
+	poolOpts := make([]ipfs.PoolOption, 0)
+	if s.scores != nil {
+		poolOpts = append(poolOpts, ipfs.WithScores(s.scores))
+	}
+	pool, err := ipfs.NewPool(gateways, 1024*1024, poolOpts...)
+	if err != nil {
+		return Ipfs{}, err
+	}
+	s.pool = pool
+
 	return s, nil
 }
 
@@ -88,7 +101,7 @@ func (s Ipfs) Resolve(ctx context.Context, network, address, link string) (ipfs.
 			return data, err
 		}
 		if s.fallback == "" {
-			return data, newResolvingError(0, ErrorTypeHttpRequest, err)
+			return data, gatewayError(requestCtx, err)
 		}
 
 		requestCtx, cancel := context.WithTimeout(ctx, s.timeout)
@@ -99,7 +112,7 @@ func (s Ipfs) Resolve(ctx context.Context, network, address, link string) (ipfs.
 			if errors.Is(err, ipfs.ErrInvalidCID) {
 				return data, newResolvingError(0, ErrorTypeReceiving, errors.Wrap(ErrInvalidURI, err.Error()))
 			}
-			return data, err
+			return data, gatewayError(requestCtx, err)
 		}
 	}
 
@@ -123,3 +136,16 @@ func (s Ipfs) pinContent(data []byte) {
 		}
 	}
 }
+
+// gatewayError - classifies error of gateway request by its context and response status
+func gatewayError(requestCtx context.Context, err error) ResolvingError {
+	var status ipfs.StatusError
+	switch {
+	case errors.Is(requestCtx.Err(), context.DeadlineExceeded) || isTimeout(err):
+		return newResolvingError(0, ErrorTypeTimeout, err)
+	case errors.As(err, &status):
+		return statusError(status.Code, status.Status, status.RetryAfter, time.Now())
+	default:
+		return newResolvingError(0, ErrorTypeHttpRequest, err)
+	}
+}
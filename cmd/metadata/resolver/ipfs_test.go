@@ -0,0 +1,31 @@
+package resolver
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dipdup-net/metadata/internal/ipfs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIpfs_ResolveScores(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"name":"test"}`))
+	}))
+	defer server.Close()
+
+	scores := ipfs.NewScores([]string{server.URL})
+	s, err := NewIPFS([]string{server.URL}, WithScoresIpfs(scores), WithTimeoutIpfs(10))
+	require.NoError(t, err)
+
+	data, err := s.Resolve(context.Background(), "mainnet", "", "ipfs://QmWYTUjkRusrhz4BCmoMKfSA8DBXk5pH2oAN83S9mABE3w")
+	require.NoError(t, err)
+	assert.Equal(t, server.URL, data.Node)
+
+	snapshot := scores.Snapshot()
+	require.Len(t, snapshot, 1)
+	assert.EqualValues(t, 1, snapshot[0].Requests, "request is observed by shared scores")
+}
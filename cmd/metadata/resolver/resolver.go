@@ -4,8 +4,10 @@ import (
 	"bytes"
 	"context"
 	stdJSON "encoding/json"
+	"time"
 
 	"github.com/dipdup-net/metadata/cmd/metadata/config"
+	"github.com/dipdup-net/metadata/cmd/metadata/helpers"
 	"github.com/dipdup-net/metadata/cmd/metadata/tezoskeys"
 	"github.com/dipdup-net/metadata/internal/ipfs"
 	"github.com/dipdup-net/metadata/internal/tezos"
@@ -39,6 +41,13 @@ const (
 	ErrorInvalidHTTPURI      ErrorType = "invalid_http_uri"
 	ErrorInvalidCID          ErrorType = "invalid_ipfs_cid"
 	ErrorUnknownStorageType  ErrorType = "unknown_storage_type"
+	ErrorTypeHashMismatch    ErrorType = "hash_mismatch"
+	ErrorTypeTooDeepNesting  ErrorType = "too_deep_nesting"
+	ErrorTypeUnknownNetwork  ErrorType = "unknown_network"
+	ErrorTypeTimeout         ErrorType = "timeout"
+	ErrorTypeRateLimited     ErrorType = "rate_limited"
+	ErrorTypeNotFound        ErrorType = "not_found"
+	ErrorTypeCircuitOpen     ErrorType = "circuit_open"
 )
 
 // ResolvingError -
@@ -46,6 +55,9 @@ type ResolvingError struct {
 	Code int
 	Type ErrorType
 	Err  error
+
+	// RetryAfter - delay requested by server in `Retry-After` header
+	RetryAfter time.Duration
 }
 
 // Error -
@@ -57,7 +69,7 @@ func (err ResolvingError) Error() string {
 }
 
 func newResolvingError(code int, typ ErrorType, err error) ResolvingError {
-	return ResolvingError{code, typ, err}
+	return ResolvingError{Code: code, Type: typ, Err: err}
 }
 
 // IsFatal -
@@ -65,7 +77,10 @@ func (err ResolvingError) IsFatal() bool {
 	return err.Type == ErrorInvalidHTTPURI ||
 		err.Type == ErrorTypeInvalidJSON ||
 		err.Type == ErrorInvalidCID ||
-		err.Type == ErrorUnknownStorageType
+		err.Type == ErrorUnknownStorageType ||
+		err.Type == ErrorTypeHashMismatch ||
+		err.Type == ErrorTypeTooDeepNesting ||
+		err.Type == ErrorTypeUnknownNetwork
 }
 
 // Resolved -
@@ -75,6 +90,7 @@ type Resolved struct {
 	Data         []byte
 	ResponseTime int64
 	URI          tezos.URI
+	Sha256       string
 }
 
 // Receiver -
@@ -85,8 +101,8 @@ type Receiver struct {
 	tezos TezosStorage
 }
 
-// New -
-func New(ctx context.Context, settings config.Settings, tezosKeys *tezoskeys.TezosKeys, node *ipfs.Node) (Receiver, error) {
+// New - `opts` are applied to HTTP storage after settings
+func New(ctx context.Context, settings config.Settings, tezosKeys *tezoskeys.TezosKeys, node *ipfs.Node, opts ...HttpOption) (Receiver, error) {
 	ipfs, err := NewIPFSNode(node,
 		WithTimeoutIpfsNode(settings.IPFS.Timeout),
 	)
@@ -97,13 +113,34 @@ func New(ctx context.Context, settings config.Settings, tezosKeys *tezoskeys.Tez
 	return Receiver{
 		ipfs:  ipfs,
 		tezos: NewTezosStorage(tezosKeys),
-		http:  NewHttp(WithTimeoutHttp(settings.HTTPTimeout)),
-		sha:   NewSha256(WithTimeoutSha256(settings.HTTPTimeout)),
+		http:  NewHttp(append([]HttpOption{WithTimeoutHttp(settings.HTTPTimeout), WithHostLimits(NewHostLimits(settings.HTTP))}, opts...)...),
+		sha:   NewSha256(),
 	}, nil
 }
 
 // Resolve -
 func (r Receiver) Resolve(ctx context.Context, network, address, link string, attempt int8) (resolved Resolved, err error) {
+	resolved, err = r.fetch(ctx, network, address, link, 0)
+	if err != nil {
+		return
+	}
+
+	// documents of every storage are escaped: Postgres rejects `\u0000` and lone surrogates in jsonb
+	resolved.Data = bytes.TrimLeft(helpers.Escape(resolved.Data), " ")
+	if len(resolved.Data) == 0 || resolved.Data[0] != '{' || !json.Valid(resolved.Data) {
+		return resolved, newResolvingError(0, ErrorTypeInvalidJSON, errors.New("invalid json"))
+	}
+
+	var buf bytes.Buffer
+	if err := stdJSON.Compact(&buf, resolved.Data); err != nil {
+		return resolved, err
+	}
+	resolved.Data = buf.Bytes()
+	return
+}
+
+// fetch - receives raw document by link. `depth` is a count of sha256 wrappers around the link.
+func (r Receiver) fetch(ctx context.Context, network, address, link string, depth int) (resolved Resolved, err error) {
 	if len(link) < 7 { // the shortest prefix is http://
 		return resolved, errors.Wrap(ErrUnknownStorageType, link)
 	}
@@ -134,32 +171,24 @@ func (r Receiver) Resolve(ctx context.Context, network, address, link string, at
 
 	case r.http.Is(link):
 		resolved.By = ResolverTypeHTTP
-		resolved.Data, err = r.http.Resolve(ctx, network, address, link)
+		resolved.Data, err = r.http.get(ctx, link)
 
 	case r.sha.Is(link):
+		if depth >= maxNestingDepth {
+			return resolved, newResolvingError(0, ErrorTypeTooDeepNesting, errors.Wrap(ErrTooDeepNesting, link))
+		}
+
+		resolved, err = r.sha.Resolve(ctx, network, address, link, func(ctx context.Context, network, address, inner string) (Resolved, error) {
+			return r.fetch(ctx, network, address, inner, depth+1)
+		})
 		resolved.By = ResolverTypeSha256
-		resolved.Data, err = r.sha.Resolve(ctx, network, address, link)
 
 	default:
 		return resolved, newResolvingError(0, ErrorUnknownStorageType, ErrUnknownStorageType)
 	}
 
-	if err != nil {
-		if errors.Is(err, ErrInvalidURI) {
-			return resolved, newResolvingError(0, ErrorInvalidHTTPURI, err)
-		}
-		return
-	}
-
-	resolved.Data = bytes.TrimLeft(resolved.Data, " ")
-	if len(resolved.Data) == 0 || resolved.Data[0] != '{' || !json.Valid(resolved.Data) {
-		return resolved, newResolvingError(0, ErrorTypeInvalidJSON, errors.New("invalid json"))
-	}
-
-	var buf bytes.Buffer
-	if err := stdJSON.Compact(&buf, resolved.Data); err != nil {
-		return resolved, err
+	if errors.Is(err, ErrInvalidURI) {
+		return resolved, newResolvingError(0, ErrorInvalidHTTPURI, err)
 	}
-	resolved.Data = buf.Bytes()
 	return
 }
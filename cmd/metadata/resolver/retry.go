@@ -0,0 +1,184 @@
+package resolver
+
+import (
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/dipdup-net/metadata/cmd/metadata/config"
+	"github.com/pkg/errors"
+)
+
+// ErrorTypeDefault - name of policy which is used by errors without own policy
+const ErrorTypeDefault ErrorType = "default"
+
+// RetryPolicy - exponential backoff of resolving after error of some type
+type RetryPolicy struct {
+	Delay       time.Duration
+	MaxDelay    time.Duration
+	Factor      float64
+	Jitter      float64
+	MaxAttempts int
+	// Revive - failed metadata is retried again by periodic retry of recent metadata
+	Revive bool
+}
+
+// backoff - delay after `attempt` failed attempts. `random` is in [0, 1) and spreads delay by jitter.
+func (p RetryPolicy) backoff(attempt int, random float64) time.Duration {
+	delay := float64(p.Delay)
+	for i := 1; i < attempt && delay < float64(p.MaxDelay); i++ {
+		delay *= p.Factor
+	}
+	if delay > float64(p.MaxDelay) {
+		delay = float64(p.MaxDelay)
+	}
+	delay *= 1 + p.Jitter*(2*random-1)
+	return time.Duration(delay)
+}
+
+func (p RetryPolicy) merge(cfg config.RetryPolicy) RetryPolicy {
+	if cfg.Delay > 0 {
+		p.Delay = time.Duration(cfg.Delay) * time.Second
+	}
+	if cfg.MaxDelay > 0 {
+		p.MaxDelay = time.Duration(cfg.MaxDelay) * time.Second
+	}
+	if cfg.Factor > 0 {
+		p.Factor = cfg.Factor
+	}
+	if cfg.Jitter > 0 {
+		p.Jitter = cfg.Jitter
+	}
+	if cfg.MaxAttempts > 0 {
+		p.MaxAttempts = cfg.MaxAttempts
+	}
+	if cfg.Revive != nil {
+		p.Revive = *cfg.Revive
+	}
+	return p
+}
+
+// builtinRetryPolicies - timeouts are retried fast and revived later, rate limited requests and requests to parked hosts wait longer, missing documents are rarely published later
+func builtinRetryPolicies(fallback RetryPolicy) map[ErrorType]RetryPolicy {
+	timeout := fallback
+	timeout.MaxDelay = 10 * time.Minute
+	timeout.Revive = true
+
+	rateLimited := fallback
+	rateLimited.Delay = time.Minute
+
+	notFound := fallback
+	notFound.Delay = 10 * time.Minute
+	notFound.MaxDelay = 24 * time.Hour
+	notFound.Factor = 4
+
+	return map[ErrorType]RetryPolicy{
+		ErrorTypeTimeout:     timeout,
+		ErrorTypeRateLimited: rateLimited,
+		ErrorTypeCircuitOpen: rateLimited,
+		ErrorTypeNotFound:    notFound,
+	}
+}
+
+// Retrier - schedules the next attempt of resolving by type of error
+type Retrier struct {
+	policies map[ErrorType]RetryPolicy
+	fallback RetryPolicy
+	random   func() float64
+}
+
+// NewRetrier - `delay` in seconds and `maxAttempts` are defaults of all policies, they're `ipfs.delay` and `max_retry_count_on_error` settings
+func NewRetrier(cfg config.Retry, delay, maxAttempts int) *Retrier {
+	fallback := RetryPolicy{
+		Delay:       time.Duration(delay) * time.Second,
+		MaxDelay:    time.Hour,
+		Factor:      2,
+		Jitter:      0.2,
+		MaxAttempts: maxAttempts,
+	}
+	if policy, ok := cfg.Policies[string(ErrorTypeDefault)]; ok {
+		fallback = fallback.merge(policy)
+	}
+
+	policies := builtinRetryPolicies(fallback)
+	for name, policy := range cfg.Policies {
+		typ := ErrorType(name)
+		if typ == ErrorTypeDefault {
+			continue
+		}
+		base, ok := policies[typ]
+		if !ok {
+			base = fallback
+		}
+		policies[typ] = base.merge(policy)
+	}
+
+	return &Retrier{
+		policies: policies,
+		fallback: fallback,
+		random:   rand.Float64,
+	}
+}
+
+// Policy - returns policy of error type
+func (r *Retrier) Policy(typ ErrorType) RetryPolicy {
+	if policy, ok := r.policies[typ]; ok {
+		return policy
+	}
+	return r.fallback
+}
+
+// Classify - returns type of error which selects retry policy
+func Classify(err error) ErrorType {
+	var e ResolvingError
+	switch {
+	case errors.As(err, &e):
+		return e.Type
+	case isTimeout(err):
+		return ErrorTypeTimeout
+	default:
+		return ErrorTypeDefault
+	}
+}
+
+// Next - returns time of the next attempt after `attempt` failed attempts. It's false if error is fatal or attempts are exhausted. Delay requested by server is honored if it's longer.
+func (r *Retrier) Next(err error, attempt int, now time.Time) (time.Time, bool) {
+	var e ResolvingError
+	if errors.As(err, &e) && e.IsFatal() {
+		return time.Time{}, false
+	}
+
+	policy := r.Policy(Classify(err))
+	if attempt >= policy.MaxAttempts {
+		return time.Time{}, false
+	}
+
+	delay := policy.backoff(attempt, r.random())
+	if e.RetryAfter > delay {
+		delay = e.RetryAfter
+	}
+	return now.Add(delay), true
+}
+
+// errorTypes - all types of errors which may be stored with failed metadata
+var errorTypes = []ErrorType{
+	ErrorTypeHttpRequest, ErrorTypeTooBig, ErrorTypeReceiving, ErrorTypeKeyTezosNotFond, ErrorTypeTezosURIParsing,
+	ErrorTypeInvalidJSON, ErrorInvalidHTTPURI, ErrorInvalidCID, ErrorUnknownStorageType, ErrorTypeHashMismatch,
+	ErrorTypeTooDeepNesting, ErrorTypeUnknownNetwork, ErrorTypeTimeout, ErrorTypeRateLimited, ErrorTypeNotFound,
+	ErrorTypeCircuitOpen, ErrorTypeDefault,
+}
+
+// Revived - types of errors which failed metadata is revived after
+func (r *Retrier) Revived() []string {
+	types := make([]string, 0)
+	for _, typ := range errorTypes {
+		if (ResolvingError{Type: typ}).IsFatal() {
+			continue
+		}
+		if r.Policy(typ).Revive {
+			types = append(types, string(typ))
+		}
+	}
+	sort.Strings(types)
+	return types
+}
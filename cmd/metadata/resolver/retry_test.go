@@ -0,0 +1,276 @@
+package resolver
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/dipdup-net/metadata/cmd/metadata/config"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryPolicy_backoff(t *testing.T) {
+	policy := RetryPolicy{
+		Delay:    10 * time.Second,
+		MaxDelay: time.Minute,
+		Factor:   2,
+		Jitter:   0.5,
+	}
+	tests := []struct {
+		name    string
+		attempt int
+		random  float64
+		want    time.Duration
+	}{
+		{
+			name:    "first attempt",
+			attempt: 1,
+			random:  0.5,
+			want:    10 * time.Second,
+		}, {
+			name:    "third attempt",
+			attempt: 3,
+			random:  0.5,
+			want:    40 * time.Second,
+		}, {
+			name:    "capped",
+			attempt: 10,
+			random:  0.5,
+			want:    time.Minute,
+		}, {
+			name:    "lower jitter bound",
+			attempt: 1,
+			random:  0,
+			want:    5 * time.Second,
+		}, {
+			name:    "upper jitter bound",
+			attempt: 10,
+			random:  1,
+			want:    90 * time.Second,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, policy.backoff(tt.attempt, tt.random))
+		})
+	}
+}
+
+func TestRetrier_Next(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	retrier := NewRetrier(config.Retry{}, 10, 3)
+	retrier.random = func() float64 { return 0.5 }
+
+	tests := []struct {
+		name    string
+		err     error
+		attempt int
+		want    time.Duration
+		wantOk  bool
+	}{
+		{
+			name:    "default error",
+			err:     errors.New("unknown"),
+			attempt: 2,
+			want:    20 * time.Second,
+			wantOk:  true,
+		}, {
+			name:    "attempts are exhausted",
+			err:     errors.New("unknown"),
+			attempt: 3,
+		}, {
+			name:    "fatal error",
+			err:     newResolvingError(0, ErrorTypeHashMismatch, errors.New("hash mismatch")),
+			attempt: 1,
+		}, {
+			name:    "timeout",
+			err:     errors.Wrap(context.DeadlineExceeded, "request"),
+			attempt: 1,
+			want:    10 * time.Second,
+			wantOk:  true,
+		}, {
+			name:    "not found",
+			err:     newResolvingError(http.StatusNotFound, ErrorTypeNotFound, errors.New("not found")),
+			attempt: 2,
+			want:    40 * time.Minute,
+			wantOk:  true,
+		}, {
+			name:    "retry after is longer than backoff",
+			err:     statusError(http.StatusTooManyRequests, "429 Too Many Requests", "300", now),
+			attempt: 1,
+			want:    5 * time.Minute,
+			wantOk:  true,
+		}, {
+			name:    "retry after is shorter than backoff",
+			err:     statusError(http.StatusTooManyRequests, "429 Too Many Requests", "1", now),
+			attempt: 1,
+			want:    time.Minute,
+			wantOk:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := retrier.Next(tt.err, tt.attempt, now)
+			assert.Equal(t, tt.wantOk, ok)
+			if tt.wantOk {
+				assert.Equal(t, tt.want, got.Sub(now))
+			}
+		})
+	}
+}
+
+func TestNewRetrier(t *testing.T) {
+	revive := true
+	retrier := NewRetrier(config.Retry{
+		Policies: map[string]config.RetryPolicy{
+			"default":      {MaxDelay: 120, Revive: &revive},
+			"not_found":    {MaxAttempts: 1},
+			"http_request": {Delay: 30},
+		},
+	}, 10, 5)
+
+	assert.Equal(t, RetryPolicy{
+		Delay:       10 * time.Second,
+		MaxDelay:    2 * time.Minute,
+		Factor:      2,
+		Jitter:      0.2,
+		MaxAttempts: 5,
+		Revive:      true,
+	}, retrier.Policy(ErrorTypeDefault))
+
+	assert.Equal(t, RetryPolicy{
+		Delay:       10 * time.Minute,
+		MaxDelay:    24 * time.Hour,
+		Factor:      4,
+		Jitter:      0.2,
+		MaxAttempts: 1,
+		Revive:      true,
+	}, retrier.Policy(ErrorTypeNotFound))
+
+	assert.Equal(t, 30*time.Second, retrier.Policy(ErrorTypeHttpRequest).Delay)
+	assert.Equal(t, 10*time.Minute, retrier.Policy(ErrorTypeTimeout).MaxDelay)
+}
+
+func TestRetrier_Revived(t *testing.T) {
+	revive := false
+	tests := []struct {
+		name string
+		cfg  config.Retry
+		want []string
+	}{
+		{
+			name: "builtin",
+			want: []string{"timeout"},
+		}, {
+			name: "timeouts aren't revived",
+			cfg: config.Retry{
+				Policies: map[string]config.RetryPolicy{"timeout": {Revive: &revive}},
+			},
+			want: []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, NewRetrier(tt.cfg, 10, 3).Revived())
+		})
+	}
+}
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want ErrorType
+	}{
+		{
+			name: "resolving error",
+			err:  errors.Wrap(newResolvingError(0, ErrorTypeRateLimited, errors.New("limited")), "resolve"),
+			want: ErrorTypeRateLimited,
+		}, {
+			name: "deadline",
+			err:  context.DeadlineExceeded,
+			want: ErrorTypeTimeout,
+		}, {
+			name: "other",
+			err:  errors.New("other"),
+			want: ErrorTypeDefault,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Classify(tt.err))
+		})
+	}
+}
+
+func Test_parseRetryAfter(t *testing.T) {
+	now := time.Date(2021, time.October, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name  string
+		value string
+		want  time.Duration
+	}{
+		{
+			name: "empty",
+		}, {
+			name:  "seconds",
+			value: "120",
+			want:  2 * time.Minute,
+		}, {
+			name:  "negative",
+			value: "-1",
+		}, {
+			name:  "date",
+			value: "Fri, 01 Oct 2021 12:01:30 GMT",
+			want:  90 * time.Second,
+		}, {
+			name:  "date in the past",
+			value: "Fri, 01 Oct 2021 11:00:00 GMT",
+		}, {
+			name:  "invalid",
+			value: "soon",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, parseRetryAfter(tt.value, now))
+		})
+	}
+}
+
+func Test_statusError(t *testing.T) {
+	tests := []struct {
+		name string
+		code int
+		want ErrorType
+	}{
+		{
+			name: "too many requests",
+			code: http.StatusTooManyRequests,
+			want: ErrorTypeRateLimited,
+		}, {
+			name: "service unavailable",
+			code: http.StatusServiceUnavailable,
+			want: ErrorTypeRateLimited,
+		}, {
+			name: "not found",
+			code: http.StatusNotFound,
+			want: ErrorTypeNotFound,
+		}, {
+			name: "gone",
+			code: http.StatusGone,
+			want: ErrorTypeNotFound,
+		}, {
+			name: "internal error",
+			code: http.StatusInternalServerError,
+			want: ErrorTypeHttpRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, statusError(tt.code, http.StatusText(tt.code), "", time.Now()).Type)
+		})
+	}
+}
@@ -1,69 +1,72 @@
 package resolver
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"strings"
+
+	"github.com/pkg/errors"
 )
 
 const (
 	prefixSha256 = "sha256://"
 )
 
+type resolveFunc func(ctx context.Context, network, address, link string) (Resolved, error)
+
 // Sha256 -
-type Sha256 struct {
-	Http
+type Sha256 struct{}
 
-	hash string
+// NewSha256 -
+func NewSha256() Sha256 {
+	return Sha256{}
 }
 
-// Sha256StorageOption -
-type Sha256Option func(*Sha256)
-
-// WithTimeoutSha256 -
-func WithTimeoutSha256(timeout uint64) Sha256Option {
-	return func(s *Sha256) {
-		WithTimeoutHttp(timeout)(&s.Http)
+// Resolve - receives document by inner link using `inner` and checks that its SHA-256 digest is equal to the hash from URI
+func (s Sha256) Resolve(ctx context.Context, network, address, value string, inner resolveFunc) (Resolved, error) {
+	var uri Sha256URI
+	if err := uri.Parse(value); err != nil {
+		return Resolved{}, err
 	}
-}
 
-// WithHashSha256 -
-func WithHashSha256(hash string) Sha256Option {
-	return func(s *Sha256) {
-		s.hash = hash
+	expected, err := decodeSha256Hash(uri.Hash)
+	if err != nil {
+		return Resolved{}, err
 	}
-}
 
-// NewSha256 -
-func NewSha256(opts ...Sha256Option) Sha256 {
-	s := Sha256{
-		Http: NewHttp(),
+	resolved, err := inner(ctx, network, address, uri.Link)
+	if err != nil {
+		return resolved, err
 	}
 
-	for i := range opts {
-		opts[i](&s)
+	if err := verifySha256(resolved.Data, expected); err != nil {
+		return resolved, err
 	}
 
-	return s
+	resolved.Sha256 = hex.EncodeToString(expected)
+	return resolved, nil
 }
 
-// Resolve -
-func (s Sha256) Resolve(ctx context.Context, network, address, value string) ([]byte, error) {
-	var uri Sha256URI
-	if err := uri.Parse(value); err != nil {
-		return nil, err
-	}
-	if !s.validate(uri.Hash) {
-		return nil, nil
-	}
-
-	return s.Http.Resolve(ctx, network, address, uri.Link)
+// Is -
+func (s Sha256) Is(link string) bool {
+	return strings.HasPrefix(link, prefixSha256)
 }
 
-func (s Sha256) validate(uriHash string) bool {
-	return s.hash != uriHash
+func decodeSha256Hash(hash string) ([]byte, error) {
+	hash = strings.TrimPrefix(strings.ToLower(hash), "0x")
+	decoded, err := hex.DecodeString(hash)
+	if err != nil || len(decoded) != sha256.Size {
+		return nil, errors.Wrapf(ErrInvalidURI, "invalid sha256 hash: %s", hash)
+	}
+	return decoded, nil
 }
 
-// Is -
-func (s Sha256) Is(link string) bool {
-	return strings.HasPrefix(link, prefixSha256)
+func verifySha256(data, expected []byte) error {
+	actual := sha256.Sum256(data)
+	if !bytes.Equal(actual[:], expected) {
+		return newResolvingError(0, ErrorTypeHashMismatch, errors.Wrapf(ErrHashMismatch, "expected=%x actual=%x", expected, actual))
+	}
+	return nil
 }
@@ -0,0 +1,117 @@
+package resolver
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func newStubHttp(body []byte) Http {
+	s := NewHttp()
+	s.client.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Status:     "200 OK",
+			Body:       io.NopCloser(bytes.NewReader(body)),
+			Header:     make(http.Header),
+			Request:    req,
+		}, nil
+	})
+	return s
+}
+
+func sha256Link(hash, link string, depth int) string {
+	for i := 0; i < depth; i++ {
+		link = "sha256://0x" + hash + "/" + url.QueryEscape(link)
+	}
+	return link
+}
+
+func TestReceiver_ResolveSha256(t *testing.T) {
+	const (
+		body  = `{"name":"test"}`
+		hash  = "7d9fd2051fc32b32feab10946fab6bb91426ab7e39aa5439289ed892864aa91d"
+		other = "eaa42ea06b95d7917d22135a630e65352cfd0a721ae88155a1512468a95cb750"
+		link  = "https://example.com/metadata.json"
+	)
+
+	tests := []struct {
+		name     string
+		link     string
+		wantHash string
+		wantType ErrorType
+		wantErr  bool
+	}{
+		{
+			name:     "valid hash",
+			link:     sha256Link(hash, link, 1),
+			wantHash: hash,
+		}, {
+			name:     "valid hash in upper case",
+			link:     "sha256://0x7D9FD2051FC32B32FEAB10946FAB6BB91426AB7E39AA5439289ED892864AA91D/https:%2F%2Fexample.com%2Fmetadata.json",
+			wantHash: hash,
+		}, {
+			name:     "nested sha256",
+			link:     sha256Link(hash, link, maxNestingDepth),
+			wantHash: hash,
+		}, {
+			name:     "hash mismatch",
+			link:     sha256Link(other, link, 1),
+			wantErr:  true,
+			wantType: ErrorTypeHashMismatch,
+		}, {
+			name:     "hash mismatch in nested link",
+			link:     "sha256://0x" + hash + "/" + url.QueryEscape(sha256Link(other, link, 1)),
+			wantErr:  true,
+			wantType: ErrorTypeHashMismatch,
+		}, {
+			name:     "too deep nesting",
+			link:     sha256Link(hash, link, maxNestingDepth+1),
+			wantErr:  true,
+			wantType: ErrorTypeTooDeepNesting,
+		}, {
+			name:     "invalid hash",
+			link:     "sha256://0xinvalid/https:%2F%2Fexample.com%2Fmetadata.json",
+			wantErr:  true,
+			wantType: ErrorInvalidHTTPURI,
+		}, {
+			name:     "unknown inner scheme",
+			link:     sha256Link(hash, "ftp://example.com/metadata.json", 1),
+			wantErr:  true,
+			wantType: ErrorUnknownStorageType,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := Receiver{
+				http: newStubHttp([]byte(body)),
+				sha:  NewSha256(),
+			}
+			got, err := r.Resolve(context.Background(), "mainnet", "KT1", tt.link, 1)
+			if tt.wantErr {
+				require.Error(t, err)
+				e, ok := err.(ResolvingError)
+				require.True(t, ok)
+				assert.Equal(t, tt.wantType, e.Type)
+				assert.True(t, e.IsFatal())
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, []byte(body), got.Data)
+			assert.Equal(t, tt.wantHash, got.Sha256)
+			assert.Equal(t, ResolverTypeSha256, got.By)
+		})
+	}
+}
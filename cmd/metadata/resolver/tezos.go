@@ -5,6 +5,7 @@ import (
 
 	"github.com/dipdup-net/metadata/cmd/metadata/tezoskeys"
 	"github.com/dipdup-net/metadata/internal/tezos"
+	"github.com/pkg/errors"
 )
 
 type tezosData struct {
@@ -37,11 +38,25 @@ func (s TezosStorage) Resolve(ctx context.Context, network, address, value strin
 		uri.Address = address
 	}
 
-	item, err := s.tk.Get(uri.Network, uri.Address, uri.Key)
-	if err != nil {
+	item, err := s.tk.Get(ctx, uri.Network, uri.Address, uri.Key)
+	switch {
+	case err == nil:
+	case errors.Is(err, tezoskeys.ErrUnknownNetwork):
+		return tezosData{
+			URI: uri,
+		}, newResolvingError(0, ErrorTypeUnknownNetwork, err)
+	case tezoskeys.IsNotFound(err):
 		return tezosData{
 			URI: uri,
 		}, newResolvingError(0, ErrorTypeKeyTezosNotFond, ErrTezosStorageKeyNotFound)
+	case isTimeout(err):
+		return tezosData{
+			URI: uri,
+		}, newResolvingError(0, ErrorTypeTimeout, err)
+	default:
+		return tezosData{
+			URI: uri,
+		}, newResolvingError(0, ErrorTypeReceiving, err)
 	}
 
 	return tezosData{
@@ -0,0 +1,103 @@
+package resolver
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dipdup-net/go-lib/tzkt/api"
+	"github.com/dipdup-net/metadata/cmd/metadata/models"
+	"github.com/dipdup-net/metadata/cmd/metadata/tezoskeys"
+	"github.com/go-pg/pg/v10"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTezosStorage_ResolveUnknownNetwork(t *testing.T) {
+	networks := tezoskeys.NewNetworks()
+	networks.Add("mainnet", nil)
+
+	s := NewTezosStorage(tezoskeys.NewTezosKeys(nil, tezoskeys.WithNetworks(networks)))
+
+	_, err := s.Resolve(context.Background(), "mainnet", "KT1Hkg5qeNhfwpKW4fXvq7HGZB9z2EnmCCA9", "tezos-storage://KT1Hkg5qeNhfwpKW4fXvq7HGZB9z2EnmCCA9.ghostnet/contents")
+	require.Error(t, err)
+
+	e, ok := err.(ResolvingError)
+	require.True(t, ok)
+	assert.Equal(t, ErrorTypeUnknownNetwork, e.Type)
+	assert.True(t, e.IsFatal())
+}
+
+type keysRepo struct {
+	err error
+}
+
+func (r keysRepo) Get(network, address, key string) (models.TezosKey, error) {
+	return models.TezosKey{}, r.err
+}
+
+func (r keysRepo) Save(tk models.TezosKey) error   { return nil }
+func (r keysRepo) Delete(tk models.TezosKey) error { return nil }
+
+func TestTezosStorage_ResolveErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/v1/bigmaps":
+			if r.URL.Query().Get("contract") == "KT1Hkg5qeNhfwpKW4fXvq7HGZB9z2EnmCCA9" {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			_, _ = w.Write([]byte(`[]`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	networks := tezoskeys.NewNetworks()
+	networks.Add("mainnet", api.New(server.URL))
+
+	tests := []struct {
+		name string
+		repo keysRepo
+		link string
+		want ErrorType
+	}{
+		{
+			name: "key isn't found in TzKT",
+			repo: keysRepo{err: pg.ErrNoRows},
+			link: "tezos-storage://KT1RJ6PbjHpwc3M5rw5s2Nbmefwbuwbdxton/contents",
+			want: ErrorTypeKeyTezosNotFond,
+		}, {
+			name: "TzKT fails",
+			repo: keysRepo{err: pg.ErrNoRows},
+			link: "tezos-storage://KT1Hkg5qeNhfwpKW4fXvq7HGZB9z2EnmCCA9/contents",
+			want: ErrorTypeReceiving,
+		}, {
+			name: "database fails",
+			repo: keysRepo{err: errors.New("connection refused")},
+			link: "tezos-storage://KT1Hkg5qeNhfwpKW4fXvq7HGZB9z2EnmCCA9/contents",
+			want: ErrorTypeReceiving,
+		}, {
+			name: "database timeout",
+			repo: keysRepo{err: errors.Wrap(context.DeadlineExceeded, "query")},
+			link: "tezos-storage://KT1Hkg5qeNhfwpKW4fXvq7HGZB9z2EnmCCA9/contents",
+			want: ErrorTypeTimeout,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewTezosStorage(tezoskeys.NewTezosKeys(tt.repo, tezoskeys.WithNetworks(networks)))
+
+			_, err := s.Resolve(context.Background(), "mainnet", "", tt.link)
+			require.Error(t, err)
+
+			e, ok := err.(ResolvingError)
+			require.True(t, ok)
+			assert.Equal(t, tt.want, e.Type)
+		})
+	}
+}
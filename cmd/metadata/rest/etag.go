@@ -0,0 +1,40 @@
+package rest
+
+import (
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"strings"
+
+	"github.com/dipdup-net/metadata/cmd/metadata/models"
+	"github.com/labstack/echo/v4"
+)
+
+// etag - update id is incremented on every change of metadata, so maximum update id with count of items identifies response
+func etag(updateID int64, count int) string {
+	return fmt.Sprintf(`"%d-%d"`, updateID, count)
+}
+
+// batchETag - batches of different tokens are requested by one URL, so hash of requested keys is added to tag
+func batchETag(updateID int64, count int, keys []models.TokenKey) string {
+	h := fnv.New64a()
+	for i := range keys {
+		fmt.Fprintf(h, "%s/%s;", keys[i].Contract, keys[i].TokenID.String())
+	}
+	return fmt.Sprintf(`"%d-%d-%x"`, updateID, count, h.Sum64())
+}
+
+func respond(c echo.Context, tag string, response any) error {
+	c.Response().Header().Set(echo.HeaderCacheControl, "no-cache")
+	c.Response().Header().Set("ETag", tag)
+
+	if match := c.Request().Header.Get("If-None-Match"); match != "" {
+		for _, value := range strings.Split(match, ",") {
+			value = strings.TrimPrefix(strings.TrimSpace(value), "W/")
+			if value == tag || value == "*" {
+				return c.NoContent(http.StatusNotModified)
+			}
+		}
+	}
+	return c.JSON(http.StatusOK, response)
+}
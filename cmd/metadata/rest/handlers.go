@@ -0,0 +1,186 @@
+package rest
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/dipdup-net/metadata/cmd/metadata/models"
+	"github.com/go-pg/pg/v10"
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	defaultLimit  = 100
+	maxLimit      = 1000
+	maxBatchCount = 100
+)
+
+type listRequest struct {
+	After int64 `query:"after"`
+	Limit int   `query:"limit"`
+}
+
+func (req *listRequest) validate() error {
+	if req.After < 0 {
+		return errors.New("after should be non-negative")
+	}
+	if req.Limit <= 0 {
+		req.Limit = defaultLimit
+	}
+	if req.Limit > maxLimit {
+		req.Limit = maxLimit
+	}
+	return nil
+}
+
+type batchRequest struct {
+	Tokens []models.TokenKey `json:"tokens"`
+}
+
+func (req batchRequest) validate() error {
+	if len(req.Tokens) == 0 {
+		return errors.New("empty tokens list")
+	}
+	if len(req.Tokens) > maxBatchCount {
+		return errors.Errorf("too many tokens: maximum is %d", maxBatchCount)
+	}
+	for i := range req.Tokens {
+		if !isContract(req.Tokens[i].Contract) {
+			return errors.Errorf("invalid contract address: %s", req.Tokens[i].Contract)
+		}
+		if !isTokenID(req.Tokens[i].TokenID) {
+			return errors.Errorf("invalid token id: %s", req.Tokens[i].TokenID.String())
+		}
+	}
+	return nil
+}
+
+func (s *Server) getContract(c echo.Context) error {
+	address := c.Param("address")
+	if !isContract(address) {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid contract address")
+	}
+
+	cm, err := s.storage.GetContract(c.Request().Context(), c.Param("network"), address)
+	if err != nil {
+		return handleError(err)
+	}
+
+	return respond(c, etag(cm.UpdateID, 1), newContract(cm))
+}
+
+func (s *Server) getToken(c echo.Context) error {
+	address := c.Param("address")
+	if !isContract(address) {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid contract address")
+	}
+	tokenID, err := decimal.NewFromString(c.Param("token_id"))
+	if err != nil || !isTokenID(tokenID) {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid token id")
+	}
+
+	tm, err := s.storage.GetToken(c.Request().Context(), c.Param("network"), address, tokenID)
+	if err != nil {
+		return handleError(err)
+	}
+
+	return respond(c, etag(tm.UpdateID, 1), newToken(tm))
+}
+
+func (s *Server) batchTokens(c echo.Context) error {
+	var req batchRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if err := req.validate(); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	tokens, err := s.storage.GetTokens(c.Request().Context(), c.Param("network"), req.Tokens)
+	if err != nil {
+		return handleError(err)
+	}
+
+	response := make([]Token, len(tokens))
+	var maxUpdateID int64
+	for i := range tokens {
+		response[i] = newToken(tokens[i])
+		if tokens[i].UpdateID > maxUpdateID {
+			maxUpdateID = tokens[i].UpdateID
+		}
+	}
+	return respond(c, batchETag(maxUpdateID, len(response), req.Tokens), response)
+}
+
+func (s *Server) listContracts(c echo.Context) error {
+	var req listRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if err := req.validate(); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	contracts, err := s.storage.ContractsAfter(c.Request().Context(), c.Param("network"), "", req.After, req.Limit)
+	if err != nil {
+		return handleError(err)
+	}
+
+	page := Page[Contract]{
+		Items:  make([]Contract, len(contracts)),
+		Cursor: req.After,
+	}
+	for i := range contracts {
+		page.Items[i] = newContract(contracts[i])
+		page.Cursor = contracts[i].UpdateID
+	}
+	return respond(c, etag(page.Cursor, len(page.Items)), page)
+}
+
+func (s *Server) listTokens(c echo.Context) error {
+	var req listRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if err := req.validate(); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	tokens, err := s.storage.TokensAfter(c.Request().Context(), c.Param("network"), "", req.After, req.Limit)
+	if err != nil {
+		return handleError(err)
+	}
+
+	page := Page[Token]{
+		Items:  make([]Token, len(tokens)),
+		Cursor: req.After,
+	}
+	for i := range tokens {
+		page.Items[i] = newToken(tokens[i])
+		page.Cursor = tokens[i].UpdateID
+	}
+	return respond(c, etag(page.Cursor, len(page.Items)), page)
+}
+
+func (s *Server) gatewayScores(c echo.Context) error {
+	return c.JSON(http.StatusOK, map[string]any{
+		"gateways": s.gateways.Snapshot(),
+	})
+}
+
+func handleError(err error) error {
+	if errors.Is(err, pg.ErrNoRows) {
+		return echo.NewHTTPError(http.StatusNotFound, "not found")
+	}
+	return err
+}
+
+func isContract(address string) bool {
+	return len(address) == 36 && strings.HasPrefix(address, "KT1")
+}
+
+func isTokenID(tokenID decimal.Decimal) bool {
+	return !tokenID.IsNegative() && tokenID.Equal(tokenID.Truncate(0))
+}
@@ -0,0 +1,30 @@
+package rest
+
+import (
+	"github.com/dipdup-net/metadata/cmd/metadata/broker"
+	"github.com/dipdup-net/metadata/internal/ipfs"
+)
+
+// ServerOption -
+type ServerOption func(*Server)
+
+// WithBroker - enables streaming of metadata changes published to broker
+func WithBroker(b *broker.Broker) ServerOption {
+	return func(s *Server) {
+		s.broker = b
+	}
+}
+
+// WithGatewayScores - enables admin endpoint with scores of IPFS gateways. It requires admin token, see `WithAdminToken`.
+func WithGatewayScores(scores *ipfs.Scores) ServerOption {
+	return func(s *Server) {
+		s.gateways = scores
+	}
+}
+
+// WithAdminToken - sets bearer token of admin endpoints. Admin endpoints aren't served without it.
+func WithAdminToken(token string) ServerOption {
+	return func(s *Server) {
+		s.adminToken = token
+	}
+}
@@ -0,0 +1,96 @@
+package rest
+
+import (
+	stdJSON "encoding/json"
+
+	"github.com/dipdup-net/metadata/cmd/metadata/models"
+	"github.com/shopspring/decimal"
+)
+
+// Contract -
+type Contract struct {
+	Network    string             `json:"network"`
+	Contract   string             `json:"contract"`
+	Link       string             `json:"link,omitempty"`
+	Status     string             `json:"status"`
+	RetryCount int8               `json:"retry_count"`
+	Metadata   stdJSON.RawMessage `json:"metadata,omitempty"`
+	Error      string             `json:"error,omitempty"`
+	Sha256     string             `json:"sha256,omitempty"`
+	Issues     stdJSON.RawMessage `json:"issues,omitempty"`
+	CreatedAt  int64              `json:"created_at"`
+	UpdatedAt  int64              `json:"updated_at"`
+	UpdateID   int64              `json:"update_id"`
+}
+
+func newContract(cm models.ContractMetadata) Contract {
+	contract := Contract{
+		Network:    cm.Network,
+		Contract:   cm.Contract,
+		Link:       cm.Link,
+		Status:     cm.Status.String(),
+		RetryCount: cm.RetryCount,
+		Error:      cm.Error,
+		Sha256:     cm.Sha256,
+		CreatedAt:  cm.CreatedAt,
+		UpdatedAt:  cm.UpdatedAt,
+		UpdateID:   cm.UpdateID,
+	}
+	if !cm.Metadata.IsNull() {
+		contract.Metadata = stdJSON.RawMessage(cm.Metadata)
+	}
+	if !cm.Issues.IsNull() {
+		contract.Issues = stdJSON.RawMessage(cm.Issues)
+	}
+	return contract
+}
+
+// Token -
+type Token struct {
+	Network        string             `json:"network"`
+	Contract       string             `json:"contract"`
+	TokenID        decimal.Decimal    `json:"token_id"`
+	Link           string             `json:"link,omitempty"`
+	Status         string             `json:"status"`
+	RetryCount     int8               `json:"retry_count"`
+	Metadata       stdJSON.RawMessage `json:"metadata,omitempty"`
+	ImageProcessed bool               `json:"image_processed"`
+	Error          string             `json:"error,omitempty"`
+	Sha256         string             `json:"sha256,omitempty"`
+	Source         string             `json:"source,omitempty"`
+	Issues         stdJSON.RawMessage `json:"issues,omitempty"`
+	CreatedAt      int64              `json:"created_at"`
+	UpdatedAt      int64              `json:"updated_at"`
+	UpdateID       int64              `json:"update_id"`
+}
+
+func newToken(tm models.TokenMetadata) Token {
+	token := Token{
+		Network:        tm.Network,
+		Contract:       tm.Contract,
+		TokenID:        tm.TokenID,
+		Link:           tm.Link,
+		Status:         tm.Status.String(),
+		RetryCount:     tm.RetryCount,
+		ImageProcessed: tm.ImageProcessed,
+		Error:          tm.Error,
+		Sha256:         tm.Sha256,
+		Source:         tm.Source,
+		CreatedAt:      tm.CreatedAt,
+		UpdatedAt:      tm.UpdatedAt,
+		UpdateID:       tm.UpdateID,
+	}
+	if !tm.Metadata.IsNull() {
+		token.Metadata = stdJSON.RawMessage(tm.Metadata)
+	}
+	if !tm.Issues.IsNull() {
+		token.Issues = stdJSON.RawMessage(tm.Issues)
+	}
+	return token
+}
+
+// Page - page of items sorted by update id. `Cursor` is the value of `after` parameter for the next page.
+type Page[T any] struct {
+	Items  []T   `json:"items"`
+	Cursor int64 `json:"cursor"`
+}
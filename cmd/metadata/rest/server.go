@@ -0,0 +1,111 @@
+package rest
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dipdup-net/metadata/cmd/metadata/broker"
+	"github.com/dipdup-net/metadata/cmd/metadata/models"
+	"github.com/dipdup-net/metadata/internal/ipfs"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"github.com/shopspring/decimal"
+)
+
+// Storage - read-only access to metadata
+type Storage interface {
+	GetContract(ctx context.Context, network, address string) (models.ContractMetadata, error)
+	GetToken(ctx context.Context, network, contract string, tokenID decimal.Decimal) (models.TokenMetadata, error)
+	GetTokens(ctx context.Context, network string, keys []models.TokenKey) ([]models.TokenMetadata, error)
+	ContractsAfter(ctx context.Context, network, contract string, updateID int64, limit int) ([]models.ContractMetadata, error)
+	TokensAfter(ctx context.Context, network, contract string, updateID int64, limit int) ([]models.TokenMetadata, error)
+}
+
+// Server - HTTP API over metadata database
+type Server struct {
+	storage Storage
+	broker  *broker.Broker
+	bind    string
+
+	gateways   *ipfs.Scores
+	adminToken string
+	echo       *echo.Echo
+}
+
+// New -
+func New(storage Storage, bind string, opts ...ServerOption) *Server {
+	e := echo.New()
+	e.HideBanner = true
+	e.HidePort = true
+	e.Use(middleware.Recover())
+
+	s := &Server{
+		storage: storage,
+		bind:    bind,
+		echo:    e,
+	}
+
+	for i := range opts {
+		opts[i](s)
+	}
+
+	v1 := e.Group("/v1/:network")
+	v1.GET("/contracts", s.listContracts)
+	v1.GET("/contracts/:address", s.getContract)
+	v1.GET("/tokens", s.listTokens)
+	v1.POST("/tokens/batch", s.batchTokens)
+	v1.GET("/tokens/:address/:token_id", s.getToken)
+
+	if s.broker != nil {
+		v1.GET("/stream/:type", s.sse)
+		v1.GET("/ws/:type", s.websocket)
+	}
+
+	if s.adminToken != "" {
+		admin := e.Group("/admin", s.adminAuth)
+		if s.gateways != nil {
+			admin.GET("/ipfs/gateways", s.gatewayScores)
+		}
+	}
+
+	return s
+}
+
+// adminAuth - admin endpoints require `Authorization: Bearer <admin_token>` header
+func (s *Server) adminAuth(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		token, ok := strings.CutPrefix(c.Request().Header.Get(echo.HeaderAuthorization), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.adminToken)) != 1 {
+			return echo.NewHTTPError(http.StatusUnauthorized)
+		}
+		return next(c)
+	}
+}
+
+// Start -
+func (s *Server) Start() {
+	go func() {
+		log.Info().Str("bind", s.bind).Msg("starting REST API...")
+		if err := s.echo.Start(s.bind); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Err(err).Msg("REST API")
+		}
+	}()
+}
+
+// Close -
+func (s *Server) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	return s.echo.Shutdown(ctx)
+}
+
+// ServeHTTP -
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.echo.ServeHTTP(w, r)
+}
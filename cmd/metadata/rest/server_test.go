@@ -0,0 +1,316 @@
+package rest
+
+import (
+	"context"
+	stdJSON "encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dipdup-net/metadata/cmd/metadata/models"
+	"github.com/dipdup-net/metadata/internal/ipfs"
+	"github.com/go-pg/pg/v10"
+	"github.com/labstack/echo/v4"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testContract = "KT1G1cCRNBgQ48mVDjopHjEmTN5Sbtar8nn9"
+	testNetwork  = "mainnet"
+)
+
+type stubStorage struct {
+	contracts []models.ContractMetadata
+	tokens    []models.TokenMetadata
+}
+
+func (s stubStorage) GetContract(ctx context.Context, network, address string) (models.ContractMetadata, error) {
+	for i := range s.contracts {
+		if s.contracts[i].Network == network && s.contracts[i].Contract == address {
+			return s.contracts[i], nil
+		}
+	}
+	return models.ContractMetadata{}, pg.ErrNoRows
+}
+
+func (s stubStorage) GetToken(ctx context.Context, network, contract string, tokenID decimal.Decimal) (models.TokenMetadata, error) {
+	for i := range s.tokens {
+		if s.tokens[i].Network == network && s.tokens[i].Contract == contract && s.tokens[i].TokenID.Equal(tokenID) {
+			return s.tokens[i], nil
+		}
+	}
+	return models.TokenMetadata{}, pg.ErrNoRows
+}
+
+func (s stubStorage) GetTokens(ctx context.Context, network string, keys []models.TokenKey) ([]models.TokenMetadata, error) {
+	result := make([]models.TokenMetadata, 0)
+	for i := range keys {
+		if token, err := s.GetToken(ctx, network, keys[i].Contract, keys[i].TokenID); err == nil {
+			result = append(result, token)
+		}
+	}
+	return result, nil
+}
+
+func (s stubStorage) ContractsAfter(ctx context.Context, network, contract string, updateID int64, limit int) ([]models.ContractMetadata, error) {
+	result := make([]models.ContractMetadata, 0)
+	for i := range s.contracts {
+		if contract != "" && s.contracts[i].Contract != contract {
+			continue
+		}
+		if s.contracts[i].Network == network && s.contracts[i].UpdateID > updateID && len(result) < limit {
+			result = append(result, s.contracts[i])
+		}
+	}
+	return result, nil
+}
+
+func (s stubStorage) TokensAfter(ctx context.Context, network, contract string, updateID int64, limit int) ([]models.TokenMetadata, error) {
+	result := make([]models.TokenMetadata, 0)
+	for i := range s.tokens {
+		if contract != "" && s.tokens[i].Contract != contract {
+			continue
+		}
+		if s.tokens[i].Network == network && s.tokens[i].UpdateID > updateID && len(result) < limit {
+			result = append(result, s.tokens[i])
+		}
+	}
+	return result, nil
+}
+
+func newTestServer(opts ...ServerOption) *Server {
+	return New(stubStorage{
+		contracts: []models.ContractMetadata{
+			{Network: testNetwork, Contract: testContract, Status: models.StatusApplied, Metadata: models.JSONB(`{"name":"test"}`), UpdateID: 10},
+		},
+		tokens: []models.TokenMetadata{
+			{Network: testNetwork, Contract: testContract, TokenID: decimal.NewFromInt(0), Status: models.StatusApplied, UpdateID: 11},
+			{Network: testNetwork, Contract: testContract, TokenID: decimal.NewFromInt(1), Status: models.StatusNew, UpdateID: 12},
+			{Network: testNetwork, Contract: testContract, TokenID: decimal.NewFromInt(2), Status: models.StatusRemoved, UpdateID: 15},
+		},
+	}, "", opts...)
+}
+
+func TestServer(t *testing.T) {
+	tests := []struct {
+		name        string
+		method      string
+		url         string
+		body        string
+		ifNoneMatch string
+		wantCode    int
+		wantETag    string
+		wantBody    string
+	}{
+		{
+			name:     "contract",
+			method:   http.MethodGet,
+			url:      "/v1/mainnet/contracts/" + testContract,
+			wantCode: http.StatusOK,
+			wantETag: `"10-1"`,
+			wantBody: `{"network":"mainnet","contract":"KT1G1cCRNBgQ48mVDjopHjEmTN5Sbtar8nn9","status":"applied","retry_count":0,"metadata":{"name":"test"},"created_at":0,"updated_at":0,"update_id":10}`,
+		}, {
+			name:        "contract not modified",
+			method:      http.MethodGet,
+			url:         "/v1/mainnet/contracts/" + testContract,
+			ifNoneMatch: `W/"10-1"`,
+			wantCode:    http.StatusNotModified,
+			wantETag:    `"10-1"`,
+		}, {
+			name:        "contract modified",
+			method:      http.MethodGet,
+			url:         "/v1/mainnet/contracts/" + testContract,
+			ifNoneMatch: `"9-1"`,
+			wantCode:    http.StatusOK,
+			wantETag:    `"10-1"`,
+		}, {
+			name:     "contract of other network",
+			method:   http.MethodGet,
+			url:      "/v1/ghostnet/contracts/" + testContract,
+			wantCode: http.StatusNotFound,
+		}, {
+			name:     "invalid address",
+			method:   http.MethodGet,
+			url:      "/v1/mainnet/contracts/tz1",
+			wantCode: http.StatusBadRequest,
+		}, {
+			name:     "token",
+			method:   http.MethodGet,
+			url:      "/v1/mainnet/tokens/" + testContract + "/1",
+			wantCode: http.StatusOK,
+			wantETag: `"12-1"`,
+			wantBody: `{"network":"mainnet","contract":"KT1G1cCRNBgQ48mVDjopHjEmTN5Sbtar8nn9","token_id":"1","status":"new","retry_count":0,"image_processed":false,"created_at":0,"updated_at":0,"update_id":12}`,
+		}, {
+			name:     "invalid token id",
+			method:   http.MethodGet,
+			url:      "/v1/mainnet/tokens/" + testContract + "/-1",
+			wantCode: http.StatusBadRequest,
+		}, {
+			name:     "unknown token",
+			method:   http.MethodGet,
+			url:      "/v1/mainnet/tokens/" + testContract + "/100",
+			wantCode: http.StatusNotFound,
+		}, {
+			name:     "batch",
+			method:   http.MethodPost,
+			url:      "/v1/mainnet/tokens/batch",
+			body:     `{"tokens":[{"contract":"KT1G1cCRNBgQ48mVDjopHjEmTN5Sbtar8nn9","token_id":"0"},{"contract":"KT1G1cCRNBgQ48mVDjopHjEmTN5Sbtar8nn9","token_id":"2"},{"contract":"KT1G1cCRNBgQ48mVDjopHjEmTN5Sbtar8nn9","token_id":"5"}]}`,
+			wantCode: http.StatusOK,
+		}, {
+			name:     "empty batch",
+			method:   http.MethodPost,
+			url:      "/v1/mainnet/tokens/batch",
+			body:     `{"tokens":[]}`,
+			wantCode: http.StatusBadRequest,
+		}, {
+			name:     "tokens first page",
+			method:   http.MethodGet,
+			url:      "/v1/mainnet/tokens?limit=2",
+			wantCode: http.StatusOK,
+			wantETag: `"12-2"`,
+		}, {
+			name:     "tokens next page",
+			method:   http.MethodGet,
+			url:      "/v1/mainnet/tokens?after=12&limit=2",
+			wantCode: http.StatusOK,
+			wantETag: `"15-1"`,
+		}, {
+			name:     "tokens last page",
+			method:   http.MethodGet,
+			url:      "/v1/mainnet/tokens?after=15",
+			wantCode: http.StatusOK,
+			wantETag: `"15-0"`,
+			wantBody: `{"items":[],"cursor":15}`,
+		}, {
+			name:     "contracts",
+			method:   http.MethodGet,
+			url:      "/v1/mainnet/contracts",
+			wantCode: http.StatusOK,
+			wantETag: `"10-1"`,
+		}, {
+			name:     "invalid cursor",
+			method:   http.MethodGet,
+			url:      "/v1/mainnet/contracts?after=-1",
+			wantCode: http.StatusBadRequest,
+		},
+	}
+
+	server := newTestServer()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.url, strings.NewReader(tt.body))
+			if tt.body != "" {
+				req.Header.Set("Content-Type", "application/json")
+			}
+			if tt.ifNoneMatch != "" {
+				req.Header.Set("If-None-Match", tt.ifNoneMatch)
+			}
+			rec := httptest.NewRecorder()
+			server.ServeHTTP(rec, req)
+
+			require.Equal(t, tt.wantCode, rec.Code, rec.Body.String())
+			if tt.wantETag != "" {
+				assert.Equal(t, tt.wantETag, rec.Header().Get("ETag"))
+			}
+			if tt.wantBody != "" {
+				assert.JSONEq(t, tt.wantBody, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestServer_batchETag(t *testing.T) {
+	server := newTestServer()
+	batch := func(body, ifNoneMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/v1/mainnet/tokens/batch", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, req)
+		return rec
+	}
+
+	first := batch(`{"tokens":[{"contract":"KT1G1cCRNBgQ48mVDjopHjEmTN5Sbtar8nn9","token_id":"0"},{"contract":"KT1G1cCRNBgQ48mVDjopHjEmTN5Sbtar8nn9","token_id":"2"}]}`, "")
+	require.Equal(t, http.StatusOK, first.Code)
+	tag := first.Header().Get("ETag")
+	assert.True(t, strings.HasPrefix(tag, `"15-2-`), tag)
+
+	same := batch(`{"tokens":[{"contract":"KT1G1cCRNBgQ48mVDjopHjEmTN5Sbtar8nn9","token_id":"0"},{"contract":"KT1G1cCRNBgQ48mVDjopHjEmTN5Sbtar8nn9","token_id":"2"}]}`, tag)
+	assert.Equal(t, http.StatusNotModified, same.Code)
+
+	// the same max update id and count, but other tokens
+	other := batch(`{"tokens":[{"contract":"KT1G1cCRNBgQ48mVDjopHjEmTN5Sbtar8nn9","token_id":"1"},{"contract":"KT1G1cCRNBgQ48mVDjopHjEmTN5Sbtar8nn9","token_id":"2"}]}`, tag)
+	assert.Equal(t, http.StatusOK, other.Code)
+	assert.NotEqual(t, tag, other.Header().Get("ETag"))
+}
+
+func TestServer_pagination(t *testing.T) {
+	server := newTestServer()
+
+	var (
+		after int64
+		ids   []string
+	)
+	for i := 0; i < 10; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/v1/mainnet/tokens?limit=1&after="+decimal.NewFromInt(after).String(), nil)
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, req)
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		var page Page[Token]
+		require.NoError(t, stdJSON.Unmarshal(rec.Body.Bytes(), &page))
+		if len(page.Items) == 0 {
+			break
+		}
+		ids = append(ids, page.Items[0].TokenID.String())
+		after = page.Cursor
+	}
+	assert.Equal(t, []string{"0", "1", "2"}, ids)
+}
+
+func TestServer_gatewayScores(t *testing.T) {
+	scores := ipfs.NewScores([]string{"https://ipfs.io"})
+	scores.Observe("https://ipfs.io", 250*time.Millisecond, 0, 0, nil)
+
+	server := newTestServer(WithGatewayScores(scores), WithAdminToken("secret"))
+	req := httptest.NewRequest(http.MethodGet, "/admin/ipfs/gateways", nil)
+	req.Header.Set(echo.HeaderAuthorization, "Bearer secret")
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"gateways":[{
+		"gateway":"https://ipfs.io",
+		"score":0.8,
+		"latency_ms":250,
+		"error_rate":0,
+		"throughput_bytes_per_ms":0,
+		"requests":1,
+		"consecutive_failures":0,
+		"quarantined":false
+	}]}`, rec.Body.String())
+
+	for _, header := range []string{"", "secret", "Bearer wrong"} {
+		req := httptest.NewRequest(http.MethodGet, "/admin/ipfs/gateways", nil)
+		if header != "" {
+			req.Header.Set(echo.HeaderAuthorization, header)
+		}
+		rec = httptest.NewRecorder()
+		server.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code, header)
+	}
+
+	rec = httptest.NewRecorder()
+	newTestServer(WithGatewayScores(scores)).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/ipfs/gateways", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code, "endpoint is disabled without admin token")
+
+	rec = httptest.NewRecorder()
+	newTestServer().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/ipfs/gateways", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code, "endpoint is disabled without scores")
+}
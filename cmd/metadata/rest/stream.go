@@ -0,0 +1,240 @@
+package rest
+
+import (
+	"context"
+	stdJSON "encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/dipdup-net/metadata/cmd/metadata/broker"
+	"github.com/gorilla/websocket"
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	pingInterval = 30 * time.Second
+	writeTimeout = 10 * time.Second
+)
+
+var upgrader = websocket.Upgrader{
+	// API is public and read-only
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+type streamRequest struct {
+	filter broker.Filter
+	after  int64
+	resume bool
+}
+
+func newStreamRequest(c echo.Context) (streamRequest, error) {
+	req := streamRequest{
+		filter: broker.Filter{
+			Network:  c.Param("network"),
+			Contract: c.QueryParam("contract"),
+		},
+	}
+
+	switch c.Param("type") {
+	case "contracts":
+		req.filter.Type = broker.TypeContract
+	case "tokens":
+		req.filter.Type = broker.TypeToken
+	default:
+		return req, errors.Errorf("unknown stream type: %s", c.Param("type"))
+	}
+
+	if req.filter.Contract != "" && !isContract(req.filter.Contract) {
+		return req, errors.New("invalid contract address")
+	}
+
+	after := c.QueryParam("after")
+	if after == "" {
+		// SSE clients send id of the last received event on reconnect
+		after = c.Request().Header.Get("Last-Event-ID")
+	}
+	if after != "" {
+		value, err := strconv.ParseInt(after, 10, 64)
+		if err != nil || value < 0 {
+			return req, errors.New("after should be non-negative integer")
+		}
+		req.after = value
+		req.resume = true
+	}
+	return req, nil
+}
+
+// stream - sends metadata changes after requested update id from database and then live events from broker
+func (s *Server) stream(ctx context.Context, req streamRequest, send func(broker.Event) error, ping func() error) error {
+	// subscribe before reading database to not miss events published meanwhile
+	sub := s.broker.Subscribe(req.filter)
+	defer sub.Close()
+
+	if req.resume {
+		last, err := s.backfill(ctx, req, send)
+		if err != nil {
+			return err
+		}
+		req.after = last
+	}
+
+	ticker := time.NewTicker(pingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := ping(); err != nil {
+				return err
+			}
+		case event, ok := <-sub.Events():
+			if !ok {
+				return nil
+			}
+			// already sent from database
+			if req.resume && event.UpdateID <= req.after {
+				continue
+			}
+			if err := send(event); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (s *Server) backfill(ctx context.Context, req streamRequest, send func(broker.Event) error) (int64, error) {
+	after := req.after
+	for {
+		events, err := s.eventsAfter(ctx, req.filter, after)
+		if err != nil {
+			return after, err
+		}
+
+		// events are filtered by database
+		for i := range events {
+			after = events[i].UpdateID
+			if err := send(events[i]); err != nil {
+				return after, err
+			}
+		}
+
+		if len(events) < maxLimit {
+			return after, nil
+		}
+	}
+}
+
+func (s *Server) eventsAfter(ctx context.Context, filter broker.Filter, after int64) ([]broker.Event, error) {
+	switch filter.Type {
+	case broker.TypeContract:
+		contracts, err := s.storage.ContractsAfter(ctx, filter.Network, filter.Contract, after, maxLimit)
+		if err != nil {
+			return nil, err
+		}
+		events := make([]broker.Event, len(contracts))
+		for i := range contracts {
+			events[i] = broker.NewContractEvent(contracts[i])
+		}
+		return events, nil
+	case broker.TypeToken:
+		tokens, err := s.storage.TokensAfter(ctx, filter.Network, filter.Contract, after, maxLimit)
+		if err != nil {
+			return nil, err
+		}
+		events := make([]broker.Event, len(tokens))
+		for i := range tokens {
+			events[i] = broker.NewTokenEvent(tokens[i])
+		}
+		return events, nil
+	default:
+		return nil, errors.Errorf("unknown event type: %s", filter.Type)
+	}
+}
+
+func (s *Server) sse(c echo.Context) error {
+	req, err := newStreamRequest(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	response := c.Response()
+	response.Header().Set(echo.HeaderContentType, "text/event-stream")
+	response.Header().Set(echo.HeaderCacheControl, "no-cache")
+	response.Header().Set(echo.HeaderConnection, "keep-alive")
+	response.WriteHeader(http.StatusOK)
+	response.Flush()
+
+	send := func(event broker.Event) error {
+		data, err := stdJSON.Marshal(event)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(response, "id: %d\nevent: %s\ndata: %s\n\n", event.UpdateID, event.Type, data); err != nil {
+			return err
+		}
+		response.Flush()
+		return nil
+	}
+	ping := func() error {
+		if _, err := fmt.Fprint(response, ": ping\n\n"); err != nil {
+			return err
+		}
+		response.Flush()
+		return nil
+	}
+
+	if err := s.stream(c.Request().Context(), req, send, ping); err != nil {
+		log.Err(err).Msg("SSE stream")
+	}
+	return nil
+}
+
+func (s *Server) websocket(c echo.Context) error {
+	req, err := newStreamRequest(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	conn, err := upgrader.Upgrade(c.Response(), c.Request(), nil)
+	if err != nil {
+		return nil
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(c.Request().Context())
+	defer cancel()
+
+	// stream is one-directional, reading is needed only to process control frames and detect closing
+	go func() {
+		defer cancel()
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	send := func(event broker.Event) error {
+		if err := conn.SetWriteDeadline(time.Now().Add(writeTimeout)); err != nil {
+			return err
+		}
+		return conn.WriteJSON(event)
+	}
+	ping := func() error {
+		return conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeTimeout))
+	}
+
+	if err := s.stream(ctx, req, send, ping); err != nil {
+		log.Err(err).Msg("websocket stream")
+		return nil
+	}
+
+	_ = conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(writeTimeout))
+	return nil
+}
@@ -0,0 +1,122 @@
+package rest
+
+import (
+	"bufio"
+	stdJSON "encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dipdup-net/metadata/cmd/metadata/broker"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestStreamServer(t *testing.T) (*broker.Broker, *httptest.Server) {
+	events := broker.New()
+	ts := httptest.NewServer(newTestServer(WithBroker(events)))
+	t.Cleanup(func() {
+		_ = events.Close()
+		ts.Close()
+	})
+	return events, ts
+}
+
+func TestServer_streamBadRequest(t *testing.T) {
+	_, ts := newTestStreamServer(t)
+
+	for _, url := range []string{
+		"/v1/mainnet/stream/blocks",
+		"/v1/mainnet/stream/tokens?contract=tz1",
+		"/v1/mainnet/stream/tokens?after=-1",
+		"/v1/mainnet/ws/contracts?after=abc",
+	} {
+		t.Run(url, func(t *testing.T) {
+			resp, err := http.Get(ts.URL + url)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		})
+	}
+}
+
+func TestServer_sse(t *testing.T) {
+	events, ts := newTestStreamServer(t)
+
+	req, err := http.NewRequest(http.MethodGet, ts.URL+"/v1/mainnet/stream/tokens", nil)
+	require.NoError(t, err)
+	req.Header.Set("Last-Event-ID", "11")
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	reader := bufio.NewReader(resp.Body)
+	assert.Equal(t, "12", readSSE(t, reader).id)
+	assert.Equal(t, "15", readSSE(t, reader).id)
+
+	// update 15 was already sent from database and other networks are filtered
+	events.Publish(
+		broker.Event{Type: broker.TypeToken, Network: "mainnet", Contract: testContract, UpdateID: 15},
+		broker.Event{Type: broker.TypeToken, Network: "ghostnet", Contract: testContract, UpdateID: 16},
+		broker.Event{Type: broker.TypeContract, Network: "mainnet", Contract: testContract, UpdateID: 17},
+		broker.Event{Type: broker.TypeToken, Network: "mainnet", Contract: testContract, Status: "applied", UpdateID: 18},
+	)
+
+	event := readSSE(t, reader)
+	assert.Equal(t, broker.Event{Type: broker.TypeToken, Network: "mainnet", Contract: testContract, Status: "applied", UpdateID: 18}, event.Event)
+}
+
+func TestServer_websocket(t *testing.T) {
+	events, ts := newTestStreamServer(t)
+
+	url := "ws" + strings.TrimPrefix(ts.URL, "http") + "/v1/mainnet/ws/contracts?after=0&contract=" + testContract
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	var event broker.Event
+	require.NoError(t, conn.ReadJSON(&event))
+	assert.Equal(t, broker.Event{Type: broker.TypeContract, Network: "mainnet", Contract: testContract, Status: "applied", UpdateID: 10}, event)
+
+	events.Publish(
+		broker.Event{Type: broker.TypeContract, Network: "mainnet", Contract: "KT1RJ6PbjHpwc3M5rw5s2Nbmefwbuwbdxton", UpdateID: 11},
+		broker.Event{Type: broker.TypeContract, Network: "mainnet", Contract: testContract, Status: "failed", UpdateID: 12},
+	)
+
+	require.NoError(t, conn.ReadJSON(&event))
+	assert.Equal(t, broker.Event{Type: broker.TypeContract, Network: "mainnet", Contract: testContract, Status: "failed", UpdateID: 12}, event)
+}
+
+type sseEvent struct {
+	broker.Event
+
+	id   string
+	name string
+}
+
+func readSSE(t *testing.T, reader *bufio.Reader) sseEvent {
+	var event sseEvent
+	for {
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err)
+		line = strings.TrimSuffix(line, "\n")
+
+		switch {
+		case line == "":
+			return event
+		case strings.HasPrefix(line, "id: "):
+			event.id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "event: "):
+			event.name = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			require.NoError(t, stdJSON.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &event.Event))
+			assert.Equal(t, event.Type, event.name)
+		}
+	}
+}
@@ -1,6 +1,8 @@
 package service
 
 import (
+	"context"
+
 	"github.com/dipdup-net/metadata/cmd/metadata/models"
 	"github.com/dipdup-net/metadata/cmd/metadata/prometheus"
 )
@@ -8,12 +10,10 @@ import (
 // ServiceOption -
 type ServiceOption[T models.Model] func(*Service[T])
 
-// WithMaxRetryCount -
-func WithMaxRetryCount[T models.Model](count int) ServiceOption[T] {
+// WithRevivedErrors - types of errors which failed metadata is periodically returned to new status after
+func WithRevivedErrors[T models.Model](errorTypes []string) ServiceOption[T] {
 	return func(cs *Service[T]) {
-		if count > 0 {
-			cs.maxRetryCount = count
-		}
+		cs.revived = errorTypes
 	}
 }
 
@@ -34,9 +34,23 @@ func WithPrometheus[T models.Model](prom *prometheus.Prometheus, gaugeType strin
 	}
 }
 
-// WithDelay -
-func WithDelay[T models.Model](delay int) ServiceOption[T] {
+// WithPublisher - sets function which is called with metadata in the transaction which saves it, e.g. to write webhooks outbox. Metadata isn't saved if it fails.
+func WithPublisher[T models.Model](publish func(context.Context, []T) error) ServiceOption[T] {
+	return func(cs *Service[T]) {
+		cs.publish = publish
+	}
+}
+
+// WithTransactions - metadata is saved, its jobs are completed and it's published in one transaction
+func WithTransactions[T models.Model](transactions *models.Transactions) ServiceOption[T] {
+	return func(cs *Service[T]) {
+		cs.transactions = transactions
+	}
+}
+
+// WithJobQueue - sets durable queue of metadata instead of polling of repository
+func WithJobQueue[T models.Model](jobs JobQueue[T]) ServiceOption[T] {
 	return func(cs *Service[T]) {
-		cs.delay = delay
+		cs.jobs = jobs
 	}
 }
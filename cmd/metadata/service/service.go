@@ -3,6 +3,7 @@ package service
 import (
 	"context"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/dipdup-net/metadata/cmd/metadata/models"
@@ -15,32 +16,40 @@ import (
 type Service[T models.Model] struct {
 	repo models.ModelRepository[T]
 
-	network       string
-	maxRetryCount int
-	workersCount  int
-	delay         int
-	handler       func(ctx context.Context, t T) error
-	prom          *prometheus.Prometheus
-	gaugeType     string
-	tasks         chan T
-	result        chan T
-	queue         *Queue
-	wg            *sync.WaitGroup
+	network      string
+	workersCount int
+	revived      []string
+	handler      func(ctx context.Context, t T) error
+	publish      func(context.Context, []T) error
+	transactions *models.Transactions
+	prom         *prometheus.Prometheus
+	gaugeType    string
+	tasks        chan T
+	result       chan T
+	queue        *Queue
+	jobs         JobQueue[T]
+	synced       atomic.Int64
+	wg           *sync.WaitGroup
+}
+
+// JobQueue - durable queue of metadata which should be resolved. Service leases metadata from it instead of polling repository if it's set.
+type JobQueue[T models.Model] interface {
+	Sync(ctx context.Context, network string, afterUpdateID int64, priority int) (int64, error)
+	Lease(ctx context.Context, network string, limit int) ([]T, error)
+	Complete(ctx context.Context, metadata []T) error
 }
 
 // NewService -
 func NewService[T models.Model](repo models.ModelRepository[T], handler func(context.Context, T) error, network string, opts ...ServiceOption[T]) *Service[T] {
 	cs := &Service[T]{
-		maxRetryCount: 3,
-		workersCount:  5,
-		repo:          repo,
-		handler:       handler,
-		tasks:         make(chan T, 512),
-		result:        make(chan T, 16),
-		network:       network,
-		queue:         NewQueue(),
-		delay:         10,
-		wg:            new(sync.WaitGroup),
+		workersCount: 5,
+		repo:         repo,
+		handler:      handler,
+		tasks:        make(chan T, 512),
+		result:       make(chan T, 16),
+		network:      network,
+		queue:        NewQueue(),
+		wg:           new(sync.WaitGroup),
 	}
 
 	for i := range opts {
@@ -85,6 +94,11 @@ func (s *Service[T]) manager(ctx context.Context) {
 		return
 	}
 
+	if s.jobs != nil {
+		s.leaser(ctx)
+		return
+	}
+
 	ticker := time.NewTicker(time.Millisecond * 10)
 	defer ticker.Stop()
 
@@ -97,7 +111,7 @@ func (s *Service[T]) manager(ctx context.Context) {
 			if len(s.tasks) > s.workersCount {
 				continue
 			}
-			data, err := s.repo.Get(s.network, models.StatusNew, 200, 0, s.maxRetryCount, s.delay)
+			data, err := s.repo.Get(s.network, models.StatusNew, 200, 0)
 			if err != nil {
 				if !errors.Is(err, context.Canceled) {
 					log.Err(err).Msg("repo.Get")
@@ -122,6 +136,65 @@ func (s *Service[T]) manager(ctx context.Context) {
 	}
 }
 
+// leaser - leases metadata from durable queue. New metadata is enqueued by its `update_id`, queue is fully synced on start and after retries of failed metadata.
+func (s *Service[T]) leaser(ctx context.Context) {
+	lastUpdateID, err := s.jobs.Sync(ctx, s.network, 0, models.JobPriorityBackfill)
+	if err != nil && !errors.Is(err, context.Canceled) {
+		log.Err(err).Msg("jobs.Sync")
+	}
+	s.synced.Store(lastUpdateID)
+
+	ticker := time.NewTicker(time.Millisecond * 10)
+	defer ticker.Stop()
+
+	syncTicker := time.NewTicker(time.Second)
+	defer syncTicker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case <-syncTicker.C:
+			// rows of transactions in progress are synced after they are committed, see `JobQueue.Sync`
+			synced, err := s.jobs.Sync(ctx, s.network, lastUpdateID, models.JobPriorityFresh)
+			if err != nil && !errors.Is(err, context.Canceled) {
+				log.Err(err).Msg("jobs.Sync")
+			}
+			if synced > lastUpdateID {
+				lastUpdateID = synced
+				s.synced.Store(lastUpdateID)
+			}
+
+		case <-ticker.C:
+			if len(s.tasks) > s.workersCount {
+				continue
+			}
+			data, err := s.jobs.Lease(ctx, s.network, s.workersCount*2)
+			if err != nil {
+				if !errors.Is(err, context.Canceled) {
+					log.Err(err).Msg("jobs.Lease")
+				}
+				continue
+			}
+
+			if len(data) == 0 {
+				time.Sleep(time.Second)
+				continue
+			}
+
+			for i := range data {
+				if s.queue.Contains(data[i].GetID()) {
+					continue
+				}
+				s.queue.Add(data[i].GetID())
+
+				s.tasks <- data[i]
+			}
+		}
+	}
+}
+
 func (s *Service[T]) saver(ctx context.Context) {
 	defer s.wg.Done()
 
@@ -141,8 +214,9 @@ func (s *Service[T]) saver(ctx context.Context) {
 				continue
 			}
 
-			if err := s.bulkSave(data); err != nil {
+			if err := s.bulkSave(ctx, data); err != nil {
 				log.Err(err).Msg("bulkSave")
+				s.forget(data)
 				data = nil
 				continue
 			}
@@ -153,8 +227,9 @@ func (s *Service[T]) saver(ctx context.Context) {
 			if len(data) == 0 {
 				continue
 			}
-			if err := s.bulkSave(data); err != nil {
+			if err := s.bulkSave(ctx, data); err != nil {
 				log.Err(err).Msg("bulkSave")
+				s.forget(data)
 				data = nil
 				continue
 			}
@@ -164,8 +239,30 @@ func (s *Service[T]) saver(ctx context.Context) {
 	}
 }
 
-func (s *Service[T]) bulkSave(data []T) error {
-	if err := s.repo.Update(data); err != nil {
+// forget - removes metadata of unsaved batch from in-memory queue, so it's taken again from repository or job queue
+func (s *Service[T]) forget(data []T) {
+	for i := range data {
+		s.queue.Delete(data[i].GetID())
+	}
+}
+
+func (s *Service[T]) bulkSave(ctx context.Context, data []T) error {
+	if err := s.transactions.Run(ctx, func(ctx context.Context) error {
+		if err := s.repo.Update(ctx, data); err != nil {
+			return err
+		}
+		if s.jobs != nil {
+			if err := s.jobs.Complete(ctx, data); err != nil {
+				return errors.Wrap(err, "jobs.Complete")
+			}
+		}
+		if s.publish != nil {
+			if err := s.publish(ctx, data); err != nil {
+				return errors.Wrap(err, "publish")
+			}
+		}
+		return nil
+	}); err != nil {
 		return err
 	}
 
@@ -193,9 +290,10 @@ func (s *Service[T]) worker(ctx context.Context) {
 			return
 		case unresolved := <-s.tasks:
 			resolveCtx, cancel := context.WithTimeout(ctx, time.Minute)
-			defer cancel()
+			err := s.handler(resolveCtx, unresolved)
+			cancel()
 
-			if err := s.handler(resolveCtx, unresolved); err != nil {
+			if err != nil {
 				if errors.Is(err, context.Canceled) {
 					return
 				}
@@ -222,16 +320,30 @@ func (s *Service[T]) lastHope(ctx context.Context) {
 		case <-ctx.Done():
 			return
 		case <-ticker.C:
-			if err := s.repo.Retry(s.network, s.maxRetryCount, 3*time.Hour/time.Second); err != nil {
+			synced := s.synced.Load()
+			if err := s.repo.Retry(s.network, s.revived, 3*time.Hour/time.Second); err != nil {
 				log.Err(err).Msg("repo.Retry")
 				continue
 			}
+			s.syncRetried(ctx, synced)
 		case <-dailyBackFillTicker.C:
-			if err := s.repo.Retry(s.network, s.maxRetryCount, 24*time.Hour/time.Second); err != nil {
+			synced := s.synced.Load()
+			if err := s.repo.Retry(s.network, s.revived, 24*time.Hour/time.Second); err != nil {
 				log.Err(err).Msg("repo.Retry")
 				continue
 			}
+			s.syncRetried(ctx, synced)
 		}
 	}
 
 }
+
+// syncRetried - retried metadata gets new update ids, so only rows after the cursor of leaser are synced. They may be already enqueued by leaser with fresh priority, sync moves them to backfill.
+func (s *Service[T]) syncRetried(ctx context.Context, afterUpdateID int64) {
+	if s.jobs == nil {
+		return
+	}
+	if _, err := s.jobs.Sync(ctx, s.network, afterUpdateID, models.JobPriorityBackfill); err != nil {
+		log.Err(err).Msg("jobs.Sync")
+	}
+}
@@ -0,0 +1,23 @@
+ALTER TABLE contract_metadata ADD COLUMN IF NOT EXISTS sha256 text;
+ALTER TABLE token_metadata ADD COLUMN IF NOT EXISTS sha256 text;
+ALTER TABLE token_metadata ADD COLUMN IF NOT EXISTS source text DEFAULT 'big_map';
+ALTER TABLE contract_metadata ADD COLUMN IF NOT EXISTS issues jsonb;
+ALTER TABLE token_metadata ADD COLUMN IF NOT EXISTS issues jsonb;
+ALTER TABLE token_metadata ADD COLUMN IF NOT EXISTS name text;
+ALTER TABLE token_metadata ADD COLUMN IF NOT EXISTS symbol text;
+ALTER TABLE token_metadata ADD COLUMN IF NOT EXISTS decimals bigint;
+ALTER TABLE token_metadata ADD COLUMN IF NOT EXISTS artifact_uri text;
+ALTER TABLE token_metadata ADD COLUMN IF NOT EXISTS display_uri text;
+ALTER TABLE token_metadata ADD COLUMN IF NOT EXISTS thumbnail_uri text;
+ALTER TABLE token_metadata ADD COLUMN IF NOT EXISTS creators text[];
+ALTER TABLE token_metadata ADD COLUMN IF NOT EXISTS tags text[];
+ALTER TABLE token_metadata ADD COLUMN IF NOT EXISTS is_boolean_amount boolean;
+ALTER TABLE token_metadata ADD COLUMN IF NOT EXISTS royalties jsonb;
+ALTER TABLE token_metadata ADD COLUMN IF NOT EXISTS on_chain_metadata json;
+ALTER TABLE token_metadata ADD COLUMN IF NOT EXISTS off_chain_metadata json;
+ALTER TABLE token_metadata ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (setweight(to_tsvector('simple', coalesce(name, '')), 'A') || setweight(to_tsvector('simple', coalesce(symbol, '')), 'A') || setweight(to_tsvector('simple', coalesce(metadata->>'description', '')), 'B') || setweight(coalesce(to_tsvector('simple', metadata->'tags'), ''::tsvector), 'C')) STORED;
+ALTER TABLE contract_metadata ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (setweight(to_tsvector('simple', coalesce(metadata->>'name', '')), 'A') || setweight(to_tsvector('simple', coalesce(metadata->>'description', '')), 'B') || setweight(coalesce(to_tsvector('simple', metadata->'tags'), ''::tsvector), 'C')) STORED;
+ALTER TABLE contract_metadata ADD COLUMN IF NOT EXISTS next_attempt_at bigint NOT NULL DEFAULT 0;
+ALTER TABLE token_metadata ADD COLUMN IF NOT EXISTS next_attempt_at bigint NOT NULL DEFAULT 0;
+ALTER TABLE contract_metadata ADD COLUMN IF NOT EXISTS error_type text;
+ALTER TABLE token_metadata ADD COLUMN IF NOT EXISTS error_type text;
@@ -0,0 +1,11 @@
+CREATE OR REPLACE FUNCTION public.token_metadata_removed(IN p_item token_metadata)
+    RETURNS boolean
+    LANGUAGE 'plpgsql' STABLE
+    PARALLEL SAFE
+    COST 100
+    
+AS $BODY$
+begin
+    return p_item.status = 4;
+end;
+$BODY$;
\ No newline at end of file
@@ -0,0 +1,19 @@
+package tezoskeys
+
+import (
+	"errors"
+
+	"github.com/go-pg/pg/v10"
+)
+
+// Errors
+var (
+	ErrBigMapNotFound = errors.New("metadata big map not found in TzKT")
+	ErrKeyNotFound    = errors.New("key not found in TzKT")
+	ErrUnknownNetwork = errors.New("unknown network")
+)
+
+// IsNotFound - key is absent both in the database and in TzKT. Other errors of `Get` are failures of the database or TzKT requests.
+func IsNotFound(err error) bool {
+	return errors.Is(err, pg.ErrNoRows) || errors.Is(err, ErrKeyNotFound) || errors.Is(err, ErrBigMapNotFound)
+}
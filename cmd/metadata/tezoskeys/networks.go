@@ -0,0 +1,127 @@
+package tezoskeys
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/dipdup-net/go-lib/tzkt/api"
+	"github.com/rs/zerolog/log"
+)
+
+// learnRetryDelay - chain info of network isn't requested again till the delay passes after failure
+const learnRetryDelay = time.Minute
+
+type network struct {
+	name    string
+	chain   string
+	chainID string
+	tzkt    *api.API
+
+	learned  bool
+	learning bool
+	retryAt  time.Time
+}
+
+// Networks - registry of configured indexers which maps TZIP-16 network names and chain ids to indexer network and its TzKT datasource
+type Networks struct {
+	networks []*network
+	mx       sync.Mutex
+}
+
+// NewNetworks -
+func NewNetworks() *Networks {
+	return &Networks{
+		networks: make([]*network, 0),
+	}
+}
+
+// Add - registers indexer `name` with its TzKT datasource
+func (n *Networks) Add(name string, tzkt *api.API) {
+	n.mx.Lock()
+	defer n.mx.Unlock()
+
+	n.networks = append(n.networks, &network{
+		name: name,
+		tzkt: tzkt,
+	})
+}
+
+// Get - returns configured network name and its TzKT API by TZIP-16 network name or chain id. Chain names and ids are requested from TzKT once.
+// Requests are sent without lock. After failed request the network isn't requested till `learnRetryDelay` passes.
+func (n *Networks) Get(ctx context.Context, value string) (string, *api.API, bool) {
+	if name, tzkt, ok := n.find(value); ok {
+		return name, tzkt, true
+	}
+
+	for _, nw := range n.unlearned(time.Now()) {
+		chain, chainID, err := nw.learn(ctx)
+		n.learned(nw, chain, chainID, err)
+	}
+
+	return n.find(value)
+}
+
+func (n *Networks) find(value string) (string, *api.API, bool) {
+	n.mx.Lock()
+	defer n.mx.Unlock()
+
+	for i := range n.networks {
+		if n.networks[i].name == value {
+			return n.networks[i].name, n.networks[i].tzkt, true
+		}
+	}
+
+	for i := range n.networks {
+		nw := n.networks[i]
+		if nw.learned && (nw.chain == value || nw.chainID == value) {
+			return nw.name, nw.tzkt, true
+		}
+	}
+
+	return "", nil, false
+}
+
+// unlearned - returns networks which should be requested now and marks them as being requested, so concurrent calls don't request them twice
+func (n *Networks) unlearned(now time.Time) []*network {
+	n.mx.Lock()
+	defer n.mx.Unlock()
+
+	result := make([]*network, 0)
+	for i := range n.networks {
+		nw := n.networks[i]
+		if nw.learned || nw.learning || now.Before(nw.retryAt) {
+			continue
+		}
+		nw.learning = true
+		result = append(result, nw)
+	}
+	return result
+}
+
+func (n *Networks) learned(nw *network, chain, chainID string, err error) {
+	n.mx.Lock()
+	defer n.mx.Unlock()
+
+	nw.learning = false
+	if err != nil {
+		nw.retryAt = time.Now().Add(learnRetryDelay)
+		log.Warn().Err(err).Str("network", nw.name).Msg("receiving chain info from TzKT")
+		return
+	}
+	nw.chain = chain
+	nw.chainID = chainID
+	nw.learned = true
+}
+
+func (nw *network) learn(ctx context.Context) (string, string, error) {
+	if nw.tzkt == nil {
+		return "", "", nil
+	}
+
+	head, err := nw.tzkt.GetHead(ctx)
+	if err != nil {
+		return "", "", err
+	}
+	return head.Chain, head.ChainID, nil
+}
@@ -0,0 +1,101 @@
+package tezoskeys
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dipdup-net/go-lib/tzkt/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func newHeadStub(chain, chainID string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/head" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"chain":"` + chain + `","chainId":"` + chainID + `","level":1}`))
+	}))
+}
+
+func TestNetworks_Get(t *testing.T) {
+	mainnet := newHeadStub("mainnet", "NetXdQprcVkpaWU")
+	defer mainnet.Close()
+	ghostnet := newHeadStub("ghostnet", "NetXnHfVqm9iesp")
+	defer ghostnet.Close()
+
+	networks := NewNetworks()
+	networks.Add("mainnet", api.New(mainnet.URL))
+	networks.Add("testnet", api.New(ghostnet.URL))
+
+	tests := []struct {
+		name   string
+		value  string
+		want   string
+		wantOk bool
+	}{
+		{
+			name:   "configured name",
+			value:  "mainnet",
+			want:   "mainnet",
+			wantOk: true,
+		}, {
+			name:   "chain name differs from configured name",
+			value:  "ghostnet",
+			want:   "testnet",
+			wantOk: true,
+		}, {
+			name:   "chain id",
+			value:  "NetXnHfVqm9iesp",
+			want:   "testnet",
+			wantOk: true,
+		}, {
+			name:   "mainnet chain id",
+			value:  "NetXdQprcVkpaWU",
+			want:   "mainnet",
+			wantOk: true,
+		}, {
+			name:  "unknown network",
+			value: "limanet",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, tzkt, ok := networks.Get(context.Background(), tt.value)
+			assert.Equal(t, tt.wantOk, ok)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantOk, tzkt != nil)
+		})
+	}
+}
+
+func TestNetworks_GetBackoff(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	networks := NewNetworks()
+	networks.Add("mainnet", api.New(server.URL))
+
+	for i := 0; i < 3; i++ {
+		_, _, ok := networks.Get(context.Background(), "ghostnet")
+		assert.False(t, ok)
+	}
+	assert.EqualValues(t, 1, atomic.LoadInt32(&requests), "failed network isn't requested again till delay passes")
+
+	networks.mx.Lock()
+	networks.networks[0].retryAt = time.Time{}
+	networks.mx.Unlock()
+
+	_, _, ok := networks.Get(context.Background(), "ghostnet")
+	assert.False(t, ok)
+	assert.EqualValues(t, 2, atomic.LoadInt32(&requests), "network is requested after delay")
+}
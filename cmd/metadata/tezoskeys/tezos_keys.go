@@ -1,11 +1,29 @@
 package tezoskeys
 
 import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/dipdup-net/go-lib/tzkt/api"
 	"github.com/dipdup-net/go-lib/tzkt/data"
 	"github.com/dipdup-net/metadata/cmd/metadata/helpers"
 	"github.com/dipdup-net/metadata/cmd/metadata/models"
+	"github.com/go-pg/pg/v10"
+	"github.com/pkg/errors"
+)
+
+// keys received from TzKT aren't updated by scanner, so they're cached in memory for limited time
+const (
+	defaultRemoteTTL = 10 * time.Minute
+	maxRemoteKeys    = 10000
 )
 
+type remoteKey struct {
+	item      models.TezosKey
+	expiresAt time.Time
+}
+
 // TezosKeysAction -
 type TezosKeysAction struct {
 	Action models.Action
@@ -14,16 +32,64 @@ type TezosKeysAction struct {
 
 // TezosKeys -
 type TezosKeys struct {
-	repo *models.TezosKeys
+	repo     models.TezosKeysRepository
+	changes  *models.Changes
+	networks *Networks
+
+	remoteTTL time.Duration
+	remote    map[string]remoteKey
+	mx        sync.Mutex
+	now       func() time.Time
+}
+
+// TezosKeysOption -
+type TezosKeysOption func(*TezosKeys)
+
+// WithNetworks - sets registry of configured networks which is used to resolve keys of other networks and to receive keys absent in the database from TzKT
+func WithNetworks(networks *Networks) TezosKeysOption {
+	return func(tk *TezosKeys) {
+		tk.networks = networks
+	}
+}
+
+// WithChanges - tracks changes of keys to revert them on reorg
+func WithChanges(changes *models.Changes) TezosKeysOption {
+	return func(tk *TezosKeys) {
+		tk.changes = changes
+	}
+}
+
+// WithRemoteTTL - sets lifetime of keys received from TzKT in memory cache
+func WithRemoteTTL(ttl time.Duration) TezosKeysOption {
+	return func(tk *TezosKeys) {
+		if ttl > 0 {
+			tk.remoteTTL = ttl
+		}
+	}
 }
 
 // NewTezosKeys -
-func NewTezosKeys(repo *models.TezosKeys) *TezosKeys {
-	return &TezosKeys{repo}
+func NewTezosKeys(repo models.TezosKeysRepository, opts ...TezosKeysOption) *TezosKeys {
+	tk := &TezosKeys{
+		repo:      repo,
+		remoteTTL: defaultRemoteTTL,
+		remote:    make(map[string]remoteKey),
+		now:       time.Now,
+	}
+
+	for i := range opts {
+		opts[i](tk)
+	}
+
+	return tk
 }
 
 // Add -
 func (tk *TezosKeys) Add(update data.BigMapUpdate, network string) error {
+	if update.Action == "remove_key" {
+		return tk.remove(update, network)
+	}
+
 	val := string(update.Content.Value)
 	if !helpers.IsJSON(val) { // wait only JSON
 		return nil
@@ -33,16 +99,97 @@ func (tk *TezosKeys) Add(update data.BigMapUpdate, network string) error {
 		return err
 	}
 
+	if tk.changes != nil {
+		if err := tk.changes.TrackTezosKey(update.Level, item); err != nil {
+			return err
+		}
+	}
+
 	switch update.Action {
 	case "add_key", "update_key":
 		return tk.repo.Save(item)
-	case "remove_key":
-		return tk.repo.Delete(item)
 	}
 	return nil
 }
 
-// Get -
-func (tk *TezosKeys) Get(network, address, key string) (models.TezosKey, error) {
-	return tk.repo.Get(network, address, key)
+func (tk *TezosKeys) remove(update data.BigMapUpdate, network string) error {
+	item := models.TezosKey{
+		Network: network,
+		Address: update.Contract.Address,
+		Key:     helpers.Trim(string(update.Content.Key)),
+	}
+
+	if tk.changes != nil {
+		if err := tk.changes.TrackTezosKey(update.Level, item); err != nil {
+			return err
+		}
+	}
+
+	return tk.repo.Delete(item)
+}
+
+// Get - returns key from the database. `network` may be TZIP-16 network name or chain id of any configured indexer.
+// If key is not found in the database it's requested from TzKT of the network. Such keys are out of indexer filters, changes of them aren't tracked,
+// so they aren't saved to the database and are cached in memory for `remoteTTL` only.
+func (tk *TezosKeys) Get(ctx context.Context, network, address, key string) (models.TezosKey, error) {
+	var tzkt *api.API
+	if tk.networks != nil {
+		name, source, ok := tk.networks.Get(ctx, network)
+		if !ok {
+			return models.TezosKey{}, errors.Wrap(ErrUnknownNetwork, network)
+		}
+		network, tzkt = name, source
+	}
+
+	item, err := tk.repo.Get(network, address, key)
+	if err == nil || !errors.Is(err, pg.ErrNoRows) {
+		return item, err
+	}
+
+	if tzkt == nil || address == "" {
+		return item, err
+	}
+
+	id := network + "/" + address + "/" + key
+	if cached, ok := tk.cached(id); ok {
+		return cached, nil
+	}
+
+	item, err = receive(ctx, tzkt, network, address, key)
+	if err != nil {
+		return item, err
+	}
+	tk.cache(id, item)
+	return item, nil
+}
+
+func (tk *TezosKeys) cached(id string) (models.TezosKey, bool) {
+	tk.mx.Lock()
+	defer tk.mx.Unlock()
+
+	cached, ok := tk.remote[id]
+	if !ok || !tk.now().Before(cached.expiresAt) {
+		return models.TezosKey{}, false
+	}
+	return cached.item, true
+}
+
+func (tk *TezosKeys) cache(id string, item models.TezosKey) {
+	tk.mx.Lock()
+	defer tk.mx.Unlock()
+
+	now := tk.now()
+	if len(tk.remote) >= maxRemoteKeys {
+		for cachedID, cached := range tk.remote {
+			if !now.Before(cached.expiresAt) {
+				delete(tk.remote, cachedID)
+			}
+		}
+	}
+	if len(tk.remote) < maxRemoteKeys {
+		tk.remote[id] = remoteKey{
+			item:      item,
+			expiresAt: now.Add(tk.remoteTTL),
+		}
+	}
 }
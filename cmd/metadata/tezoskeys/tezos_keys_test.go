@@ -0,0 +1,105 @@
+package tezoskeys
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dipdup-net/go-lib/tzkt/api"
+	"github.com/dipdup-net/metadata/cmd/metadata/models"
+	"github.com/go-pg/pg/v10"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memoryKeys struct {
+	items map[string]models.TezosKey
+	err   error
+}
+
+func newMemoryKeys() *memoryKeys {
+	return &memoryKeys{
+		items: make(map[string]models.TezosKey),
+	}
+}
+
+func (m *memoryKeys) id(network, address, key string) string {
+	return network + "/" + address + "/" + key
+}
+
+func (m *memoryKeys) Get(network, address, key string) (models.TezosKey, error) {
+	if m.err != nil {
+		return models.TezosKey{}, m.err
+	}
+	item, ok := m.items[m.id(network, address, key)]
+	if !ok {
+		return models.TezosKey{}, pg.ErrNoRows
+	}
+	return item, nil
+}
+
+func (m *memoryKeys) Save(tk models.TezosKey) error {
+	m.items[m.id(tk.Network, tk.Address, tk.Key)] = tk
+	return nil
+}
+
+func (m *memoryKeys) Delete(tk models.TezosKey) error {
+	delete(m.items, m.id(tk.Network, tk.Address, tk.Key))
+	return nil
+}
+
+func TestTezosKeys_Get(t *testing.T) {
+	stub := newTzKTStub()
+	defer stub.Close()
+
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		stub.Config.Handler.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	networks := NewNetworks()
+	networks.Add("mainnet", api.New(server.URL))
+
+	repo := newMemoryKeys()
+	tk := NewTezosKeys(repo, WithNetworks(networks))
+	want := models.TezosKey{
+		Network: "mainnet",
+		Address: "KT1Remote",
+		Key:     "contents",
+		Value:   []byte(`{"name":"test"}`),
+	}
+
+	got, err := tk.Get(context.Background(), "mainnet", "KT1Remote", "contents")
+	require.NoError(t, err)
+	assert.Equal(t, want, got)
+	assert.EqualValues(t, 2, atomic.LoadInt32(&requests), "big map and its key are requested")
+	assert.NotContains(t, repo.items, "mainnet/KT1Remote/contents", "received key isn't saved to the database")
+
+	got, err = tk.Get(context.Background(), "mainnet", "KT1Remote", "contents")
+	require.NoError(t, err)
+	assert.Equal(t, want, got)
+	assert.EqualValues(t, 2, atomic.LoadInt32(&requests), "cached key is returned without requests")
+
+	now := time.Now()
+	tk.now = func() time.Time { return now.Add(defaultRemoteTTL) }
+	got, err = tk.Get(context.Background(), "mainnet", "KT1Remote", "contents")
+	require.NoError(t, err)
+	assert.Equal(t, want, got)
+	assert.EqualValues(t, 4, atomic.LoadInt32(&requests), "expired key is requested again")
+
+	_, err = tk.Get(context.Background(), "mainnet", "KT1Remote", "unknown")
+	require.ErrorIs(t, err, ErrKeyNotFound)
+	assert.True(t, IsNotFound(err))
+	assert.NotContains(t, repo.items, "mainnet/KT1Remote/unknown")
+
+	repo.err = errors.New("connection refused")
+	_, err = tk.Get(context.Background(), "mainnet", "KT1Remote", "contents")
+	require.Error(t, err)
+	assert.False(t, IsNotFound(err), "database failure isn't missing key")
+}
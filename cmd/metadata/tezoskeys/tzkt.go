@@ -0,0 +1,51 @@
+package tezoskeys
+
+import (
+	"context"
+
+	"github.com/dipdup-net/go-lib/tzkt/api"
+	"github.com/dipdup-net/metadata/cmd/metadata/helpers"
+	"github.com/dipdup-net/metadata/cmd/metadata/models"
+	"github.com/pkg/errors"
+)
+
+const bigMapMetadata = "metadata"
+
+func receive(ctx context.Context, tzkt *api.API, network, address, key string) (models.TezosKey, error) {
+	item := models.TezosKey{
+		Network: network,
+		Address: address,
+		Key:     key,
+	}
+
+	bigMaps, err := tzkt.GetBigmaps(ctx, map[string]string{
+		"contract": address,
+		"path":     bigMapMetadata,
+		"limit":    "1",
+	})
+	if err != nil {
+		return item, errors.Wrap(err, "receiving metadata big map from TzKT")
+	}
+	if len(bigMaps) == 0 {
+		return item, errors.Wrapf(ErrBigMapNotFound, "contract=%s", address)
+	}
+
+	keys, err := tzkt.GetBigmapKeys(ctx, uint64(bigMaps[0].Ptr), map[string]string{
+		"key":    key,
+		"active": "true",
+		"limit":  "1",
+	})
+	if err != nil {
+		return item, errors.Wrap(err, "receiving metadata key from TzKT")
+	}
+	if len(keys) == 0 {
+		return item, errors.Wrapf(ErrKeyNotFound, "contract=%s big_map=%d key=%s", address, bigMaps[0].Ptr, key)
+	}
+
+	value, err := helpers.Decode(keys[0].Value)
+	if err != nil {
+		return item, err
+	}
+	item.Value = value
+	return item, nil
+}
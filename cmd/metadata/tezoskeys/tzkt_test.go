@@ -0,0 +1,83 @@
+package tezoskeys
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dipdup-net/go-lib/tzkt/api"
+	"github.com/dipdup-net/metadata/cmd/metadata/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTzKTStub() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/v1/bigmaps":
+			if r.URL.Query().Get("contract") != "KT1Remote" || r.URL.Query().Get("path") != "metadata" {
+				_, _ = w.Write([]byte(`[]`))
+				return
+			}
+			_, _ = w.Write([]byte(`[{"ptr":42,"path":"metadata"}]`))
+		case "/v1/bigmaps/42/keys":
+			if r.URL.Query().Get("key") != "contents" {
+				_, _ = w.Write([]byte(`[]`))
+				return
+			}
+			_, _ = w.Write([]byte(`[{"id":1,"active":true,"key":"contents","value":"7b226e616d65223a2274657374227d"}]`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func TestTezosKeys_receive(t *testing.T) {
+	server := newTzKTStub()
+	defer server.Close()
+
+	tzkt := api.New(server.URL)
+
+	tests := []struct {
+		name    string
+		address string
+		key     string
+		want    models.TezosKey
+		wantErr error
+	}{
+		{
+			name:    "key exists",
+			address: "KT1Remote",
+			key:     "contents",
+			want: models.TezosKey{
+				Network: "mainnet",
+				Address: "KT1Remote",
+				Key:     "contents",
+				Value:   []byte(`{"name":"test"}`),
+			},
+		}, {
+			name:    "unknown key",
+			address: "KT1Remote",
+			key:     "unknown",
+			wantErr: ErrKeyNotFound,
+		}, {
+			name:    "contract without metadata",
+			address: "KT1Other",
+			key:     "contents",
+			wantErr: ErrBigMapNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := receive(context.Background(), tzkt, "mainnet", tt.address, tt.key)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
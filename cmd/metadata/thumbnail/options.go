@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/dipdup-net/metadata/cmd/metadata/prometheus"
+	"github.com/dipdup-net/metadata/internal/ipfs"
 )
 
 // ThumbnailOption -
@@ -16,6 +17,13 @@ func WithPrometheus(prom *prometheus.Prometheus) ThumbnailOption {
 	}
 }
 
+// WithGatewayScores - gateways are requested in order of their scores instead of random one
+func WithGatewayScores(scores *ipfs.Scores) ThumbnailOption {
+	return func(m *Service) {
+		m.scores = scores
+	}
+}
+
 // WithWorkers -
 func WithWorkers(workersCount int) ThumbnailOption {
 	return func(m *Service) {
@@ -28,6 +28,7 @@ type Service struct {
 	cursor   uint64
 	limit    int
 	gateways []string
+	scores   *ipfs.Scores
 	storage  storage.Storage
 	db       *models.Tokens
 	prom     *prometheus.Prometheus
@@ -182,26 +183,56 @@ func (s *Service) Close() error {
 }
 
 func (s *Service) processLink(ctx context.Context, link, mime, filename string) error {
+	body, err := s.open(ctx, link)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	reader := io.LimitReader(body, s.maxFileSizeMB*1048576)
+	return s.createThumbnail(reader, mime, filename)
+}
+
+// processGatewayLink - processes link of IPFS gateway. Response of gateway is observed by its scores, failures of thumbnail creating aren't counted.
+func (s *Service) processGatewayLink(ctx context.Context, gateway, link, mime, filename string) error {
+	if s.scores == nil {
+		return s.processLink(ctx, link, mime, filename)
+	}
+
+	start := time.Now()
+	body, err := s.open(ctx, link)
+	if !errors.Is(ctx.Err(), context.Canceled) {
+		latency := time.Since(start)
+		s.scores.Observe(gateway, latency, 0, latency, err)
+	}
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	reader := io.LimitReader(body, s.maxFileSizeMB*1048576)
+	return s.createThumbnail(reader, mime, filename)
+}
+
+func (s *Service) open(ctx context.Context, link string) (io.ReadCloser, error) {
 	if _, err := url.ParseRequestURI(link); err != nil {
-		return errors.Errorf("Invalid file link: %s", link)
+		return nil, errors.Errorf("Invalid file link: %s", link)
 	}
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, link, nil)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return errors.Errorf("Invalid status code: %s", resp.Status)
+		resp.Body.Close()
+		return nil, errors.Errorf("Invalid status code: %s", resp.Status)
 	}
-
-	reader := io.LimitReader(resp.Body, s.maxFileSizeMB*1048576)
-	return s.createThumbnail(reader, mime, filename)
+	return resp.Body, nil
 }
 
 func (s *Service) createThumbnail(reader io.Reader, mime, filename string) error {
@@ -241,9 +272,12 @@ func (s *Service) resolve(ctx context.Context, link, mime, filename string) erro
 		}
 
 		gateways := ipfs.ShuffleGateways(s.gateways)
+		if s.scores != nil {
+			gateways = s.scores.Order()
+		}
 		for _, gateway := range gateways {
 			link := ipfs.Link(gateway, hash)
-			if err := s.processLink(ctx, link, mime, filename); err != nil {
+			if err := s.processGatewayLink(ctx, gateway, link, mime, filename); err != nil {
 				log.Err(err).Fields(map[string]interface{}{
 					"link": link,
 					"mime": mime,
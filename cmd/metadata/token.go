@@ -2,9 +2,10 @@ package main
 
 import (
 	"context"
-	"encoding/hex"
+	stdJSON "encoding/json"
 	"fmt"
 	"net/url"
+	"time"
 	"unicode/utf8"
 
 	jsoniter "github.com/json-iterator/go"
@@ -16,70 +17,29 @@ import (
 	"github.com/dipdup-net/metadata/cmd/metadata/models"
 	"github.com/dipdup-net/metadata/cmd/metadata/prometheus"
 	"github.com/dipdup-net/metadata/cmd/metadata/resolver"
+	"github.com/dipdup-net/metadata/cmd/metadata/validation"
 )
 
 var json = jsoniter.ConfigCompatibleWithStandardLibrary
 
-// TokenInfo -
-type TokenInfo struct {
-	TokenID   decimal.Decimal   `json:"token_id"`
-	TokenInfo map[string]string `json:"token_info"`
-	Link      string            `json:"-"`
-}
-
-// UnmarshalJSON -
-func (tokenInfo *TokenInfo) UnmarshalJSON(data []byte) error {
-	var generalTokenInfo map[string]interface{}
-	if err := json.Unmarshal(data, &generalTokenInfo); err != nil {
-		return err
-	}
-	for _, value := range generalTokenInfo {
-		switch typedValue := value.(type) {
-		case string:
-			tokenID, err := decimal.NewFromString(typedValue)
-			if err != nil {
-				return err
-			}
-			tokenInfo.TokenID = tokenID
-		case map[string]interface{}:
-			tokenInfo.TokenInfo = make(map[string]string)
-			for infoKey, infoValue := range typedValue {
-				stringValue, ok := infoValue.(string)
-				if !ok {
-					continue
-				}
-				tokenInfo.TokenInfo[infoKey] = stringValue
-			}
-		}
+func (indexer *Indexer) processTokenMetadata(update api.BigMapUpdate) (*models.TokenMetadata, error) {
+	if update.Content == nil {
+		return nil, nil
 	}
 
-	if link, ok := tokenInfo.TokenInfo[""]; ok {
-		b, err := hex.DecodeString(link)
+	if update.Action == actionRemoveKey {
+		tokenID, err := decimal.NewFromString(helpers.Trim(string(update.Content.Key)))
 		if err != nil {
-			return err
-		}
-		if utf8.Valid(b) {
-			tokenInfo.Link = string(b)
+			return nil, errors.Wrap(err, "token_id of removed key")
 		}
-		delete(tokenInfo.TokenInfo, "")
-	}
-
-	decodeMap(tokenInfo.TokenInfo)
-
-	return nil
-}
-
-func decodeMap(m map[string]string) {
-	for key, value := range m {
-		if b, err := hex.DecodeString(value); err == nil && utf8.Valid(b) {
-			m[key] = string(b)
-		}
-	}
-}
-
-func (indexer *Indexer) processTokenMetadata(update api.BigMapUpdate) (*models.TokenMetadata, error) {
-	if update.Content == nil {
-		return nil, nil
+		indexer.prom.IncrementMetadataCounter(indexer.network, prometheus.MetadataTypeToken, models.StatusRemoved.String())
+		return &models.TokenMetadata{
+			Network:  indexer.network,
+			Contract: update.Contract.Address,
+			TokenID:  tokenID,
+			Status:   models.StatusRemoved,
+			Source:   models.TokenSourceBigMap,
+		}, nil
 	}
 
 	var tokenInfo TokenInfo
@@ -87,6 +47,10 @@ func (indexer *Indexer) processTokenMetadata(update api.BigMapUpdate) (*models.T
 		return nil, err
 	}
 
+	return indexer.newTokenMetadata(update.Contract.Address, tokenInfo, models.TokenSourceBigMap)
+}
+
+func (indexer *Indexer) newTokenMetadata(contract string, tokenInfo TokenInfo, source string) (*models.TokenMetadata, error) {
 	metadata, err := json.Marshal(tokenInfo.TokenInfo)
 	if err != nil {
 		return nil, err
@@ -94,17 +58,27 @@ func (indexer *Indexer) processTokenMetadata(update api.BigMapUpdate) (*models.T
 
 	token := models.TokenMetadata{
 		Network:  indexer.network,
-		Contract: update.Contract.Address,
+		Contract: contract,
 		TokenID:  tokenInfo.TokenID,
 		Status:   models.StatusNew,
+		Source:   source,
 	}
+	indexer.countIssues(prometheus.MetadataTypeToken, tokenInfo.Issues)
+	token.Issues = indexer.marshalIssues(prometheus.MetadataTypeToken, tokenInfo.Issues)
+
 	if len(metadata) > 2 {
 		token.Metadata = helpers.Escape(metadata)
+		token.OnChainMetadata = token.Metadata
 	}
 
 	if _, err := url.ParseRequestURI(tokenInfo.Link); err != nil {
 		token.Status = models.StatusApplied
 		token.RetryCount = 1
+		// token has on-chain metadata only, so it's the final document
+		if !token.Metadata.IsNull() {
+			token.Issues = indexer.validate(prometheus.MetadataTypeToken, token.Metadata, validation.Token, tokenInfo.Issues)
+		}
+		token.Normalize()
 		indexer.prom.IncrementMetadataCounter(indexer.network, prometheus.MetadataTypeToken, token.Status.String())
 	} else {
 		token.Link = tokenInfo.Link
@@ -133,16 +107,15 @@ func (indexer *Indexer) resolveTokenMetadata(ctx context.Context, tm *models.Tok
 			return err
 		}
 		tm.Error = err.Error()
+		tm.ErrorType = string(resolver.Classify(err))
+		next, retry := indexer.retrier.Next(err, int(tm.RetryCount), time.Now())
 		if e, ok := err.(resolver.ResolvingError); ok {
 			indexer.prom.IncrementErrorCounter(indexer.network, e)
 			err = e.Err
-
-			if e.IsFatal() {
-				tm.RetryCount = int8(indexer.settings.MaxRetryCountOnError)
-			}
 		}
 
-		if tm.RetryCount < int8(indexer.settings.MaxRetryCountOnError) {
+		if retry {
+			tm.NextAttemptAt = next.Unix()
 			indexer.logTokenMetadata(*tm, fmt.Sprintf("retry: %s", err.Error()))
 		} else {
 			tm.Status = models.StatusFailed
@@ -150,12 +123,24 @@ func (indexer *Indexer) resolveTokenMetadata(ctx context.Context, tm *models.Tok
 		}
 	} else {
 		if utf8.Valid(resolved.Data) {
+			// rows created before on-chain metadata was stored separately keep it in `metadata` until they're applied
+			if tm.OnChainMetadata.IsNull() && tm.Status != models.StatusApplied {
+				tm.OnChainMetadata = tm.Metadata
+			}
+
 			tm.Status = models.StatusApplied
 			tm.Error = ""
-			tm.Metadata = resolved.Data
+			tm.ErrorType = ""
+			tm.OffChainMetadata = resolved.Data
+			tm.Metadata = mergeTokenMetadata(tm.OnChainMetadata, resolved.Data)
+			tm.Sha256 = resolved.Sha256
+			// merged document is validated: required fields may be on-chain only
+			tm.Issues = indexer.validate(prometheus.MetadataTypeToken, tm.Metadata, validation.Token, tokenInfoIssues(tm.Issues))
+			tm.Normalize()
 			indexer.log().Int64("response_time", resolved.ResponseTime).Str("contract", tm.Contract).Str("token_id", tm.TokenID.String()).Msg("resolved token metadata")
 		} else {
 			tm.Error = "invalid json"
+			tm.ErrorType = string(resolver.ErrorTypeInvalidJSON)
 			tm.Status = models.StatusFailed
 		}
 	}
@@ -168,6 +153,33 @@ func (indexer *Indexer) resolveTokenMetadata(ctx context.Context, tm *models.Tok
 	return nil
 }
 
+// mergeTokenMetadata - merges on-chain `token_info` fields with off-chain document. By TZIP-12 on-chain fields take precedence. If off-chain document is not JSON object it's returned as is.
+func mergeTokenMetadata(onChain, offChain models.JSONB) models.JSONB {
+	if onChain.IsNull() {
+		return offChain
+	}
+
+	var fields map[string]stdJSON.RawMessage
+	if err := json.Unmarshal(onChain, &fields); err != nil || len(fields) == 0 {
+		return offChain
+	}
+
+	var document map[string]stdJSON.RawMessage
+	if err := json.Unmarshal(offChain, &document); err != nil || document == nil {
+		return offChain
+	}
+
+	for key, value := range fields {
+		document[key] = value
+	}
+
+	merged, err := json.Marshal(document)
+	if err != nil {
+		return offChain
+	}
+	return merged
+}
+
 var legacyTokens = []*models.TokenMetadata{
 	{
 		Network:        "mainnet",
@@ -293,6 +305,7 @@ var legacyContracts = map[string]struct{}{
 func (indexer *Indexer) initialTokenMetadata(ctx context.Context) error {
 	for i := range legacyTokens {
 		legacyTokens[i].UpdateID = models.TokenUpdateID.Increment()
+		legacyTokens[i].Normalize()
 	}
-	return indexer.db.Tokens.Save(legacyTokens)
+	return indexer.db.Tokens.Save(ctx, legacyTokens)
 }
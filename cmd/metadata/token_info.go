@@ -0,0 +1,247 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	stdJSON "encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+
+	"github.com/dipdup-net/metadata/cmd/metadata/models"
+	"github.com/dipdup-net/metadata/cmd/metadata/validation"
+)
+
+const tokenInfoPath = "token_info"
+
+// TokenInfo - decoded `Pair nat (map string bytes)` value of `token_metadata` big map or off-chain view. Values of `TokenInfo` are strings, or `stdJSON.RawMessage` if bytes contain JSON object or array. Bytes which are not UTF-8 string are kept as 0x-prefixed hex.
+type TokenInfo struct {
+	TokenID   decimal.Decimal   `json:"token_id"`
+	TokenInfo map[string]any    `json:"token_info"`
+	Link      string            `json:"-"`
+	Issues    validation.Issues `json:"-"`
+}
+
+// NewTokenInfo - decodes hex-encoded values of `token_info` map
+func NewTokenInfo(tokenID decimal.Decimal, info map[string]string) TokenInfo {
+	tokenInfo := TokenInfo{
+		TokenID:   tokenID,
+		TokenInfo: make(map[string]any, len(info)),
+	}
+	for key, value := range info {
+		tokenInfo.setBytes(key, value)
+	}
+	tokenInfo.sortIssues()
+	return tokenInfo
+}
+
+type michelineNode struct {
+	Prim   string               `json:"prim,omitempty"`
+	Args   []stdJSON.RawMessage `json:"args,omitempty"`
+	Int    *string              `json:"int,omitempty"`
+	String *string              `json:"string,omitempty"`
+	Bytes  *string              `json:"bytes,omitempty"`
+}
+
+// UnmarshalJSON - supports TzKT JSON (`{"token_id": "0", "token_info": {"": "68747470..."}}`) and Micheline (`{"prim": "Pair", "args": [{"int": "0"}, [{"prim": "Elt", ...}]]}`) shapes
+func (tokenInfo *TokenInfo) UnmarshalJSON(data []byte) error {
+	tokenInfo.TokenInfo = make(map[string]any)
+	defer tokenInfo.sortIssues()
+
+	data = bytes.TrimSpace(data)
+	if bytes.HasPrefix(data, []byte("[")) {
+		var args []stdJSON.RawMessage
+		if err := json.Unmarshal(data, &args); err != nil {
+			return err
+		}
+		return tokenInfo.unmarshalPair(args)
+	}
+
+	var fields map[string]stdJSON.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	if _, ok := fields["prim"]; ok {
+		var pair michelineNode
+		if err := json.Unmarshal(data, &pair); err != nil {
+			return err
+		}
+		if pair.Prim != "Pair" {
+			return errors.Errorf("expected Pair, got %s", pair.Prim)
+		}
+		return tokenInfo.unmarshalPair(pair.Args)
+	}
+
+	for _, value := range fields {
+		value = bytes.TrimSpace(value)
+		switch {
+		case bytes.HasPrefix(value, []byte(`"`)):
+			var str string
+			if err := json.Unmarshal(value, &str); err != nil {
+				return err
+			}
+			tokenID, err := decimal.NewFromString(str)
+			if err != nil {
+				return err
+			}
+			tokenInfo.TokenID = tokenID
+		case bytes.HasPrefix(value, []byte("{")):
+			if err := tokenInfo.unmarshalMap(value); err != nil {
+				return err
+			}
+		case bytes.HasPrefix(value, []byte("[")):
+			if err := tokenInfo.unmarshalElts(value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (tokenInfo *TokenInfo) unmarshalPair(args []stdJSON.RawMessage) error {
+	if len(args) != 2 {
+		return errors.Errorf("expected pair of token_id and token_info, got %d arguments", len(args))
+	}
+
+	var id michelineNode
+	if err := json.Unmarshal(args[0], &id); err != nil {
+		return err
+	}
+	if id.Int == nil {
+		return errors.New("token_id is not an integer")
+	}
+	tokenID, err := decimal.NewFromString(*id.Int)
+	if err != nil {
+		return err
+	}
+	tokenInfo.TokenID = tokenID
+
+	return tokenInfo.unmarshalElts(args[1])
+}
+
+// unmarshalMap - TzKT JSON representation of `map string bytes`
+func (tokenInfo *TokenInfo) unmarshalMap(data []byte) error {
+	var values map[string]stdJSON.RawMessage
+	if err := json.Unmarshal(data, &values); err != nil {
+		return err
+	}
+	for key, value := range values {
+		var str string
+		if err := json.Unmarshal(value, &str); err != nil {
+			tokenInfo.TokenInfo[key] = stdJSON.RawMessage(bytes.TrimSpace(value))
+			tokenInfo.addIssue(key, validation.KindInvalidType, "expected hex-encoded bytes, got %s", string(bytes.TrimSpace(value)))
+			continue
+		}
+		tokenInfo.setBytes(key, str)
+	}
+	return nil
+}
+
+// unmarshalElts - Micheline representation of `map string bytes`
+func (tokenInfo *TokenInfo) unmarshalElts(data []byte) error {
+	var elts []michelineNode
+	if err := json.Unmarshal(data, &elts); err != nil {
+		return errors.Wrap(err, "token_info is not a map")
+	}
+	for i := range elts {
+		if elts[i].Prim != "Elt" || len(elts[i].Args) != 2 {
+			return errors.New("invalid token_info element")
+		}
+
+		var key, value michelineNode
+		if err := json.Unmarshal(elts[i].Args[0], &key); err != nil {
+			return err
+		}
+		if key.String == nil {
+			return errors.New("token_info key is not a string")
+		}
+		if err := json.Unmarshal(elts[i].Args[1], &value); err != nil {
+			return err
+		}
+
+		switch {
+		case value.Bytes != nil:
+			tokenInfo.setBytes(*key.String, *value.Bytes)
+		case value.String != nil:
+			// Micheline strings are kept as is: they must not be decoded as hex
+			tokenInfo.TokenInfo[*key.String] = *value.String
+			tokenInfo.addIssue(*key.String, validation.KindInvalidType, "expected bytes, got string")
+		case value.Int != nil:
+			tokenInfo.TokenInfo[*key.String] = *value.Int
+			tokenInfo.addIssue(*key.String, validation.KindInvalidType, "expected bytes, got int")
+		default:
+			tokenInfo.TokenInfo[*key.String] = elts[i].Args[1]
+			tokenInfo.addIssue(*key.String, validation.KindInvalidType, "expected bytes, got %s", string(elts[i].Args[1]))
+		}
+	}
+	return nil
+}
+
+func (tokenInfo *TokenInfo) setBytes(key, value string) {
+	raw, err := hex.DecodeString(value)
+	if err != nil {
+		tokenInfo.TokenInfo[key] = value
+		tokenInfo.addIssue(key, validation.KindInvalidBytes, "value is not hex-encoded bytes")
+		return
+	}
+
+	if !utf8.Valid(raw) {
+		tokenInfo.TokenInfo[key] = "0x" + value
+		tokenInfo.addIssue(key, validation.KindBinaryValue, "value is not UTF-8 string, it's kept as 0x-prefixed hex")
+		return
+	}
+
+	// empty key contains link to off-chain metadata by TZIP-12
+	if key == "" {
+		tokenInfo.Link = string(raw)
+		return
+	}
+
+	if trimmed := bytes.TrimSpace(raw); len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') {
+		var compacted bytes.Buffer
+		if err := stdJSON.Compact(&compacted, trimmed); err == nil {
+			tokenInfo.TokenInfo[key] = stdJSON.RawMessage(compacted.Bytes())
+			return
+		}
+	}
+	tokenInfo.TokenInfo[key] = string(raw)
+}
+
+func (tokenInfo *TokenInfo) addIssue(key, kind, format string, args ...any) {
+	tokenInfo.Issues = append(tokenInfo.Issues, validation.Issue{
+		Path:    tokenInfoPath + "." + key,
+		Kind:    kind,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// sortIssues - map iteration order is random, but issues should be stable
+func (tokenInfo *TokenInfo) sortIssues() {
+	sort.SliceStable(tokenInfo.Issues, func(i, j int) bool {
+		return tokenInfo.Issues[i].Path < tokenInfo.Issues[j].Path
+	})
+}
+
+// tokenInfoIssues - returns issues of on-chain `token_info` decoding from stored issues
+func tokenInfoIssues(stored models.JSONB) validation.Issues {
+	if stored.IsNull() {
+		return nil
+	}
+
+	var issues validation.Issues
+	if err := json.Unmarshal(stored, &issues); err != nil {
+		return nil
+	}
+
+	result := make(validation.Issues, 0, len(issues))
+	for i := range issues {
+		if strings.HasPrefix(issues[i].Path, tokenInfoPath+".") {
+			result = append(result, issues[i])
+		}
+	}
+	return result
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	stdJSON "encoding/json"
+	"testing"
+
+	"github.com/dipdup-net/metadata/cmd/metadata/models"
+	"github.com/dipdup-net/metadata/cmd/metadata/validation"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenInfo_UnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name       string
+		data       string
+		wantID     decimal.Decimal
+		wantLink   string
+		wantInfo   string
+		wantIssues validation.Issues
+		wantErr    bool
+	}{
+		{
+			name:     "TzKT",
+			data:     `{"token_id":"7","token_info":{"":"697066733a2f2f516d","name":"4865646765686f6765","decimals":"36"}}`,
+			wantID:   decimal.NewFromInt(7),
+			wantLink: "ipfs://Qm",
+			wantInfo: `{"decimals":"6","name":"Hedgehoge"}`,
+		}, {
+			name:     "JSON-encoded bytes",
+			data:     `{"token_id":"0","token_info":{"object":"7b2261223a205b315d7d","array":"5b5d","number":"3132"}}`,
+			wantID:   decimal.Zero,
+			wantInfo: `{"array":[],"number":"12","object":{"a":[1]}}`,
+		}, {
+			name:     "binary and malformed values",
+			data:     `{"token_id":"1","token_info":{"hash":"ff00","name":"zz","decimals":6}}`,
+			wantID:   decimal.NewFromInt(1),
+			wantInfo: `{"decimals":6,"hash":"0xff00","name":"zz"}`,
+			wantIssues: validation.Issues{
+				{Path: "token_info.decimals", Kind: validation.KindInvalidType, Message: "expected hex-encoded bytes, got 6"},
+				{Path: "token_info.hash", Kind: validation.KindBinaryValue, Message: "value is not UTF-8 string, it's kept as 0x-prefixed hex"},
+				{Path: "token_info.name", Kind: validation.KindInvalidBytes, Message: "value is not hex-encoded bytes"},
+			},
+		}, {
+			name:     "Micheline",
+			data:     `{"prim":"Pair","args":[{"int":"42"},[{"prim":"Elt","args":[{"string":""},{"bytes":"697066733a2f2f516d"}]},{"prim":"Elt","args":[{"string":"symbol"},{"bytes":"484548"}]},{"prim":"Elt","args":[{"string":"code"},{"string":"4142"}]}]]}`,
+			wantID:   decimal.NewFromInt(42),
+			wantLink: "ipfs://Qm",
+			wantInfo: `{"code":"4142","symbol":"HEH"}`,
+			wantIssues: validation.Issues{
+				{Path: "token_info.code", Kind: validation.KindInvalidType, Message: "expected bytes, got string"},
+			},
+		}, {
+			name:     "Micheline sequence pair",
+			data:     `[{"int":"3"},[{"prim":"Elt","args":[{"string":"name"},{"bytes":"54657374"}]}]]`,
+			wantID:   decimal.NewFromInt(3),
+			wantInfo: `{"name":"Test"}`,
+		}, {
+			name:    "invalid token id",
+			data:    `{"token_id":"abc","token_info":{}}`,
+			wantErr: true,
+		}, {
+			name:    "invalid Micheline element",
+			data:    `{"prim":"Pair","args":[{"int":"0"},[{"prim":"Pair","args":[]}]]}`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var tokenInfo TokenInfo
+			err := stdJSON.Unmarshal([]byte(tt.data), &tokenInfo)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			assert.True(t, tt.wantID.Equal(tokenInfo.TokenID), "TokenID")
+			assert.Equal(t, tt.wantLink, tokenInfo.Link, "Link")
+			assert.Equal(t, tt.wantIssues, tokenInfo.Issues, "Issues")
+
+			info, err := json.Marshal(tokenInfo.TokenInfo)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantInfo, string(info), "TokenInfo")
+		})
+	}
+}
+
+func TestNewTokenInfo(t *testing.T) {
+	tokenInfo := NewTokenInfo(decimal.NewFromInt(1), map[string]string{
+		"":     "697066733a2f2f516d",
+		"name": "74657374",
+		"raw":  "c328",
+	})
+
+	assert.Equal(t, "ipfs://Qm", tokenInfo.Link)
+	assert.Equal(t, map[string]any{
+		"name": "test",
+		"raw":  "0xc328",
+	}, tokenInfo.TokenInfo)
+	assert.Equal(t, validation.Issues{
+		{Path: "token_info.raw", Kind: validation.KindBinaryValue, Message: "value is not UTF-8 string, it's kept as 0x-prefixed hex"},
+	}, tokenInfo.Issues)
+}
+
+func Test_tokenInfoIssues(t *testing.T) {
+	stored := models.JSONB(`[{"path":"token_info.hash","kind":"binary_value","message":"binary"},{"path":"name","kind":"missing_field","message":"required field is missing"}]`)
+	assert.Equal(t, validation.Issues{
+		{Path: "token_info.hash", Kind: validation.KindBinaryValue, Message: "binary"},
+	}, tokenInfoIssues(stored))
+	assert.Nil(t, tokenInfoIssues(nil))
+}
@@ -6,11 +6,15 @@ import (
 
 	api "github.com/dipdup-net/go-lib/tzkt/data"
 	"github.com/dipdup-net/metadata/cmd/metadata/models"
+	"github.com/dipdup-net/metadata/cmd/metadata/validation"
 	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestIndexer_processTokenMetadata(t *testing.T) {
+	hedgehogeDecimals := 6
+
 	tests := []struct {
 		name    string
 		update  api.BigMapUpdate
@@ -45,11 +49,39 @@ func TestIndexer_processTokenMetadata(t *testing.T) {
 				},
 			},
 			want: &models.TokenMetadata{
-				TokenID:    decimal.NewFromInt(0),
-				Contract:   "KT1G1cCRNBgQ48mVDjopHjEmTN5Sbtar8nn9",
-				Metadata:   models.JSONB(`{"decimals":"6","icon":"ipfs://QmXL3FZ5kcwXC8mdwkS1iCHS2qVoyg69ugBhU2ap8z1zcs","name":"Hedgehoge","symbol":"HEH","test_object":"{}"}`),
-				Status:     models.StatusApplied,
-				RetryCount: 1,
+				TokenID:         decimal.NewFromInt(0),
+				Contract:        "KT1G1cCRNBgQ48mVDjopHjEmTN5Sbtar8nn9",
+				Metadata:        models.JSONB(`{"decimals":"6","icon":"ipfs://QmXL3FZ5kcwXC8mdwkS1iCHS2qVoyg69ugBhU2ap8z1zcs","name":"Hedgehoge","symbol":"HEH","test_object":{}}`),
+				OnChainMetadata: models.JSONB(`{"decimals":"6","icon":"ipfs://QmXL3FZ5kcwXC8mdwkS1iCHS2qVoyg69ugBhU2ap8z1zcs","name":"Hedgehoge","symbol":"HEH","test_object":{}}`),
+				Status:          models.StatusApplied,
+				RetryCount:      1,
+				Source:          models.TokenSourceBigMap,
+				Name:            "Hedgehoge",
+				Symbol:          "HEH",
+				Decimals:        &hedgehogeDecimals,
+			},
+		}, {
+			name: "removed key",
+			update: api.BigMapUpdate{
+				ID:     4163560,
+				Level:  1477523,
+				Bigmap: 3688,
+				Path:   "token_metadata",
+				Action: "remove_key",
+				Contract: api.Address{
+					Alias:   "Hedgehoge",
+					Address: "KT1G1cCRNBgQ48mVDjopHjEmTN5Sbtar8nn9",
+				},
+				Content: &api.BigMapUpdateContent{
+					Hash: "exprtZBwZUeYYYfUs9B9Rg2ywHezVHnCCnmF9WsDQVrs582dSK63dC",
+					Key:  stdJSON.RawMessage(`"42"`),
+				},
+			},
+			want: &models.TokenMetadata{
+				TokenID:  decimal.NewFromInt(42),
+				Contract: "KT1G1cCRNBgQ48mVDjopHjEmTN5Sbtar8nn9",
+				Status:   models.StatusRemoved,
+				Source:   models.TokenSourceBigMap,
 			},
 		},
 	}
@@ -65,3 +97,94 @@ func TestIndexer_processTokenMetadata(t *testing.T) {
 		})
 	}
 }
+
+func Test_mergeTokenMetadata(t *testing.T) {
+	tests := []struct {
+		name     string
+		onChain  models.JSONB
+		offChain models.JSONB
+		want     string
+	}{
+		{
+			name:     "on-chain fields override off-chain ones",
+			onChain:  models.JSONB(`{"decimals":"6","symbol":"HEH"}`),
+			offChain: models.JSONB(`{"decimals":0,"name":"Hedgehoge","symbol":"hedgehoge","tags":["meme"]}`),
+			want:     `{"decimals":"6","name":"Hedgehoge","symbol":"HEH","tags":["meme"]}`,
+		}, {
+			name:     "on-chain fields are added",
+			onChain:  models.JSONB(`{"decimals":"8"}`),
+			offChain: models.JSONB(`{"name":"tzBTC"}`),
+			want:     `{"decimals":"8","name":"tzBTC"}`,
+		}, {
+			name:     "nested off-chain objects are kept",
+			onChain:  models.JSONB(`{"name":"Token"}`),
+			offChain: models.JSONB(`{"name":"Other","royalties":{"decimals":3,"shares":{"tz1":"50"}}}`),
+			want:     `{"name":"Token","royalties":{"decimals":3,"shares":{"tz1":"50"}}}`,
+		}, {
+			name:     "without on-chain fields",
+			offChain: models.JSONB(`{"name":"Token","decimals":2}`),
+			want:     `{"name":"Token","decimals":2}`,
+		}, {
+			name:     "empty on-chain fields",
+			onChain:  models.JSONB(`{}`),
+			offChain: models.JSONB(`{"name":"Token"}`),
+			want:     `{"name":"Token"}`,
+		}, {
+			name:     "off-chain document is not object",
+			onChain:  models.JSONB(`{"decimals":"6"}`),
+			offChain: models.JSONB(`["decimals"]`),
+			want:     `["decimals"]`,
+		}, {
+			name:     "off-chain document is null",
+			onChain:  models.JSONB(`{"decimals":"6"}`),
+			offChain: models.JSONB(`null`),
+			want:     `null`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := mergeTokenMetadata(tt.onChain, tt.offChain)
+			assert.Equal(t, tt.want, string(got))
+		})
+	}
+}
+
+func TestIndexer_newTokenMetadataIssues(t *testing.T) {
+	tests := []struct {
+		name       string
+		info       map[string]string
+		wantIssues []string
+	}{
+		{
+			name: "valid on-chain metadata",
+			info: map[string]string{"name": "4865646765686f6765", "decimals": "36"},
+		}, {
+			name:       "on-chain metadata without name",
+			info:       map[string]string{"symbol": "484548", "decimals": "78"},
+			wantIssues: []string{"name missing_field", "decimals invalid_number"},
+		}, {
+			name: "token with link is validated after resolving",
+			info: map[string]string{"": "697066733a2f2f516d584c33465a356b63775843386d64776b5331694348533271566f796736397567426855326170387a317a6373", "decimals": "78"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			indexer := &Indexer{}
+			got, err := indexer.newTokenMetadata("KT1G1cCRNBgQ48mVDjopHjEmTN5Sbtar8nn9", NewTokenInfo(decimal.NewFromInt(0), tt.info), models.TokenSourceBigMap)
+			require.NoError(t, err)
+
+			if len(tt.wantIssues) == 0 {
+				assert.True(t, got.Issues.IsNull())
+				return
+			}
+
+			var issues validation.Issues
+			require.NoError(t, stdJSON.Unmarshal(got.Issues, &issues))
+			kinds := make([]string, len(issues))
+			for i := range issues {
+				kinds[i] = issues[i].Path + " " + issues[i].Kind
+			}
+			assert.ElementsMatch(t, tt.wantIssues, kinds)
+		})
+	}
+}
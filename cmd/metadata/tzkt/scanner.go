@@ -20,10 +20,19 @@ const (
 	pageSize = 1000
 )
 
+type eventsClient interface {
+	Connect(ctx context.Context) error
+	Close() error
+	IsConnected() bool
+	Listen() <-chan events.Message
+	SubscribeToBlocks() error
+	SubscribeToBigMaps(ptr *int64, contract, path string, tags ...string) error
+}
+
 // Scanner -
 type Scanner struct {
 	api       *api.API
-	client    *events.TzKT
+	client    eventsClient
 	lastID    uint64
 	level     uint64
 	msg       Message
@@ -200,12 +209,41 @@ func (scanner *Scanner) listen(ctx context.Context) {
 				default:
 					log.Error().Msgf("Unknown channel %s", msg.Channel)
 				}
-			case events.MessageTypeReorg, events.MessageTypeSubscribed:
+			case events.MessageTypeReorg:
+				if msg.Channel != events.ChannelBlocks {
+					continue
+				}
+
+				if err := scanner.rollback(ctx, msg.State); err != nil {
+					log.Err(err).Msg("rollback error")
+					return
+				}
+
+			case events.MessageTypeSubscribed:
 			}
 		}
 	}
 }
 
+func (scanner *Scanner) rollback(ctx context.Context, level uint64) error {
+	log.Warn().Uint64("old_state", scanner.level).Uint64("new_level", level).Msg("reorg detected. rollback...")
+
+	scanner.msg.clear()
+	scanner.lastID = 0
+	scanner.level = level
+	scanner.diffs <- Message{
+		Type:  events.MessageTypeReorg,
+		Level: level,
+		Body:  make([]data.BigMapUpdate, 0),
+	}
+
+	head, err := scanner.api.GetHead(ctx)
+	if err != nil {
+		return err
+	}
+	return scanner.sync(ctx, head.Level)
+}
+
 func (scanner *Scanner) sync(ctx context.Context, headLevel uint64) error {
 	for {
 		select {
@@ -240,7 +278,7 @@ func (scanner *Scanner) sync(ctx context.Context, headLevel uint64) error {
 func (scanner *Scanner) getSyncUpdates(ctx context.Context, headLevel uint64) ([]data.BigMapUpdate, error) {
 	filters := map[string]string{
 		"path.as":   "*metadata",
-		"action.in": "add_key,update_key",
+		"action.in": "add_key,update_key,remove_key",
 		"limit":     fmt.Sprintf("%d", pageSize),
 		"level.le":  fmt.Sprintf("%d", headLevel),
 		"sort.asc":  "id",
@@ -0,0 +1,113 @@
+package tzkt
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dipdup-net/go-lib/tzkt/api"
+	"github.com/dipdup-net/go-lib/tzkt/data"
+	"github.com/dipdup-net/go-lib/tzkt/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeEvents struct {
+	messages chan events.Message
+}
+
+func newFakeEvents() *fakeEvents {
+	return &fakeEvents{
+		messages: make(chan events.Message, 10),
+	}
+}
+
+func (f *fakeEvents) Connect(ctx context.Context) error { return nil }
+func (f *fakeEvents) Close() error                      { return nil }
+func (f *fakeEvents) IsConnected() bool                 { return true }
+func (f *fakeEvents) Listen() <-chan events.Message     { return f.messages }
+func (f *fakeEvents) SubscribeToBlocks() error          { return nil }
+func (f *fakeEvents) SubscribeToBigMaps(ptr *int64, contract, path string, tags ...string) error {
+	return nil
+}
+
+func newTzKTStub() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/v1/head":
+			_, _ = w.Write([]byte(`{"level":9}`))
+		case "/v1/bigmaps/updates":
+			if r.URL.Query().Get("level.gt") == "8" {
+				_, _ = w.Write([]byte(`[{"id":100,"level":9,"bigmap":1,"contract":{"address":"KT1Hkg5qeNhfwpKW4fXvq7HGZB9z2EnmCCA9"},"path":"metadata","action":"update_key"}]`))
+				return
+			}
+			_, _ = w.Write([]byte(`[]`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func receive(t *testing.T, ch <-chan Message) Message {
+	select {
+	case msg := <-ch:
+		return msg
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "message was not received")
+	}
+	return Message{}
+}
+
+func TestScanner_listenReorg(t *testing.T) {
+	server := newTzKTStub()
+	defer server.Close()
+
+	client := newFakeEvents()
+	scanner := &Scanner{
+		api:    api.New(server.URL),
+		client: client,
+		level:  10,
+		lastID: 50,
+		msg:    newMessage(),
+		diffs:  make(chan Message, 10),
+		blocks: make(chan data.Block, 10),
+		wg:     new(sync.WaitGroup),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	scanner.wg.Add(1)
+	go scanner.listen(ctx)
+
+	client.messages <- events.Message{
+		Channel: events.ChannelBigMap,
+		Type:    events.MessageTypeReorg,
+		State:   8,
+	}
+	client.messages <- events.Message{
+		Channel: events.ChannelBlocks,
+		Type:    events.MessageTypeReorg,
+		State:   8,
+	}
+
+	reorg := receive(t, scanner.BigMaps())
+	assert.Equal(t, events.MessageTypeReorg, reorg.Type)
+	assert.EqualValues(t, 8, reorg.Level)
+	assert.Empty(t, reorg.Body)
+
+	resynced := receive(t, scanner.BigMaps())
+	assert.NotEqual(t, events.MessageTypeReorg, resynced.Type)
+	assert.EqualValues(t, 9, resynced.Level)
+	require.Len(t, resynced.Body, 1)
+	assert.EqualValues(t, 100, resynced.Body[0].ID)
+
+	cancel()
+	scanner.wg.Wait()
+
+	assert.EqualValues(t, 9, scanner.level)
+	assert.EqualValues(t, 100, scanner.lastID)
+	assert.Empty(t, scanner.diffs)
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"github.com/dipdup-net/metadata/cmd/metadata/models"
+	"github.com/dipdup-net/metadata/cmd/metadata/validation"
+	"github.com/rs/zerolog/log"
+)
+
+// validate - checks resolved document against metadata schema. Documents with issues are applied anyway, issues are stored to warn users. `known` issues (e.g. of on-chain data decoding) are stored too.
+func (indexer *Indexer) validate(typ string, data []byte, validate func([]byte) (validation.Issues, error), known validation.Issues) models.JSONB {
+	issues, err := validate(data)
+	if err != nil {
+		log.Warn().Err(err).Str("network", indexer.network).Str("type", typ).Msg("metadata validation")
+	}
+	indexer.countIssues(typ, issues)
+	return indexer.marshalIssues(typ, append(known, issues...))
+}
+
+func (indexer *Indexer) countIssues(typ string, issues validation.Issues) {
+	for i := range issues {
+		indexer.prom.IncrementValidationIssue(indexer.network, typ, issues[i].Kind)
+	}
+}
+
+func (indexer *Indexer) marshalIssues(typ string, issues validation.Issues) models.JSONB {
+	if len(issues) == 0 {
+		return nil
+	}
+
+	raw, err := json.Marshal(issues)
+	if err != nil {
+		log.Warn().Err(err).Str("network", indexer.network).Str("type", typ).Msg("marshal validation issues")
+		return nil
+	}
+	return raw
+}
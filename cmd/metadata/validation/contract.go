@@ -0,0 +1,33 @@
+package validation
+
+// Contract - validates TZIP-16 contract metadata
+func Contract(data []byte) (Issues, error) {
+	document, err := decode(data)
+	if err != nil {
+		return nil, err
+	}
+
+	issues := make(Issues, 0)
+	required(&issues, document, "", "name")
+	isString(&issues, document, "", "name", "description", "version")
+	isURI(&issues, document, "", "homepage")
+	isStringArray(&issues, document, "", "authors", "interfaces")
+
+	object(&issues, document, "", "license", func(issues *Issues, license map[string]any, path string) {
+		required(issues, license, path, "name")
+		isString(issues, license, path, "name", "details")
+	})
+	object(&issues, document, "", "source", func(issues *Issues, source map[string]any, path string) {
+		isStringArray(issues, source, path, "tools")
+		isString(issues, source, path, "location")
+	})
+	objects(&issues, document, "", "views", func(issues *Issues, view map[string]any, path string) {
+		required(issues, view, path, "name", "implementations")
+		isString(issues, view, path, "name", "description")
+		isBoolean(issues, view, path, "pure")
+		objects(issues, view, path, "implementations", func(*Issues, map[string]any, string) {})
+	})
+	objects(&issues, document, "", "errors", func(*Issues, map[string]any, string) {})
+
+	return issues, nil
+}
@@ -0,0 +1,51 @@
+package validation
+
+import "sort"
+
+// Token - validates TZIP-21 token metadata
+func Token(data []byte) (Issues, error) {
+	document, err := decode(data)
+	if err != nil {
+		return nil, err
+	}
+
+	issues := make(Issues, 0)
+	required(&issues, document, "", "name")
+	token(&issues, document, "")
+	return issues, nil
+}
+
+func token(issues *Issues, document map[string]any, parent string) {
+	isString(issues, document, parent, "name", "symbol", "description", "minter", "language", "rights", "mintingTool")
+	isNatural(issues, document, parent, "decimals")
+	isBoolean(issues, document, parent, "isBooleanAmount", "shouldPreferSymbol", "isTransferable")
+	isURI(issues, document, parent, "artifactUri", "displayUri", "thumbnailUri", "externalUri", "rightUri")
+	isStringArray(issues, document, parent, "creators", "contributors", "publishers", "tags")
+	isDate(issues, document, parent, "date")
+
+	objects(issues, document, parent, "formats", func(issues *Issues, format map[string]any, path string) {
+		isURI(issues, format, path, "uri")
+		isString(issues, format, path, "hash", "mimeType", "fileName", "duration", "dataRate")
+		isNatural(issues, format, path, "fileSize")
+		object(issues, format, path, "dimensions", func(issues *Issues, dimensions map[string]any, path string) {
+			isString(issues, dimensions, path, "value", "unit")
+		})
+	})
+	objects(issues, document, parent, "attributes", func(issues *Issues, attribute map[string]any, path string) {
+		required(issues, attribute, path, "name", "value")
+		isString(issues, attribute, path, "name", "type")
+	})
+	object(issues, document, parent, "royalties", func(issues *Issues, royalties map[string]any, path string) {
+		isNatural(issues, royalties, path, "decimals")
+		object(issues, royalties, path, "shares", func(issues *Issues, shares map[string]any, path string) {
+			addresses := make([]string, 0, len(shares))
+			for address := range shares {
+				addresses = append(addresses, address)
+			}
+			sort.Strings(addresses)
+			isNatural(issues, shares, path, addresses...)
+		})
+	})
+	// assets are token metadata too, but all of their fields are optional
+	objects(issues, document, parent, "assets", token)
+}
@@ -0,0 +1,244 @@
+package validation
+
+import (
+	"bytes"
+	stdJSON "encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// issue kinds
+const (
+	KindMissingField  = "missing_field"
+	KindInvalidType   = "invalid_type"
+	KindInvalidURI    = "invalid_uri"
+	KindInvalidNumber = "invalid_number"
+	KindInvalidDate   = "invalid_date"
+
+	// kinds of on-chain `token_info` decoding issues
+	KindInvalidBytes = "invalid_bytes"
+	KindBinaryValue  = "binary_value"
+)
+
+// Issue - violation of metadata schema
+type Issue struct {
+	Path    string `json:"path"`
+	Kind    string `json:"kind"`
+	Message string `json:"message"`
+}
+
+// Issues -
+type Issues []Issue
+
+func (issues *Issues) add(path, kind, format string, args ...any) {
+	*issues = append(*issues, Issue{
+		Path:    path,
+		Kind:    kind,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// ErrNotObject -
+var ErrNotObject = errors.New("metadata is not JSON object")
+
+func decode(data []byte) (map[string]any, error) {
+	decoder := stdJSON.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var document map[string]any
+	if err := decoder.Decode(&document); err != nil {
+		return nil, errors.Wrap(ErrNotObject, err.Error())
+	}
+	if document == nil {
+		return nil, ErrNotObject
+	}
+	return document, nil
+}
+
+func path(parent, field string) string {
+	if parent == "" {
+		return field
+	}
+	return parent + "." + field
+}
+
+func index(parent string, i int) string {
+	return fmt.Sprintf("%s[%d]", parent, i)
+}
+
+func typeName(value any) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case stdJSON.Number:
+		return "number"
+	case bool:
+		return "boolean"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+func required(issues *Issues, document map[string]any, parent string, fields ...string) {
+	for _, field := range fields {
+		if _, ok := document[field]; !ok {
+			issues.add(path(parent, field), KindMissingField, "required field is missing")
+		}
+	}
+}
+
+func isString(issues *Issues, document map[string]any, parent string, fields ...string) {
+	for _, field := range fields {
+		value, ok := document[field]
+		if !ok {
+			continue
+		}
+		if _, ok := value.(string); !ok {
+			issues.add(path(parent, field), KindInvalidType, "expected string, got %s", typeName(value))
+		}
+	}
+}
+
+func isBoolean(issues *Issues, document map[string]any, parent string, fields ...string) {
+	for _, field := range fields {
+		value, ok := document[field]
+		if !ok {
+			continue
+		}
+		if _, ok := value.(bool); !ok {
+			issues.add(path(parent, field), KindInvalidType, "expected boolean, got %s", typeName(value))
+		}
+	}
+}
+
+func isStringArray(issues *Issues, document map[string]any, parent string, fields ...string) {
+	for _, field := range fields {
+		value, ok := document[field]
+		if !ok {
+			continue
+		}
+		items, ok := value.([]any)
+		if !ok {
+			issues.add(path(parent, field), KindInvalidType, "expected array, got %s", typeName(value))
+			continue
+		}
+		for i := range items {
+			if _, ok := items[i].(string); !ok {
+				issues.add(index(path(parent, field), i), KindInvalidType, "expected string, got %s", typeName(items[i]))
+			}
+		}
+	}
+}
+
+func isURI(issues *Issues, document map[string]any, parent string, fields ...string) {
+	for _, field := range fields {
+		value, ok := document[field]
+		if !ok {
+			continue
+		}
+		str, ok := value.(string)
+		if !ok {
+			issues.add(path(parent, field), KindInvalidType, "expected string, got %s", typeName(value))
+			continue
+		}
+		if !validURI(str) {
+			issues.add(path(parent, field), KindInvalidURI, "malformed URI: %s", str)
+		}
+	}
+}
+
+func validURI(value string) bool {
+	uri, err := url.Parse(value)
+	if err != nil || uri.Scheme == "" {
+		return false
+	}
+	return uri.Host != "" || uri.Opaque != "" || uri.Path != ""
+}
+
+// isNatural - TZIP-12 numbers are often encoded as strings, so numeric strings are valid too
+func isNatural(issues *Issues, document map[string]any, parent string, fields ...string) {
+	for _, field := range fields {
+		value, ok := document[field]
+		if !ok {
+			continue
+		}
+
+		var str string
+		switch typed := value.(type) {
+		case stdJSON.Number:
+			str = typed.String()
+		case string:
+			str = typed
+		default:
+			issues.add(path(parent, field), KindInvalidType, "expected number, got %s", typeName(value))
+			continue
+		}
+
+		if str == "" || strings.TrimLeft(str, "0123456789") != "" {
+			issues.add(path(parent, field), KindInvalidNumber, "expected non-negative integer, got %q", str)
+		}
+	}
+}
+
+func isDate(issues *Issues, document map[string]any, parent string, fields ...string) {
+	for _, field := range fields {
+		value, ok := document[field]
+		if !ok {
+			continue
+		}
+		str, ok := value.(string)
+		if !ok {
+			issues.add(path(parent, field), KindInvalidType, "expected string, got %s", typeName(value))
+			continue
+		}
+		if _, err := time.Parse(time.RFC3339, str); err != nil {
+			issues.add(path(parent, field), KindInvalidDate, "expected RFC 3339 date-time, got %q", str)
+		}
+	}
+}
+
+// objects - calls `validate` for every object of array field
+func objects(issues *Issues, document map[string]any, parent, field string, validate func(issues *Issues, object map[string]any, path string)) {
+	value, ok := document[field]
+	if !ok {
+		return
+	}
+	items, ok := value.([]any)
+	if !ok {
+		issues.add(path(parent, field), KindInvalidType, "expected array, got %s", typeName(value))
+		return
+	}
+	for i := range items {
+		itemPath := index(path(parent, field), i)
+		object, ok := items[i].(map[string]any)
+		if !ok {
+			issues.add(itemPath, KindInvalidType, "expected object, got %s", typeName(items[i]))
+			continue
+		}
+		validate(issues, object, itemPath)
+	}
+}
+
+// object - calls `validate` for object field
+func object(issues *Issues, document map[string]any, parent, field string, validate func(issues *Issues, object map[string]any, path string)) {
+	value, ok := document[field]
+	if !ok {
+		return
+	}
+	typed, ok := value.(map[string]any)
+	if !ok {
+		issues.add(path(parent, field), KindInvalidType, "expected object, got %s", typeName(value))
+		return
+	}
+	validate(issues, typed, path(parent, field))
+}
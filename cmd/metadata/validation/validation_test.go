@@ -0,0 +1,96 @@
+package validation
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContract(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    Issues
+		wantErr bool
+	}{
+		{
+			name: "valid",
+			data: `{"name":"FA2 NFT","version":"1.0.0","license":{"name":"MIT"},"authors":["dipdup <info@dipdup.net>"],"homepage":"https://dipdup.net","interfaces":["TZIP-012","TZIP-016"],"views":[{"name":"get_balance","pure":true,"implementations":[{"michelsonStorageView":{}}]}]}`,
+			want: Issues{},
+		}, {
+			name: "missing name",
+			data: `{"description":"test"}`,
+			want: Issues{
+				{Path: "name", Kind: KindMissingField, Message: "required field is missing"},
+			},
+		}, {
+			name: "invalid fields",
+			data: `{"name":1,"homepage":"dipdup.net","authors":"dipdup","license":{"details":"text"},"views":[{"name":"get_balance","pure":"true"},"view"]}`,
+			want: Issues{
+				{Path: "name", Kind: KindInvalidType, Message: "expected string, got number"},
+				{Path: "homepage", Kind: KindInvalidURI, Message: "malformed URI: dipdup.net"},
+				{Path: "authors", Kind: KindInvalidType, Message: "expected array, got string"},
+				{Path: "license.name", Kind: KindMissingField, Message: "required field is missing"},
+				{Path: "views[0].implementations", Kind: KindMissingField, Message: "required field is missing"},
+				{Path: "views[0].pure", Kind: KindInvalidType, Message: "expected boolean, got string"},
+				{Path: "views[1]", Kind: KindInvalidType, Message: "expected object, got string"},
+			},
+		}, {
+			name:    "not object",
+			data:    `[1, 2]`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Contract([]byte(tt.data))
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestToken(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want Issues
+	}{
+		{
+			name: "valid",
+			data: `{"name":"Hedgehoge","symbol":"HEH","decimals":6,"isBooleanAmount":false,"artifactUri":"ipfs://QmXL3FZ5kcwXC8mdwkS1iCHS2qVoyg69ugBhU2ap8z1zcs","date":"2021-06-01T12:00:00Z","formats":[{"uri":"ipfs://QmXL3FZ5kcwXC8mdwkS1iCHS2qVoyg69ugBhU2ap8z1zcs","mimeType":"image/png","fileSize":"1024","dimensions":{"value":"100x100","unit":"px"}}],"attributes":[{"name":"color","value":"red"}],"royalties":{"decimals":2,"shares":{"tz1aSkwEot3L2kmUvcoxzjMomb9mvBNuzFK6":5}}}`,
+			want: Issues{},
+		}, {
+			name: "decimals as string",
+			data: `{"name":"tzBTC","decimals":"8"}`,
+			want: Issues{},
+		}, {
+			name: "invalid fields",
+			data: `{"decimals":"six","tags":["art",1],"date":"yesterday","thumbnailUri":"","formats":[{"uri":"not a uri","fileSize":-1}],"attributes":[{"value":"red"}],"royalties":{"shares":{"tz1b":"1.5","tz1a":10}},"assets":[{"decimals":1.5}]}`,
+			want: Issues{
+				{Path: "name", Kind: KindMissingField, Message: "required field is missing"},
+				{Path: "decimals", Kind: KindInvalidNumber, Message: `expected non-negative integer, got "six"`},
+				{Path: "thumbnailUri", Kind: KindInvalidURI, Message: "malformed URI: "},
+				{Path: "tags[1]", Kind: KindInvalidType, Message: "expected string, got number"},
+				{Path: "date", Kind: KindInvalidDate, Message: `expected RFC 3339 date-time, got "yesterday"`},
+				{Path: "formats[0].uri", Kind: KindInvalidURI, Message: "malformed URI: not a uri"},
+				{Path: "formats[0].fileSize", Kind: KindInvalidNumber, Message: `expected non-negative integer, got "-1"`},
+				{Path: "attributes[0].name", Kind: KindMissingField, Message: "required field is missing"},
+				{Path: "royalties.shares.tz1b", Kind: KindInvalidNumber, Message: `expected non-negative integer, got "1.5"`},
+				{Path: "assets[0].decimals", Kind: KindInvalidNumber, Message: `expected non-negative integer, got "1.5"`},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Token([]byte(tt.data))
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	api "github.com/dipdup-net/go-lib/tzkt/data"
+	"github.com/dipdup-net/metadata/cmd/metadata/models"
+	"github.com/dipdup-net/metadata/cmd/metadata/offchainviews"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+// off-chain views scheduling: how often committed contract metadata is checked and how many rows are read at once
+const (
+	viewsPollInterval = time.Second
+	viewsPageSize     = 100
+)
+
+// watchViews - schedules off-chain views of contracts which metadata is committed by any instance. It runs on leader, so views are bound to its head which is persisted state.
+// Metadata is read by update id from the beginning, so tokens of all contracts with views are processed once per leadership term.
+func (indexer *Indexer) watchViews(ctx context.Context) {
+	defer indexer.wg.Done()
+
+	ticker := time.NewTicker(viewsPollInterval)
+	defer ticker.Stop()
+
+	var after int64
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			after = indexer.scheduleViews(ctx, after)
+		}
+	}
+}
+
+func (indexer *Indexer) scheduleViews(ctx context.Context, after int64) int64 {
+	head := indexer.head.Load()
+	if head == nil {
+		return after
+	}
+
+	for {
+		contracts, err := indexer.db.Updates.ContractsAfter(ctx, indexer.network, "", after, viewsPageSize)
+		if err != nil {
+			if !errors.Is(err, context.Canceled) {
+				log.Err(err).Str("network", indexer.network).Msg("contracts with off-chain views")
+			}
+			return after
+		}
+
+		for i := range contracts {
+			after = contracts[i].UpdateID
+
+			// views of contract which metadata isn't applied anymore are forgotten
+			var metadata []byte
+			if contracts[i].Status == models.StatusApplied {
+				metadata = contracts[i].Metadata
+			}
+			indexer.views.Add(contracts[i].Contract, metadata, head.Level, head.Timestamp)
+		}
+
+		if len(contracts) < viewsPageSize {
+			return after
+		}
+	}
+}
+
+func (indexer *Indexer) handleViewTokens(ctx context.Context, contract string, level uint64, timestamp time.Time, tokens []offchainviews.Token) error {
+	metadata := make([]*models.TokenMetadata, 0, len(tokens))
+	history := make([]*models.TokenMetadataHistory, 0, len(tokens))
+	for i := range tokens {
+		tokenInfo := NewTokenInfo(tokens[i].TokenID, tokens[i].Info)
+		token, err := indexer.newTokenMetadata(contract, tokenInfo, models.TokenSourceOffChainView)
+		if err != nil {
+			return err
+		}
+		metadata = append(metadata, token)
+
+		// off-chain view is executed on the head, so its result is bound to the head at the moment the view was scheduled
+		history = append(history, newTokenHistory(api.BigMapUpdate{
+			Level:     level,
+			Timestamp: timestamp,
+		}, token))
+	}
+
+	indexer.log().Str("contract", contract).Int("tokens", len(metadata)).Msg("token metadata received from off-chain view")
+	return indexer.db.Transactions.Run(ctx, func(ctx context.Context) error {
+		if err := indexer.db.History.AddTokens(ctx, history); err != nil {
+			return err
+		}
+		if err := indexer.db.Changes.TrackTokens(ctx, indexer.network, level, metadata); err != nil {
+			return err
+		}
+		if err := indexer.db.Tokens.Save(ctx, metadata); err != nil {
+			return err
+		}
+		return indexer.notifyTokens(ctx, metadata)
+	})
+}
@@ -0,0 +1,64 @@
+package webhooks
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/dipdup-net/metadata/cmd/metadata/prometheus"
+)
+
+// ServiceOption -
+type ServiceOption func(*Service)
+
+// WithMaxAttempts - count of delivery attempts after which delivery is marked as failed
+func WithMaxAttempts(count int) ServiceOption {
+	return func(s *Service) {
+		if count > 0 {
+			s.maxAttempts = count
+		}
+	}
+}
+
+// WithTimeout - timeout of webhook request in seconds
+func WithTimeout(timeout int) ServiceOption {
+	return func(s *Service) {
+		if timeout > 0 {
+			s.client.Timeout = time.Duration(timeout) * time.Second
+		}
+	}
+}
+
+// WithWorkers - count of concurrent deliveries
+func WithWorkers(count int) ServiceOption {
+	return func(s *Service) {
+		if count > 0 {
+			s.workers = count
+		}
+	}
+}
+
+// WithBackoff - delay before the first retry which is doubled on every next one up to `max`
+func WithBackoff(initial, max time.Duration) ServiceOption {
+	return func(s *Service) {
+		if initial > 0 && max >= initial {
+			s.initialBackoff = initial
+			s.maxBackoff = max
+		}
+	}
+}
+
+// WithPrometheus -
+func WithPrometheus(prom *prometheus.Prometheus) ServiceOption {
+	return func(s *Service) {
+		s.prom = prom
+	}
+}
+
+// WithClient -
+func WithClient(client *http.Client) ServiceOption {
+	return func(s *Service) {
+		if client != nil {
+			s.client = client
+		}
+	}
+}
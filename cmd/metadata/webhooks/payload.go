@@ -0,0 +1,71 @@
+package webhooks
+
+import (
+	stdJSON "encoding/json"
+	"fmt"
+
+	"github.com/dipdup-net/metadata/cmd/metadata/broker"
+	"github.com/dipdup-net/metadata/cmd/metadata/models"
+	"github.com/shopspring/decimal"
+)
+
+// Payload - body of webhook request
+type Payload struct {
+	Event    string             `json:"event"`
+	Type     string             `json:"type"`
+	Network  string             `json:"network"`
+	Contract string             `json:"contract"`
+	TokenID  *decimal.Decimal   `json:"token_id,omitempty"`
+	Status   string             `json:"status"`
+	UpdateID int64              `json:"update_id"`
+	Link     string             `json:"link,omitempty"`
+	Metadata stdJSON.RawMessage `json:"metadata,omitempty"`
+	Error    string             `json:"error,omitempty"`
+	Issues   stdJSON.RawMessage `json:"issues,omitempty"`
+}
+
+func eventName(typ string, status models.Status) string {
+	return fmt.Sprintf("%s.%s", typ, status.String())
+}
+
+func newContractPayload(cm models.ContractMetadata) Payload {
+	payload := Payload{
+		Event:    eventName(broker.TypeContract, cm.Status),
+		Type:     broker.TypeContract,
+		Network:  cm.Network,
+		Contract: cm.Contract,
+		Status:   cm.Status.String(),
+		UpdateID: cm.UpdateID,
+		Link:     cm.Link,
+		Error:    cm.Error,
+	}
+	if !cm.Metadata.IsNull() {
+		payload.Metadata = stdJSON.RawMessage(cm.Metadata)
+	}
+	if !cm.Issues.IsNull() {
+		payload.Issues = stdJSON.RawMessage(cm.Issues)
+	}
+	return payload
+}
+
+func newTokenPayload(tm models.TokenMetadata) Payload {
+	tokenID := tm.TokenID
+	payload := Payload{
+		Event:    eventName(broker.TypeToken, tm.Status),
+		Type:     broker.TypeToken,
+		Network:  tm.Network,
+		Contract: tm.Contract,
+		TokenID:  &tokenID,
+		Status:   tm.Status.String(),
+		UpdateID: tm.UpdateID,
+		Link:     tm.Link,
+		Error:    tm.Error,
+	}
+	if !tm.Metadata.IsNull() {
+		payload.Metadata = stdJSON.RawMessage(tm.Metadata)
+	}
+	if !tm.Issues.IsNull() {
+		payload.Issues = stdJSON.RawMessage(tm.Issues)
+	}
+	return payload
+}
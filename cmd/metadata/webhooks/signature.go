@@ -0,0 +1,26 @@
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+)
+
+// headers of webhook request
+const (
+	HeaderEvent     = "X-Metadata-Event"
+	HeaderDelivery  = "X-Metadata-Delivery"
+	HeaderTimestamp = "X-Metadata-Timestamp"
+	HeaderSignature = "X-Metadata-Signature"
+)
+
+// Sign - returns value of signature header: hex-encoded HMAC-SHA256 of `{timestamp}.{body}` with endpoint secret.
+// Receivers should compute it by themselves and compare with the header value to verify request.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
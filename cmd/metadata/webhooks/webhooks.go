@@ -0,0 +1,354 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	stdJSON "encoding/json"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/dipdup-net/metadata/cmd/metadata/config"
+	"github.com/dipdup-net/metadata/cmd/metadata/models"
+	"github.com/dipdup-net/metadata/cmd/metadata/prometheus"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	batchSize = 100
+
+	maxResponseSize = 1024
+)
+
+// delivery results
+const (
+	ResultDelivered = "delivered"
+	ResultRetry     = "retry"
+	ResultFailed    = "failed"
+)
+
+// Repository - outbox of webhook deliveries
+type Repository interface {
+	Add(ctx context.Context, deliveries []*models.WebhookDelivery) error
+	Pending(ctx context.Context, network string, now int64, limit int) ([]models.WebhookDelivery, error)
+	Update(ctx context.Context, delivery *models.WebhookDelivery) error
+	Delete(ctx context.Context, id int64) error
+}
+
+// Service - sends notifications about applied and failed metadata to configured endpoints. Notifications are written to outbox first and are retried with exponential backoff until they're delivered or attempts are exhausted.
+type Service struct {
+	repo      Repository
+	endpoints map[string]*config.Webhook
+	names     []string
+	network   string
+	client    *http.Client
+	prom      *prometheus.Prometheus
+
+	maxAttempts    int
+	workers        int
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+
+	wg *sync.WaitGroup
+}
+
+// New -
+func New(repo Repository, endpoints map[string]*config.Webhook, network string, opts ...ServiceOption) *Service {
+	s := &Service{
+		repo:           repo,
+		endpoints:      endpoints,
+		names:          make([]string, 0, len(endpoints)),
+		network:        network,
+		client:         &http.Client{Timeout: 10 * time.Second},
+		maxAttempts:    10,
+		workers:        5,
+		initialBackoff: 10 * time.Second,
+		maxBackoff:     time.Hour,
+		wg:             new(sync.WaitGroup),
+	}
+
+	for name := range endpoints {
+		s.names = append(s.names, name)
+	}
+	sort.Strings(s.names)
+
+	for i := range opts {
+		opts[i](s)
+	}
+
+	return s
+}
+
+// Start -
+func (s *Service) Start(ctx context.Context) {
+	s.wg.Add(1)
+	go s.dispatcher(ctx)
+}
+
+// Close -
+func (s *Service) Close() error {
+	s.wg.Wait()
+	return nil
+}
+
+// Contracts - adds notifications about applied and failed contract metadata to outbox
+func (s *Service) Contracts(ctx context.Context, contracts []*models.ContractMetadata) error {
+	if s == nil {
+		return nil
+	}
+
+	deliveries := make([]*models.WebhookDelivery, 0)
+	for i := range contracts {
+		if !isFinal(contracts[i].Status) || contracts[i].UpdateID == 0 {
+			continue
+		}
+		items, err := s.deliveries(newContractPayload(*contracts[i]))
+		if err != nil {
+			return err
+		}
+		deliveries = append(deliveries, items...)
+	}
+	return s.repo.Add(ctx, deliveries)
+}
+
+// Tokens - adds notifications about applied and failed token metadata to outbox
+func (s *Service) Tokens(ctx context.Context, tokens []*models.TokenMetadata) error {
+	if s == nil {
+		return nil
+	}
+
+	deliveries := make([]*models.WebhookDelivery, 0)
+	for i := range tokens {
+		if !isFinal(tokens[i].Status) || tokens[i].UpdateID == 0 {
+			continue
+		}
+		items, err := s.deliveries(newTokenPayload(*tokens[i]))
+		if err != nil {
+			return err
+		}
+		deliveries = append(deliveries, items...)
+	}
+	return s.repo.Add(ctx, deliveries)
+}
+
+func (s *Service) deliveries(payload Payload) ([]*models.WebhookDelivery, error) {
+	var (
+		deliveries = make([]*models.WebhookDelivery, 0)
+		body       []byte
+	)
+	for _, name := range s.names {
+		if !match(s.endpoints[name], payload) {
+			continue
+		}
+
+		if body == nil {
+			data, err := stdJSON.Marshal(payload)
+			if err != nil {
+				return nil, err
+			}
+			body = data
+		}
+
+		deliveries = append(deliveries, &models.WebhookDelivery{
+			Network:  payload.Network,
+			Endpoint: name,
+			Event:    payload.Event,
+			Payload:  models.JSONB(body),
+			Status:   models.WebhookStatusPending,
+		})
+	}
+	return deliveries, nil
+}
+
+func (s *Service) dispatcher(ctx context.Context) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	// outbox isn't read again till `pausedUntil` after failure, so deliveries which status wasn't saved aren't resent at once
+	var (
+		failures    int
+		pausedUntil time.Time
+	)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if now.Before(pausedUntil) {
+				continue
+			}
+			err := s.dispatch(ctx)
+			switch {
+			case err == nil:
+				failures = 0
+			case errors.Is(err, context.Canceled):
+			default:
+				failures++
+				pausedUntil = time.Now().Add(s.backoff(failures))
+				log.Err(err).Str("network", s.network).Time("paused_until", pausedUntil).Msg("webhooks dispatch")
+			}
+		}
+	}
+}
+
+// dispatch - sends pending deliveries. It stops if status of delivery isn't saved: the outbox would return the delivery again.
+func (s *Service) dispatch(ctx context.Context) error {
+	for {
+		deliveries, err := s.repo.Pending(ctx, s.network, time.Now().Unix(), batchSize)
+		if err != nil {
+			return err
+		}
+		if len(deliveries) == 0 {
+			return nil
+		}
+
+		var (
+			wg        sync.WaitGroup
+			mx        sync.Mutex
+			saveError error
+		)
+		failed := func() error {
+			mx.Lock()
+			defer mx.Unlock()
+			return saveError
+		}
+
+		semaphore := make(chan struct{}, s.workers)
+		for i := range deliveries {
+			if failed() != nil {
+				break
+			}
+
+			select {
+			case <-ctx.Done():
+				wg.Wait()
+				return ctx.Err()
+			case semaphore <- struct{}{}:
+			}
+
+			wg.Add(1)
+			go func(delivery *models.WebhookDelivery) {
+				defer func() {
+					<-semaphore
+					wg.Done()
+				}()
+
+				if err := s.process(ctx, delivery); err != nil {
+					mx.Lock()
+					if saveError == nil {
+						saveError = errors.Wrapf(err, "save status of delivery %d", delivery.ID)
+					}
+					mx.Unlock()
+				}
+			}(&deliveries[i])
+		}
+		wg.Wait()
+
+		if err := failed(); err != nil {
+			return err
+		}
+		if len(deliveries) < batchSize {
+			return nil
+		}
+	}
+}
+
+func (s *Service) process(ctx context.Context, delivery *models.WebhookDelivery) error {
+	endpoint, ok := s.endpoints[delivery.Endpoint]
+	if !ok {
+		delivery.Status = models.WebhookStatusFailed
+		delivery.Error = "unknown endpoint"
+		s.prom.IncrementWebhookCounter(s.network, delivery.Endpoint, ResultFailed)
+		return s.repo.Update(ctx, delivery)
+	}
+
+	err := s.send(ctx, endpoint, delivery)
+	if err == nil {
+		s.prom.IncrementWebhookCounter(s.network, delivery.Endpoint, ResultDelivered)
+		return s.repo.Delete(ctx, delivery.ID)
+	}
+	if errors.Is(err, context.Canceled) {
+		return nil
+	}
+
+	delivery.Attempts++
+	delivery.Error = err.Error()
+	if delivery.Attempts >= s.maxAttempts {
+		delivery.Status = models.WebhookStatusFailed
+		s.prom.IncrementWebhookCounter(s.network, delivery.Endpoint, ResultFailed)
+	} else {
+		delivery.NextAttemptAt = time.Now().Add(s.backoff(delivery.Attempts)).Unix()
+		s.prom.IncrementWebhookCounter(s.network, delivery.Endpoint, ResultRetry)
+	}
+	return s.repo.Update(ctx, delivery)
+}
+
+func (s *Service) send(ctx context.Context, endpoint *config.Webhook, delivery *models.WebhookDelivery) error {
+	body := []byte(delivery.Payload)
+	timestamp := time.Now().Unix()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, delivery.Event)
+	req.Header.Set(HeaderDelivery, strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(endpoint.Secret, timestamp, body))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+		return errors.Errorf("unexpected status code %d: %s", resp.StatusCode, string(data))
+	}
+
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return nil
+}
+
+// backoff - delay before attempt with number `attempt + 1`
+func (s *Service) backoff(attempt int) time.Duration {
+	delay := s.initialBackoff
+	for i := 1; i < attempt && delay < s.maxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > s.maxBackoff {
+		delay = s.maxBackoff
+	}
+	return delay
+}
+
+func isFinal(status models.Status) bool {
+	return status == models.StatusApplied || status == models.StatusFailed
+}
+
+func match(endpoint *config.Webhook, payload Payload) bool {
+	if len(endpoint.Networks) > 0 && !contains(endpoint.Networks, payload.Network) {
+		return false
+	}
+	if len(endpoint.Contracts) > 0 && !contains(endpoint.Contracts, payload.Contract) {
+		return false
+	}
+	return true
+}
+
+func contains(values []string, value string) bool {
+	for i := range values {
+		if values[i] == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,244 @@
+package webhooks
+
+import (
+	"context"
+	stdJSON "encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dipdup-net/metadata/cmd/metadata/config"
+	"github.com/dipdup-net/metadata/cmd/metadata/models"
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testContract = "KT1G1cCRNBgQ48mVDjopHjEmTN5Sbtar8nn9"
+
+type memoryOutbox struct {
+	deliveries map[int64]*models.WebhookDelivery
+	lastID     int64
+	writeErr   error
+	mx         sync.Mutex
+}
+
+func newMemoryOutbox() *memoryOutbox {
+	return &memoryOutbox{deliveries: make(map[int64]*models.WebhookDelivery)}
+}
+
+func (m *memoryOutbox) Add(ctx context.Context, deliveries []*models.WebhookDelivery) error {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+	for i := range deliveries {
+		m.lastID++
+		deliveries[i].ID = m.lastID
+		copied := *deliveries[i]
+		m.deliveries[copied.ID] = &copied
+	}
+	return nil
+}
+
+func (m *memoryOutbox) Pending(ctx context.Context, network string, now int64, limit int) ([]models.WebhookDelivery, error) {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+	result := make([]models.WebhookDelivery, 0)
+	for id := int64(1); id <= m.lastID && len(result) < limit; id++ {
+		delivery, ok := m.deliveries[id]
+		if ok && delivery.Network == network && delivery.Status == models.WebhookStatusPending && delivery.NextAttemptAt <= now {
+			result = append(result, *delivery)
+		}
+	}
+	return result, nil
+}
+
+func (m *memoryOutbox) Update(ctx context.Context, delivery *models.WebhookDelivery) error {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+	if m.writeErr != nil {
+		return m.writeErr
+	}
+	copied := *delivery
+	m.deliveries[delivery.ID] = &copied
+	return nil
+}
+
+func (m *memoryOutbox) Delete(ctx context.Context, id int64) error {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+	if m.writeErr != nil {
+		return m.writeErr
+	}
+	delete(m.deliveries, id)
+	return nil
+}
+
+func TestService_Tokens(t *testing.T) {
+	outbox := newMemoryOutbox()
+	s := New(outbox, map[string]*config.Webhook{
+		"all":     {URL: "http://localhost/all", Secret: "secret"},
+		"ghost":   {URL: "http://localhost/ghost", Secret: "secret", Networks: []string{"ghostnet"}},
+		"hedgies": {URL: "http://localhost/hedgies", Secret: "secret", Contracts: []string{testContract}},
+	}, "mainnet")
+
+	err := s.Tokens(context.Background(), []*models.TokenMetadata{
+		{Network: "mainnet", Contract: testContract, TokenID: decimal.NewFromInt(1), Status: models.StatusApplied, UpdateID: 1, Metadata: models.JSONB(`{"name":"Hedgehoge"}`)},
+		{Network: "mainnet", Contract: "KT1RJ6PbjHpwc3M5rw5s2Nbmefwbuwbdxton", TokenID: decimal.NewFromInt(2), Status: models.StatusFailed, UpdateID: 2, Error: "timeout"},
+		{Network: "mainnet", Contract: testContract, TokenID: decimal.NewFromInt(3), Status: models.StatusNew, UpdateID: 3},
+		{Network: "mainnet", Contract: testContract, TokenID: decimal.NewFromInt(4), Status: models.StatusApplied},
+	})
+	require.NoError(t, err)
+
+	deliveries, err := outbox.Pending(context.Background(), "mainnet", time.Now().Unix(), 10)
+	require.NoError(t, err)
+
+	got := make([]string, len(deliveries))
+	for i := range deliveries {
+		got[i] = deliveries[i].Endpoint + " " + deliveries[i].Event
+	}
+	assert.Equal(t, []string{
+		"all token.applied",
+		"hedgies token.applied",
+		"all token.failed",
+	}, got)
+
+	var payload Payload
+	require.NoError(t, stdJSON.Unmarshal(deliveries[0].Payload, &payload))
+	assert.Equal(t, "token.applied", payload.Event)
+	assert.Equal(t, "1", payload.TokenID.String())
+	assert.JSONEq(t, `{"name":"Hedgehoge"}`, string(payload.Metadata))
+}
+
+func TestService_dispatch(t *testing.T) {
+	var (
+		received []*http.Request
+		bodies   [][]byte
+		mx       sync.Mutex
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		mx.Lock()
+		received = append(received, r)
+		bodies = append(bodies, body)
+		mx.Unlock()
+
+		if r.URL.Path == "/broken" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	outbox := newMemoryOutbox()
+	s := New(outbox, map[string]*config.Webhook{
+		"ok":     {URL: server.URL + "/ok", Secret: "secret"},
+		"broken": {URL: server.URL + "/broken", Secret: "secret"},
+	}, "mainnet", WithMaxAttempts(2), WithBackoff(time.Hour, 2*time.Hour))
+
+	require.NoError(t, s.Contracts(context.Background(), []*models.ContractMetadata{
+		{Network: "mainnet", Contract: testContract, Status: models.StatusApplied, UpdateID: 5},
+	}))
+	require.Len(t, outbox.deliveries, 2)
+
+	require.NoError(t, s.dispatch(context.Background()))
+	require.Len(t, received, 2)
+
+	for i := range received {
+		timestamp, err := strconv.ParseInt(received[i].Header.Get(HeaderTimestamp), 10, 64)
+		require.NoError(t, err)
+		assert.Equal(t, Sign("secret", timestamp, bodies[i]), received[i].Header.Get(HeaderSignature))
+		assert.Equal(t, "contract.applied", received[i].Header.Get(HeaderEvent))
+		assert.Equal(t, "application/json", received[i].Header.Get("Content-Type"))
+	}
+
+	// delivered notification is removed, failed one is postponed
+	require.Len(t, outbox.deliveries, 1)
+	for _, delivery := range outbox.deliveries {
+		assert.Equal(t, "broken", delivery.Endpoint)
+		assert.Equal(t, models.WebhookStatusPending, delivery.Status)
+		assert.Equal(t, 1, delivery.Attempts)
+		assert.Greater(t, delivery.NextAttemptAt, time.Now().Add(59*time.Minute).Unix())
+		assert.Contains(t, delivery.Error, "503")
+
+		delivery.NextAttemptAt = 0
+	}
+
+	require.NoError(t, s.dispatch(context.Background()))
+	require.Len(t, received, 3)
+	for _, delivery := range outbox.deliveries {
+		assert.Equal(t, models.WebhookStatusFailed, delivery.Status)
+		assert.Equal(t, 2, delivery.Attempts)
+	}
+
+	require.NoError(t, s.dispatch(context.Background()))
+	require.Len(t, received, 3)
+}
+
+func TestService_dispatchSaveFailure(t *testing.T) {
+	var (
+		received = make(map[string]int)
+		mx       sync.Mutex
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mx.Lock()
+		received[r.Header.Get(HeaderDelivery)]++
+		mx.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	outbox := newMemoryOutbox()
+	s := New(outbox, map[string]*config.Webhook{
+		"ok": {URL: server.URL, Secret: "secret"},
+	}, "mainnet")
+
+	deliveries := make([]*models.WebhookDelivery, batchSize*2)
+	for i := range deliveries {
+		deliveries[i] = &models.WebhookDelivery{Network: "mainnet", Endpoint: "ok", Event: "token.applied", Payload: models.JSONB(`{}`), Status: models.WebhookStatusPending}
+	}
+	require.NoError(t, outbox.Add(context.Background(), deliveries))
+	outbox.writeErr = errors.New("connection refused")
+
+	err := s.dispatch(context.Background())
+	require.ErrorIs(t, err, outbox.writeErr)
+
+	mx.Lock()
+	defer mx.Unlock()
+	assert.NotEmpty(t, received)
+	assert.LessOrEqual(t, len(received), batchSize, "the next batch isn't read after failure")
+	for id, count := range received {
+		assert.Equal(t, 1, count, "delivery %s is sent once", id)
+	}
+}
+
+func TestService_backoff(t *testing.T) {
+	s := New(nil, nil, "mainnet", WithBackoff(10*time.Second, time.Minute))
+
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{attempt: 1, want: 10 * time.Second},
+		{attempt: 2, want: 20 * time.Second},
+		{attempt: 3, want: 40 * time.Second},
+		{attempt: 4, want: time.Minute},
+		{attempt: 100, want: time.Minute},
+	}
+	for _, tt := range tests {
+		t.Run(strconv.Itoa(tt.attempt), func(t *testing.T) {
+			assert.Equal(t, tt.want, s.backoff(tt.attempt))
+		})
+	}
+}
+
+func TestSign(t *testing.T) {
+	got := Sign("secret", 1700000000, []byte(`{"event":"token.applied"}`))
+	assert.Equal(t, "sha256=ff77fe8b85c0ee1bd5a867c5198c565a28cd7c23ded1020943b36f2a23908802", got)
+}
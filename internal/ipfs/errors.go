@@ -1,6 +1,9 @@
 package ipfs
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
 
 // Errors
 var (
@@ -11,3 +14,15 @@ var (
 	ErrJSONDecoding         = errors.New("JSON decoding error")
 	ErrNoIPFSResponse       = errors.New("can't load document from IPFS")
 )
+
+// StatusError - unsuccessful response of gateway
+type StatusError struct {
+	Code       int
+	Status     string
+	RetryAfter string
+}
+
+// Error -
+func (e StatusError) Error() string {
+	return fmt.Sprintf("invalid status: %s", e.Status)
+}
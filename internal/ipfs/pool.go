@@ -3,7 +3,6 @@ package ipfs
 import (
 	"context"
 	"io"
-	"math/rand"
 	"net/http"
 	"net/url"
 	"time"
@@ -16,12 +15,23 @@ import (
 type Pool struct {
 	limiters map[string]*rate.Limiter
 	gateways []string
+	scores   *Scores
 	limit    int64
 	client   *http.Client
 }
 
+// PoolOption -
+type PoolOption func(*Pool)
+
+// WithScores - sets scores of gateways shared with other users of gateways
+func WithScores(scores *Scores) PoolOption {
+	return func(pool *Pool) {
+		pool.scores = scores
+	}
+}
+
 // NewPool -
-func NewPool(gateways []string, limit int64) (*Pool, error) {
+func NewPool(gateways []string, limit int64, opts ...PoolOption) (*Pool, error) {
 	if len(gateways) == 0 {
 		return nil, ErrEmptyIPFSGatewayList
 	}
@@ -30,7 +40,7 @@ func NewPool(gateways []string, limit int64) (*Pool, error) {
 		pool[gateways[i]] = rate.NewLimiter(rate.Every(time.Second/10), 10)
 	}
 
-	return &Pool{
+	p := &Pool{
 		gateways: gateways,
 		limiters: pool,
 		limit:    limit,
@@ -39,12 +49,20 @@ func NewPool(gateways []string, limit int64) (*Pool, error) {
 				DisableKeepAlives: true,
 			},
 		},
-	}, nil
+	}
+
+	for i := range opts {
+		opts[i](p)
+	}
+	if p.scores == nil {
+		p.scores = NewScores(gateways)
+	}
+	return p, nil
 }
 
-// Get - returns result if one of node returns it
+// Get - returns result if one of node returns it. Nodes are requested in order of their scores.
 func (pool *Pool) Get(ctx context.Context, link string) (Data, error) {
-	for _, node := range ShuffleGateways(pool.gateways) {
+	for _, node := range pool.scores.Order() {
 		if data, err := pool.request(ctx, link, node); err == nil {
 			return Data{
 				Raw:  data,
@@ -55,23 +73,28 @@ func (pool *Pool) Get(ctx context.Context, link string) (Data, error) {
 	return Data{}, ErrNoIPFSResponse
 }
 
-// GetFromRandomGateway - returns result if random node returns it
+// GetFromRandomGateway - returns result if node picked by scores returns it. Random node of two healthy ones with the better score is picked.
 func (pool *Pool) GetFromRandomGateway(ctx context.Context, link string) (Data, error) {
-	index := rand.Intn(len(pool.gateways))
+	node := pool.scores.Pick()
 	start := time.Now()
-	data, err := pool.request(ctx, link, pool.gateways[index])
+	data, err := pool.request(ctx, link, node)
 	if err != nil {
 		return Data{
-			Node: pool.gateways[index],
+			Node: node,
 		}, err
 	}
 	return Data{
 		Raw:          data,
-		Node:         pool.gateways[index],
+		Node:         node,
 		ResponseTime: time.Since(start).Milliseconds(),
 	}, nil
 }
 
+// Scores - returns scores of pool's gateways
+func (pool *Pool) Scores() *Scores {
+	return pool.scores
+}
+
 // GetFromNode - returns result if `node` returns it
 func (pool *Pool) GetFromNode(ctx context.Context, link, node string) (Data, error) {
 	data, err := pool.request(ctx, link, node)
@@ -91,27 +114,43 @@ func (pool *Pool) request(ctx context.Context, link, node string) ([]byte, error
 		}
 	}
 
-	path := Path(link)
-	gatewayURL := Link(node, path)
-
+	gatewayURL := Link(node, Path(link))
 	if _, err := url.ParseRequestURI(gatewayURL); err != nil {
 		return nil, errors.Wrap(ErrInvalidURI, gatewayURL)
 	}
+
+	start := time.Now()
+	data, latency, err := pool.fetch(ctx, gatewayURL)
+	if !errors.Is(ctx.Err(), context.Canceled) {
+		pool.scores.Observe(node, latency, len(data), time.Since(start), err)
+	}
+	return data, err
+}
+
+// fetch - returns body of gateway's response and time to its headers
+func (pool *Pool) fetch(ctx context.Context, gatewayURL string) ([]byte, time.Duration, error) {
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, gatewayURL, nil)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
+	start := time.Now()
 	resp, err := pool.client.Do(req)
 	if err != nil {
-		return nil, errors.Wrap(ErrHTTPRequest, err.Error())
+		return nil, 0, errors.Wrap(ErrHTTPRequest, err.Error())
 	}
 	defer resp.Body.Close()
+	latency := time.Since(start)
 
 	switch resp.StatusCode {
 	case http.StatusOK:
-		return io.ReadAll(io.LimitReader(resp.Body, pool.limit))
+		data, err := io.ReadAll(io.LimitReader(resp.Body, pool.limit))
+		return data, latency, err
 	default:
-		return nil, errors.Errorf("invalid status: %s", resp.Status)
+		return nil, latency, StatusError{
+			Code:       resp.StatusCode,
+			Status:     resp.Status,
+			RetryAfter: resp.Header.Get("Retry-After"),
+		}
 	}
 }
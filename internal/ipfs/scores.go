@@ -0,0 +1,322 @@
+package ipfs
+
+import (
+	"context"
+	"math/rand"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// defaults of gateway scoring
+const (
+	defaultScoreDecay         = 0.2
+	defaultQuarantineFailures = 5
+	defaultProbeInterval      = 30 * time.Second
+
+	// exploration - share of picks of random healthy gateway, so scores of gateways which lose comparisons are updated too
+	exploration = 0.1
+
+	// ProbeCID - identity CID of empty block. Gateways return it without requests to network, so it checks gateway itself.
+	ProbeCID = "bafkqaaa"
+)
+
+// GatewayScore - observed state of gateway. Latency is exponentially weighted time to response headers, error rate and throughput are weighted the same way.
+type GatewayScore struct {
+	Gateway     string  `json:"gateway"`
+	Score       float64 `json:"score"`
+	Latency     float64 `json:"latency_ms"`
+	ErrorRate   float64 `json:"error_rate"`
+	Throughput  float64 `json:"throughput_bytes_per_ms"`
+	Requests    int64   `json:"requests"`
+	Failures    int     `json:"consecutive_failures"`
+	Quarantined bool    `json:"quarantined"`
+}
+
+// ScoresOption -
+type ScoresOption func(*Scores)
+
+// WithQuarantineFailures - count of consecutive failures after which gateway is quarantined
+func WithQuarantineFailures(failures int) ScoresOption {
+	return func(s *Scores) {
+		if failures > 0 {
+			s.quarantineFailures = failures
+		}
+	}
+}
+
+// WithProbeInterval - interval of health probes of quarantined gateways
+func WithProbeInterval(interval time.Duration) ScoresOption {
+	return func(s *Scores) {
+		if interval > 0 {
+			s.probeInterval = interval
+		}
+	}
+}
+
+// WithScoreObserver - sets function which is called with score of gateway after it's changed
+func WithScoreObserver(observer func(GatewayScore)) ScoresOption {
+	return func(s *Scores) {
+		s.observer = observer
+	}
+}
+
+type gatewayStats struct {
+	GatewayScore
+
+	successes int64
+	transfers int64
+}
+
+// Scores - tracks latency and errors of gateways and picks gateways with the best score. Failing gateway is quarantined: it isn't picked till health probe succeeds.
+type Scores struct {
+	gateways []string
+	stats    map[string]*gatewayStats
+	mx       sync.RWMutex
+
+	decay              float64
+	quarantineFailures int
+	probeInterval      time.Duration
+	observer           func(GatewayScore)
+	client             *http.Client
+
+	wg *sync.WaitGroup
+}
+
+// NewScores -
+func NewScores(gateways []string, opts ...ScoresOption) *Scores {
+	s := &Scores{
+		gateways:           gateways,
+		stats:              make(map[string]*gatewayStats, len(gateways)),
+		decay:              defaultScoreDecay,
+		quarantineFailures: defaultQuarantineFailures,
+		probeInterval:      defaultProbeInterval,
+		client: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+		wg: new(sync.WaitGroup),
+	}
+	for _, gateway := range gateways {
+		s.stats[gateway] = &gatewayStats{
+			GatewayScore: GatewayScore{Gateway: gateway, Score: 1},
+		}
+	}
+
+	for i := range opts {
+		opts[i](s)
+	}
+	return s
+}
+
+// Start - runs health probes of quarantined gateways
+func (s *Scores) Start(ctx context.Context) {
+	s.wg.Add(1)
+	go s.probing(ctx)
+}
+
+// Close -
+func (s *Scores) Close() error {
+	s.wg.Wait()
+	return nil
+}
+
+// score - new gateways have the best score, so they're explored first
+func score(stats GatewayScore) float64 {
+	return (1 - stats.ErrorRate) / (1 + stats.Latency/1000)
+}
+
+func (s *Scores) ewma(current, value float64, first bool) float64 {
+	if first {
+		return value
+	}
+	return current + s.decay*(value-current)
+}
+
+// Observe - records result of request to gateway. `latency` is time to response headers, `size` is count of received bytes and `elapsed` is time of the whole request.
+func (s *Scores) Observe(gateway string, latency time.Duration, size int, elapsed time.Duration, err error) {
+	if errors.Is(err, context.Canceled) {
+		return
+	}
+
+	s.mx.Lock()
+	stats, ok := s.stats[gateway]
+	if !ok {
+		s.mx.Unlock()
+		return
+	}
+
+	first := stats.Requests == 0
+	stats.Requests++
+	if err != nil {
+		stats.ErrorRate = s.ewma(stats.ErrorRate, 1, first)
+		stats.Failures++
+		if stats.Failures >= s.quarantineFailures {
+			stats.Quarantined = true
+		}
+	} else {
+		stats.ErrorRate = s.ewma(stats.ErrorRate, 0, first)
+		stats.Latency = s.ewma(stats.Latency, float64(latency.Milliseconds()), stats.successes == 0)
+		stats.successes++
+		if size > 0 {
+			stats.Throughput = s.ewma(stats.Throughput, float64(size)/float64(elapsed.Milliseconds()+1), stats.transfers == 0)
+			stats.transfers++
+		}
+		stats.Failures = 0
+	}
+	stats.Score = score(stats.GatewayScore)
+	snapshot := stats.GatewayScore
+	s.mx.Unlock()
+
+	s.notify(snapshot)
+}
+
+func (s *Scores) notify(stats GatewayScore) {
+	if s.observer != nil {
+		s.observer(stats)
+	}
+}
+
+// Pick - picks the better of two random healthy gateways. Gateway with the best score is returned if all gateways are quarantined.
+func (s *Scores) Pick() string {
+	s.mx.RLock()
+	defer s.mx.RUnlock()
+
+	healthy := make([]string, 0, len(s.gateways))
+	for _, gateway := range s.gateways {
+		if !s.stats[gateway].Quarantined {
+			healthy = append(healthy, gateway)
+		}
+	}
+
+	switch len(healthy) {
+	case 0:
+		return s.best(s.gateways)
+	case 1:
+		return healthy[0]
+	}
+
+	i := rand.Intn(len(healthy))
+	if rand.Float64() < exploration {
+		return healthy[i]
+	}
+	j := rand.Intn(len(healthy) - 1)
+	if j >= i {
+		j++
+	}
+	if s.stats[healthy[j]].Score > s.stats[healthy[i]].Score {
+		return healthy[j]
+	}
+	return healthy[i]
+}
+
+func (s *Scores) best(gateways []string) string {
+	var result string
+	for _, gateway := range gateways {
+		if result == "" || s.stats[gateway].Score > s.stats[result].Score {
+			result = gateway
+		}
+	}
+	return result
+}
+
+// Order - returns healthy gateways by descending score and then quarantined ones
+func (s *Scores) Order() []string {
+	s.mx.RLock()
+	defer s.mx.RUnlock()
+
+	ordered := make([]string, len(s.gateways))
+	copy(ordered, s.gateways)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		left, right := s.stats[ordered[i]], s.stats[ordered[j]]
+		if left.Quarantined != right.Quarantined {
+			return right.Quarantined
+		}
+		return left.Score > right.Score
+	})
+	return ordered
+}
+
+// Snapshot - returns scores of all gateways
+func (s *Scores) Snapshot() []GatewayScore {
+	s.mx.RLock()
+	defer s.mx.RUnlock()
+
+	result := make([]GatewayScore, 0, len(s.gateways))
+	for _, gateway := range s.gateways {
+		result = append(result, s.stats[gateway].GatewayScore)
+	}
+	return result
+}
+
+func (s *Scores) quarantined() []string {
+	s.mx.RLock()
+	defer s.mx.RUnlock()
+
+	result := make([]string, 0)
+	for _, gateway := range s.gateways {
+		if s.stats[gateway].Quarantined {
+			result = append(result, gateway)
+		}
+	}
+	return result
+}
+
+func (s *Scores) probing(ctx context.Context) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.probeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for _, gateway := range s.quarantined() {
+				if err := s.probe(ctx, gateway); err != nil {
+					continue
+				}
+				s.release(gateway)
+			}
+		}
+	}
+}
+
+func (s *Scores) probe(ctx context.Context, gateway string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, Link(gateway, ProbeCID), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return StatusError{
+			Code:   resp.StatusCode,
+			Status: resp.Status,
+		}
+	}
+	return nil
+}
+
+// release - returns gateway from quarantine. Its error rate stays high, so it gets traffic gradually by exploration.
+func (s *Scores) release(gateway string) {
+	s.mx.Lock()
+	stats, ok := s.stats[gateway]
+	if !ok {
+		s.mx.Unlock()
+		return
+	}
+	stats.Quarantined = false
+	stats.Failures = 0
+	snapshot := stats.GatewayScore
+	s.mx.Unlock()
+
+	s.notify(snapshot)
+}
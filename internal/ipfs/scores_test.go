@@ -0,0 +1,175 @@
+package ipfs
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScores_Observe(t *testing.T) {
+	errGateway := errors.New("gateway error")
+	tests := []struct {
+		name            string
+		observe         func(s *Scores)
+		wantLatency     float64
+		wantErrorRate   float64
+		wantFailures    int
+		wantQuarantined bool
+	}{
+		{
+			name: "first success",
+			observe: func(s *Scores) {
+				s.Observe("a", 100*time.Millisecond, 1000, 200*time.Millisecond, nil)
+			},
+			wantLatency: 100,
+		}, {
+			name: "weighted latency",
+			observe: func(s *Scores) {
+				s.Observe("a", 100*time.Millisecond, 0, 0, nil)
+				s.Observe("a", 600*time.Millisecond, 0, 0, nil)
+			},
+			wantLatency: 200,
+		}, {
+			name: "failures don't change latency",
+			observe: func(s *Scores) {
+				s.Observe("a", 100*time.Millisecond, 0, 0, nil)
+				s.Observe("a", 0, 0, 0, errGateway)
+			},
+			wantLatency:   100,
+			wantErrorRate: 0.2,
+			wantFailures:  1,
+		}, {
+			name: "quarantine",
+			observe: func(s *Scores) {
+				for i := 0; i < 3; i++ {
+					s.Observe("a", 0, 0, 0, errGateway)
+				}
+			},
+			wantErrorRate:   1,
+			wantFailures:    3,
+			wantQuarantined: true,
+		}, {
+			name: "success resets failures",
+			observe: func(s *Scores) {
+				s.Observe("a", 0, 0, 0, errGateway)
+				s.Observe("a", 0, 0, 0, errGateway)
+				s.Observe("a", 0, 0, 0, nil)
+				s.Observe("a", 0, 0, 0, errGateway)
+			},
+			wantErrorRate: 0.84,
+			wantFailures:  1,
+		}, {
+			name: "canceled requests are ignored",
+			observe: func(s *Scores) {
+				s.Observe("a", 0, 0, 0, context.Canceled)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewScores([]string{"a", "b"}, WithQuarantineFailures(3))
+			tt.observe(s)
+
+			got := s.Snapshot()[0]
+			assert.Equal(t, "a", got.Gateway)
+			assert.InDelta(t, tt.wantLatency, got.Latency, 1e-9)
+			assert.InDelta(t, tt.wantErrorRate, got.ErrorRate, 1e-9)
+			assert.Equal(t, tt.wantFailures, got.Failures)
+			assert.Equal(t, tt.wantQuarantined, got.Quarantined)
+			assert.InDelta(t, score(got), got.Score, 1e-9)
+		})
+	}
+}
+
+func TestScores_Pick(t *testing.T) {
+	errGateway := errors.New("gateway error")
+	s := NewScores([]string{"slow", "fast", "broken"}, WithQuarantineFailures(1))
+	s.Observe("slow", 2*time.Second, 0, 0, nil)
+	s.Observe("fast", 100*time.Millisecond, 0, 0, nil)
+	s.Observe("broken", 0, 0, 0, errGateway)
+
+	assert.Equal(t, []string{"fast", "slow", "broken"}, s.Order())
+
+	picked := make(map[string]int)
+	for i := 0; i < 1000; i++ {
+		picked[s.Pick()]++
+	}
+	assert.Zero(t, picked["broken"], "quarantined gateway isn't picked")
+	assert.Greater(t, picked["fast"], picked["slow"])
+	assert.NotZero(t, picked["slow"], "worse gateway is explored")
+
+	s.Observe("fast", 0, 0, 0, errGateway)
+	s.Observe("slow", 0, 0, 0, errGateway)
+	assert.Equal(t, "fast", s.Pick(), "the best gateway is picked if all are quarantined")
+}
+
+func TestScores_probing(t *testing.T) {
+	var healthy bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/ipfs/"+ProbeCID, r.URL.Path)
+		if !healthy {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer server.Close()
+
+	var observed []GatewayScore
+	s := NewScores([]string{server.URL}, WithQuarantineFailures(1), WithScoreObserver(func(score GatewayScore) {
+		observed = append(observed, score)
+	}))
+	s.Observe(server.URL, 0, 0, 0, errors.New("gateway error"))
+	require.Equal(t, []string{server.URL}, s.quarantined())
+
+	ctx := context.Background()
+	require.Error(t, s.probe(ctx, server.URL))
+
+	healthy = true
+	require.NoError(t, s.probe(ctx, server.URL))
+	s.release(server.URL)
+	assert.Empty(t, s.quarantined())
+
+	require.Len(t, observed, 2)
+	assert.True(t, observed[0].Quarantined)
+	assert.False(t, observed[1].Quarantined)
+}
+
+func TestPool_Get(t *testing.T) {
+	var requests []string
+	newGateway := func(status int) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests = append(requests, r.Host)
+			w.WriteHeader(status)
+			_, _ = w.Write([]byte(`{}`))
+		}))
+	}
+	broken := newGateway(http.StatusGatewayTimeout)
+	defer broken.Close()
+	working := newGateway(http.StatusOK)
+	defer working.Close()
+
+	pool, err := NewPool([]string{broken.URL, working.URL}, 1024, WithScores(NewScores([]string{broken.URL, working.URL}, WithQuarantineFailures(1))))
+	require.NoError(t, err)
+
+	const link = "ipfs://QmWYTUjkRusrhz4BCmoMKfSA8DBXk5pH2oAN83S9mABE3w"
+	for i := 0; i < 3; i++ {
+		data, err := pool.Get(context.Background(), link)
+		require.NoError(t, err)
+		assert.Equal(t, working.URL, data.Node)
+	}
+
+	brokenHost := broken.Listener.Addr().String()
+	var brokenRequests int
+	for _, host := range requests {
+		if host == brokenHost {
+			brokenRequests++
+		}
+	}
+	assert.LessOrEqual(t, brokenRequests, 1, "quarantined gateway is requested last")
+	assert.Equal(t, []string{working.URL, broken.URL}, pool.Scores().Order())
+}